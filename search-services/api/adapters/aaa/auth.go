@@ -1,6 +1,8 @@
 package aaa
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -8,140 +10,309 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/liy0aay/xkcd-search/api/core"
 )
 
 const adminRole = "superuser"
 
+const defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
 type AAA struct {
 	secretKey       string
-	users           map[string]string
+	users           map[string]credential
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	sliding         bool
+	revoked         *revocations
+	revocationStore RevocationStore
+	sessions        *sessions
+	sessionStore    SessionStore
+	issuer          string
+	audience        string
 	log             *slog.Logger
 }
 
-func New(tokenTTL time.Duration, log *slog.Logger) (AAA, error) {
+type Option func(*AAA)
+
+// WithIssuer sets the iss claim of issued tokens and requires it on verification.
+func WithIssuer(issuer string) Option {
+	return func(a *AAA) {
+		a.issuer = issuer
+	}
+}
+
+// WithAudience sets the aud claim of issued tokens and requires it on verification.
+func WithAudience(audience string) Option {
+	return func(a *AAA) {
+		a.audience = audience
+	}
+}
+
+// WithRefreshTTL sets the lifetime of refresh tokens, 30 days by default.
+func WithRefreshTTL(ttl time.Duration) Option {
+	return func(a *AAA) {
+		a.refreshTokenTTL = ttl
+	}
+}
+
+// WithSlidingRefresh rotates refresh tokens: every refresh issues a new
+// refresh token with a fresh lifetime and revokes the one used.
+func WithSlidingRefresh() Option {
+	return func(a *AAA) {
+		a.sliding = true
+	}
+}
+
+// WithMaxSessions limits live refresh tokens per user, a login over
+// the limit revokes the oldest session.
+func WithMaxSessions(n int) Option {
+	return func(a *AAA) {
+		if n > 0 {
+			a.sessions = newSessions(n)
+		}
+	}
+}
+
+// WithRevocationStore keeps revoked refresh tokens revoked across restarts.
+func WithRevocationStore(store RevocationStore) Option {
+	return func(a *AAA) {
+		a.revocationStore = store
+	}
+}
+
+// WithSessionStore keeps sessions limited by WithMaxSessions across restarts.
+func WithSessionStore(store SessionStore) Option {
+	return func(a *AAA) {
+		a.sessionStore = store
+	}
+}
+
+func New(tokenTTL time.Duration, log *slog.Logger, opts ...Option) (AAA, error) {
 	const adminUser = "ADMIN_USER"
 	const adminPass = "ADMIN_PASSWORD"
 	const secretKeyEnv = "JWT_SECRET_KEY"
+	const usersFile = "USERS_FILE"
 
+	// users of the file take over the admin of the same name
+	users, err := loadUsers(os.Getenv(usersFile))
+	if err != nil {
+		return AAA{}, err
+	}
 	user, ok := os.LookupEnv(adminUser)
-	if !ok {
+	if !ok && len(users) == 0 {
 		return AAA{}, fmt.Errorf("could not get admin user from enviroment")
 	}
-	password, ok := os.LookupEnv(adminPass)
-	if !ok {
-		return AAA{}, fmt.Errorf("could not get admin password from enviroment")
+	if ok {
+		password, ok := os.LookupEnv(adminPass)
+		if !ok {
+			return AAA{}, fmt.Errorf("could not get admin password from enviroment")
+		}
+		if _, found := users[user]; !found {
+			users[user] = envCredential(password)
+		}
 	}
 	secretKey, ok := os.LookupEnv(secretKeyEnv)
 	if !ok {
 		return AAA{}, fmt.Errorf("could not get JWT secret key from enviroment")
 	}
 
-	return AAA{
+	a := AAA{
 		secretKey:       secretKey,
-		users:           map[string]string{user: password},
+		users:           users,
 		accessTokenTTL:  tokenTTL,
-		refreshTokenTTL: 30 * 24 * time.Hour,
+		refreshTokenTTL: defaultRefreshTokenTTL,
+		revoked:         newRevocations(),
 		log:             log,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(&a)
+	}
+	if a.refreshTokenTTL <= 0 {
+		return AAA{}, fmt.Errorf("wrong refresh token TTL: %v", a.refreshTokenTTL)
+	}
+	if a.revocationStore != nil {
+		if err := a.revoked.restore(a.revocationStore, log); err != nil {
+			return AAA{}, fmt.Errorf("cannot load revocations: %w", err)
+		}
+	}
+	if a.sessions != nil && a.sessionStore != nil {
+		if err := a.sessions.restore(a.sessionStore, log); err != nil {
+			return AAA{}, fmt.Errorf("cannot load sessions: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// newID returns a random jti, so that a token can be revoked.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func (a AAA) newToken(name, tokenType string, ttl time.Duration) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	return a.signToken(name, tokenType, time.Now().Add(ttl), id)
+}
+
+// newRefreshToken issues a refresh token identified by a random jti.
+func (a AAA) newRefreshToken(name string) (string, Session, error) {
+	id, err := newID()
+	if err != nil {
+		return "", Session{}, err
+	}
+	s := Session{ID: id, Expires: time.Now().Add(a.refreshTokenTTL)}
+	token, err := a.signToken(name, "refresh", s.Expires, s.ID)
+	return token, s, err
+}
+
+func (a AAA) signToken(name, tokenType string, expires time.Time, id string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":  adminRole,
+		"name": name,
+		"type": tokenType,
+		"exp":  jwt.NewNumericDate(expires),
+		"iat":  jwt.NewNumericDate(time.Now()),
+	}
+	if a.issuer != "" {
+		claims["iss"] = a.issuer
+	}
+	if a.audience != "" {
+		claims["aud"] = a.audience
+	}
+	if id != "" {
+		claims["jti"] = id
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(a.secretKey))
+}
+
+func (a AAA) parse(tokenString string) (*jwt.Token, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()})}
+	if a.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		opts = append(opts, jwt.WithAudience(a.audience))
+	}
+	return jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		return []byte(a.secretKey), nil
+	}, opts...)
 }
 
 func (a AAA) Login(name, password string) (accessToken string, refreshToken string, err error) {
 	if name == "" {
 		return "", "", errors.New("empty user")
 	}
-	savedPass, ok := a.users[name]
+	saved, ok := a.users[name]
 	if !ok {
 		return "", "", errors.New("unknown user")
 	}
-	if savedPass != password {
+	if !saved.matches(password) {
 		return "", "", errors.New("wrong password")
 	}
 
-	accessClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  adminRole,
-		"name": name,
-		"type": "access",
-		"exp":  jwt.NewNumericDate(time.Now().Add(a.accessTokenTTL)),
-		"iat":  jwt.NewNumericDate(time.Now()),
-	})
-	accessTokenStr, err := accessClaims.SignedString([]byte(a.secretKey))
+	accessTokenStr, err := a.newToken(name, "access", a.accessTokenTTL)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create access token: %w", err)
 	}
 
-	refreshClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  adminRole,
-		"name": name,
-		"type": "refresh",
-		"exp":  jwt.NewNumericDate(time.Now().Add(a.refreshTokenTTL)),
-		"iat":  jwt.NewNumericDate(time.Now()),
-	})
-	refreshTokenStr, err := refreshClaims.SignedString([]byte(a.secretKey))
+	refreshTokenStr, started, err := a.newRefreshToken(name)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create refresh token: %w", err)
 	}
+	if a.sessions != nil {
+		for _, evicted := range a.sessions.add(name, started) {
+			a.log.Info("session limit reached, revoking oldest session", "name", name)
+			a.revoked.revoke(evicted.ID, evicted.Expires)
+		}
+	}
 
 	return accessTokenStr, refreshTokenStr, nil
 }
 
-func (a AAA) RefreshAccessToken(refreshTokenString string) (string, error) {
-	token, err := jwt.Parse(refreshTokenString, func(token *jwt.Token) (any, error) {
-		return []byte(a.secretKey), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
-
+// RefreshAccessToken issues a new access token, the new refresh token
+// is only issued with sliding refresh and is empty otherwise.
+func (a AAA) RefreshAccessToken(refreshTokenString string) (accessToken string, refreshToken string, err error) {
+	token, err := a.parse(refreshTokenString)
 	if err != nil {
 		a.log.Error("cannot parse refresh token", "error", err)
-		return "", fmt.Errorf("cannot parse token")
+		return "", "", fmt.Errorf("cannot parse token")
 	}
 
 	if !token.Valid {
 		a.log.Error("refresh token is invalid")
-		return "", errors.New("token is invalid")
+		return "", "", errors.New("token is invalid")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		a.log.Error("invalid token claims")
-		return "", errors.New("invalid token claims")
+		return "", "", errors.New("invalid token claims")
 	}
 	tokenType, ok := claims["type"].(string)
 	if !ok || tokenType != "refresh" {
 		a.log.Error("invalid token type")
-		return "", errors.New("invalid token type")
+		return "", "", errors.New("invalid token type")
 	}
 
 	subject, err := token.Claims.GetSubject()
 	if err != nil {
 		a.log.Error("no subject", "error", err)
-		return "", errors.New("incomplete token")
+		return "", "", errors.New("incomplete token")
 	}
 	if subject != adminRole {
 		a.log.Error("not admin", "subject", subject)
-		return "", errors.New("not authorized")
+		return "", "", errors.New("not authorized")
 	}
 
 	name, ok := claims["name"].(string)
 	if !ok {
-		return "", errors.New("no name in token")
+		return "", "", errors.New("no name in token")
 	}
 
-	newAccessClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  adminRole,
-		"name": name,
-		"type": "access",
-		"exp":  jwt.NewNumericDate(time.Now().Add(a.accessTokenTTL)),
-		"iat":  jwt.NewNumericDate(time.Now()),
-	})
+	id, _ := claims["jti"].(string)
+	if id != "" && a.revoked.isRevoked(id) {
+		a.log.Error("refresh token is revoked", "name", name)
+		return "", "", errors.New("token is revoked")
+	}
+
+	accessToken, err = a.newToken(name, "access", a.accessTokenTTL)
+	if err != nil || !a.sliding {
+		return accessToken, "", err
+	}
+
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil || expiresAt == nil || id == "" {
+		a.log.Error("refresh token cannot be rotated", "name", name)
+		return "", "", errors.New("incomplete token")
+	}
+	if !a.revoked.revoke(id, expiresAt.Time) {
+		a.log.Error("refresh token is revoked", "name", name)
+		return "", "", errors.New("token is revoked")
+	}
+	refreshToken, rotated, err := a.newRefreshToken(name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	if a.sessions != nil {
+		a.sessions.rotate(name, id, rotated)
+	}
+	return accessToken, refreshToken, nil
+}
 
-	return newAccessClaims.SignedString([]byte(a.secretKey))
+// RefreshTTL is the lifetime of issued refresh tokens.
+func (a AAA) RefreshTTL() time.Duration {
+	return a.refreshTokenTTL
 }
 
 func (a AAA) Verify(tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		return []byte(a.secretKey), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	token, err := a.parse(tokenString)
 	if err != nil {
 		a.log.Error("cannot parse token", "error", err)
 		return fmt.Errorf("cannot parse token")
@@ -162,6 +333,10 @@ func (a AAA) Verify(tokenString string) error {
 		a.log.Error("invalid token type, expected access")
 		return errors.New("invalid token type")
 	}
+	if id, _ := claims["jti"].(string); id != "" && a.revoked.isRevoked(id) {
+		a.log.Error("access token is revoked")
+		return errors.New("token is revoked")
+	}
 
 	subject, err := token.Claims.GetSubject()
 	if err != nil {
@@ -174,3 +349,60 @@ func (a AAA) Verify(tokenString string) error {
 	}
 	return nil
 }
+
+// Revoke rejects the token from now on until it expires, an already
+// expired token needs no revocation.
+func (a AAA) Revoke(tokenString string) error {
+	token, err := a.parse(tokenString)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil
+	}
+	if err != nil || !token.Valid {
+		a.log.Error("cannot parse token to revoke", "error", err)
+		return errors.New("cannot parse token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return errors.New("invalid token claims")
+	}
+	id, _ := claims["jti"].(string)
+	expiresAt, err := claims.GetExpirationTime()
+	if id == "" || err != nil || expiresAt == nil {
+		a.log.Error("token cannot be revoked")
+		return errors.New("incomplete token")
+	}
+	a.revoked.revoke(id, expiresAt.Time)
+	if tokenType, _ := claims["type"].(string); tokenType == "refresh" && a.sessions != nil {
+		name, _ := claims["name"].(string)
+		a.sessions.end(name, id)
+	}
+	return nil
+}
+
+// Introspect reports claims of a verified token, invalid, expired and
+// revoked tokens are just not active.
+func (a AAA) Introspect(tokenString string) core.TokenInfo {
+	token, err := a.parse(tokenString)
+	if err != nil || !token.Valid {
+		a.log.Debug("inactive token introspected", "error", err)
+		return core.TokenInfo{}
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return core.TokenInfo{}
+	}
+	if id, _ := claims["jti"].(string); id != "" && a.revoked.isRevoked(id) {
+		return core.TokenInfo{}
+	}
+	info := core.TokenInfo{Active: true}
+	info.Name, _ = claims["name"].(string)
+	info.Type, _ = claims["type"].(string)
+	info.Role, _ = claims.GetSubject()
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Time
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		info.IssuedAt = iat.Time
+	}
+	return info
+}
@@ -0,0 +1,388 @@
+package aaa
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+var noopLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+func newTestAAA(t *testing.T, opts ...Option) AAA {
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", "password")
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	a, err := New(time.Minute, noopLogger, opts...)
+	require.NoError(t, err)
+	return a
+}
+
+func TestLogin_WrongPassword(t *testing.T) {
+	a := newTestAAA(t)
+
+	_, _, err := a.Login("admin", "wrong")
+
+	require.EqualError(t, err, "wrong password")
+}
+
+func TestLogin_HashedAdminPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", string(hash))
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	hashed, err := New(time.Minute, noopLogger)
+	require.NoError(t, err)
+	plain := newTestAAA(t)
+
+	for name, a := range map[string]AAA{"hashed": hashed, "plaintext": plain} {
+		_, _, err = a.Login("admin", "password")
+		assert.NoError(t, err, name)
+		_, _, err = a.Login("admin", "wrong")
+		assert.EqualError(t, err, "wrong password", name)
+	}
+	_, _, err = hashed.Login("admin", string(hash))
+	assert.EqualError(t, err, "wrong password", "the hash is not a password")
+}
+
+func TestVerify_IssuerAndAudience(t *testing.T) {
+	a := newTestAAA(t, WithIssuer("xkcd-api"), WithAudience("xkcd-clients"))
+
+	access, refresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+
+	require.NoError(t, a.Verify(access))
+	_, _, err = a.RefreshAccessToken(refresh)
+	require.NoError(t, err)
+}
+
+func TestVerify_IssuerMismatch(t *testing.T) {
+	issuer := newTestAAA(t, WithIssuer("other-api"))
+	verifier := newTestAAA(t, WithIssuer("xkcd-api"))
+
+	access, refresh, err := issuer.Login("admin", "password")
+	require.NoError(t, err)
+
+	assert.Error(t, verifier.Verify(access))
+	_, _, err = verifier.RefreshAccessToken(refresh)
+	assert.Error(t, err)
+}
+
+func TestVerify_AudienceMismatch(t *testing.T) {
+	issuer := newTestAAA(t, WithAudience("other-clients"))
+	verifier := newTestAAA(t, WithAudience("xkcd-clients"))
+
+	access, _, err := issuer.Login("admin", "password")
+	require.NoError(t, err)
+
+	assert.Error(t, verifier.Verify(access))
+}
+
+func TestVerify_ClaimsNotRequiredWhenUnconfigured(t *testing.T) {
+	issuer := newTestAAA(t, WithIssuer("xkcd-api"), WithAudience("xkcd-clients"))
+	verifier := newTestAAA(t)
+
+	access, _, err := issuer.Login("admin", "password")
+	require.NoError(t, err)
+
+	assert.NoError(t, verifier.Verify(access))
+}
+
+func TestRefresh_FixedTTL(t *testing.T) {
+	a := newTestAAA(t, WithRefreshTTL(time.Hour))
+
+	_, refresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+
+	for range 2 {
+		access, rotated, err := a.RefreshAccessToken(refresh)
+		require.NoError(t, err)
+		assert.Empty(t, rotated)
+		assert.NoError(t, a.Verify(access))
+	}
+}
+
+func TestRefresh_ExpiredRefreshToken(t *testing.T) {
+	a := newTestAAA(t, WithRefreshTTL(time.Nanosecond))
+
+	_, refresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	_, _, err = a.RefreshAccessToken(refresh)
+	assert.Error(t, err)
+}
+
+func TestRefresh_SlidingRotation(t *testing.T) {
+	a := newTestAAA(t, WithSlidingRefresh())
+
+	_, refresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+
+	access, rotated, err := a.RefreshAccessToken(refresh)
+	require.NoError(t, err)
+	require.NotEmpty(t, rotated)
+	assert.NotEqual(t, refresh, rotated)
+	assert.NoError(t, a.Verify(access))
+
+	_, _, err = a.RefreshAccessToken(refresh)
+	assert.Error(t, err, "rotated out token must stop working")
+
+	_, next, err := a.RefreshAccessToken(rotated)
+	require.NoError(t, err)
+	assert.NotEmpty(t, next)
+}
+
+func TestNew_WrongRefreshTTL(t *testing.T) {
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", "password")
+	t.Setenv("JWT_SECRET_KEY", "secret")
+
+	_, err := New(time.Minute, noopLogger, WithRefreshTTL(-time.Hour))
+
+	assert.Error(t, err)
+}
+
+func TestLogin_MaxSessionsEvictsOldest(t *testing.T) {
+	a := newTestAAA(t, WithMaxSessions(2))
+
+	var refreshTokens []string
+	for range 3 {
+		_, refresh, err := a.Login("admin", "password")
+		require.NoError(t, err)
+		refreshTokens = append(refreshTokens, refresh)
+	}
+
+	_, _, err := a.RefreshAccessToken(refreshTokens[0])
+	assert.Error(t, err, "oldest session must be evicted")
+	for _, refresh := range refreshTokens[1:] {
+		_, _, err := a.RefreshAccessToken(refresh)
+		assert.NoError(t, err)
+	}
+}
+
+func TestLogin_MaxSessionsAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions")
+	before := newTestAAA(t, WithMaxSessions(2), WithSessionStore(NewFileSessions(path)))
+	var refreshTokens []string
+	for range 2 {
+		_, refresh, err := before.Login("admin", "password")
+		require.NoError(t, err)
+		refreshTokens = append(refreshTokens, refresh)
+	}
+
+	after := newTestAAA(t, WithMaxSessions(2), WithSessionStore(NewFileSessions(path)))
+	_, _, err := after.Login("admin", "password")
+	require.NoError(t, err)
+
+	_, _, err = after.RefreshAccessToken(refreshTokens[0])
+	assert.Error(t, err, "oldest session before restart must be evicted")
+	_, _, err = after.RefreshAccessToken(refreshTokens[1])
+	assert.NoError(t, err)
+}
+
+func TestLogin_MaxSessionsWithSlidingRefresh(t *testing.T) {
+	a := newTestAAA(t, WithMaxSessions(2), WithSlidingRefresh())
+
+	_, first, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	_, first, err = a.RefreshAccessToken(first)
+	require.NoError(t, err)
+	_, second, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	_, _, err = a.Login("admin", "password")
+	require.NoError(t, err)
+
+	_, _, err = a.RefreshAccessToken(first)
+	assert.Error(t, err, "rotated session keeps its age and is evicted first")
+	_, _, err = a.RefreshAccessToken(second)
+	assert.NoError(t, err)
+}
+
+func TestIntrospect(t *testing.T) {
+	a := newTestAAA(t)
+
+	access, _, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	info := a.Introspect(access)
+	assert.True(t, info.Active)
+	assert.Equal(t, "admin", info.Name)
+	assert.Equal(t, "superuser", info.Role)
+	assert.Equal(t, "access", info.Type)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), info.ExpiresAt, 2*time.Second)
+	assert.WithinDuration(t, time.Now(), info.IssuedAt, 2*time.Second)
+
+	expired, err := a.newToken("admin", "access", -time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, core.TokenInfo{}, a.Introspect(expired))
+
+	t.Setenv("JWT_SECRET_KEY", "forged")
+	forger, err := New(time.Minute, noopLogger)
+	require.NoError(t, err)
+	forged, _, err := forger.Login("admin", "password")
+	require.NoError(t, err)
+	assert.False(t, a.Introspect(forged).Active)
+}
+
+type fakeRevocationStore map[string]time.Time
+
+func (f fakeRevocationStore) Load() (map[string]time.Time, error) {
+	return maps.Clone(f), nil
+}
+
+func (f fakeRevocationStore) Add(id string, until time.Time) error {
+	f[id] = until
+	return nil
+}
+
+func TestRefresh_RevokedSurvivesRestart(t *testing.T) {
+	store := fakeRevocationStore{}
+	a := newTestAAA(t, WithSlidingRefresh(), WithRevocationStore(store))
+
+	_, refresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	_, rotated, err := a.RefreshAccessToken(refresh)
+	require.NoError(t, err)
+	require.Len(t, store, 1)
+
+	restarted := newTestAAA(t, WithSlidingRefresh(), WithRevocationStore(store))
+	_, _, err = restarted.RefreshAccessToken(refresh)
+	assert.Error(t, err, "revoked token must stay revoked after restart")
+	_, _, err = restarted.RefreshAccessToken(rotated)
+	assert.NoError(t, err)
+}
+
+func TestFileRevocations_DropsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations")
+	store := NewFileRevocations(path)
+
+	revoked, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, revoked)
+
+	live := time.Now().Add(time.Hour).Truncate(time.Second)
+	require.NoError(t, store.Add("live", live))
+	require.NoError(t, store.Add("expired", time.Now().Add(-time.Hour)))
+
+	revoked, err = NewFileRevocations(path).Load()
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+	assert.True(t, live.Equal(revoked["live"]))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "expired")
+}
+
+func TestFileRevocations_CompactsOnAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations")
+	store := NewFileRevocations(path)
+	_, err := store.Load()
+	require.NoError(t, err)
+
+	live := time.Now().Add(time.Hour)
+	require.NoError(t, store.Add("live", live))
+	for i := range minCompactLines - 1 {
+		require.NoError(t, store.Add(fmt.Sprintf("expired-%d", i), time.Now().Add(-time.Hour)))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"), "expired revocations are compacted away")
+	assert.Contains(t, string(data), `"live"`)
+}
+
+func writeUsersFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLogin_UsersFile(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("operator-secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	t.Setenv("USERS_FILE", writeUsersFile(t, `{"operator": "`+string(hash)+`"}`))
+	a := newTestAAA(t)
+
+	_, _, err = a.Login("operator", "operator-secret")
+	require.NoError(t, err)
+	_, _, err = a.Login("operator", string(hash))
+	require.EqualError(t, err, "wrong password")
+	_, _, err = a.Login("admin", "password")
+	require.NoError(t, err, "admin of the environment is kept")
+}
+
+func TestNew_UsersFileWithoutAdmin(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	t.Setenv("USERS_FILE", writeUsersFile(t, "alice: "+string(hash)+"\nbob: "+string(hash)+"\n"))
+	t.Setenv("JWT_SECRET_KEY", "secret")
+
+	a, err := New(time.Minute, noopLogger)
+	require.NoError(t, err)
+	for _, name := range []string{"alice", "bob"} {
+		_, _, err = a.Login(name, "secret")
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestNew_UsersFileMissingOrBroken(t *testing.T) {
+	t.Setenv("USERS_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	a := newTestAAA(t)
+	_, _, err := a.Login("admin", "password")
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{
+		"syntax":    "alice: [",
+		"plaintext": "alice: secret",
+	} {
+		t.Setenv("USERS_FILE", writeUsersFile(t, content))
+		_, err := New(time.Minute, noopLogger)
+		assert.ErrorContains(t, err, "cannot parse users file", name)
+	}
+}
+
+func TestRevoke_AccessAndRefresh(t *testing.T) {
+	a := newTestAAA(t)
+
+	access, refresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	other, otherRefresh, err := a.Login("admin", "password")
+	require.NoError(t, err)
+
+	require.NoError(t, a.Revoke(access))
+	require.NoError(t, a.Revoke(refresh))
+	assert.EqualError(t, a.Verify(access), "token is revoked")
+	assert.NoError(t, a.Verify(other), "other tokens stay valid")
+	_, _, err = a.RefreshAccessToken(refresh)
+	assert.EqualError(t, err, "token is revoked")
+	_, _, err = a.RefreshAccessToken(otherRefresh)
+	assert.NoError(t, err)
+
+	assert.Error(t, a.Revoke("garbage"))
+}
+
+func TestRevoke_Expired(t *testing.T) {
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", "password")
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	a, err := New(-time.Minute, noopLogger)
+	require.NoError(t, err)
+
+	access, _, err := a.Login("admin", "password")
+	require.NoError(t, err)
+	assert.NoError(t, a.Revoke(access))
+}
@@ -0,0 +1,194 @@
+package aaa
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RevocationStore keeps revoked token ids across restarts.
+type RevocationStore interface {
+	// Load returns ids revoked before with the time they expire at.
+	Load() (map[string]time.Time, error)
+	Add(id string, until time.Time) error
+}
+
+// revocations keeps ids of revoked tokens until the tokens expire anyway.
+type revocations struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	store   RevocationStore
+	log     *slog.Logger
+}
+
+func newRevocations() *revocations {
+	return &revocations{revoked: make(map[string]time.Time)}
+}
+
+// restore loads revocations persisted by store and saves new ones to it.
+func (r *revocations) restore(store RevocationStore, log *slog.Logger) error {
+	revoked, err := store.Load()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, until := range revoked {
+		r.revoked[id] = until
+	}
+	r.prune(time.Now())
+	r.store = store
+	r.log = log
+	return nil
+}
+
+// revoke returns false if id was already revoked.
+func (r *revocations) revoke(id string, until time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(time.Now())
+	if _, ok := r.revoked[id]; ok {
+		return false
+	}
+	r.revoked[id] = until
+	if r.store != nil {
+		// the token stays revoked until restart anyway
+		if err := r.store.Add(id, until); err != nil {
+			r.log.Error("failed to persist revocation", "error", err)
+		}
+	}
+	return true
+}
+
+func (r *revocations) isRevoked(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[id]
+	return ok
+}
+
+func (r *revocations) prune(now time.Time) {
+	for id, until := range r.revoked {
+		if now.After(until) {
+			delete(r.revoked, id)
+		}
+	}
+}
+
+// minCompactLines is the fewest lines of revocations worth compacting.
+const minCompactLines = 1000
+
+// FileRevocations appends revocations to a file, one JSON line each.
+// Load drops the expired ones from it, and so does Add once the file
+// grows to twice the live ones and at least minCompactLines.
+type FileRevocations struct {
+	path string
+	mu   sync.Mutex
+	// lines are in the file, live ones were left by the last compaction
+	lines int
+	live  int
+}
+
+func NewFileRevocations(path string) *FileRevocations {
+	return &FileRevocations{path: path}
+}
+
+type revocation struct {
+	ID    string    `json:"id"`
+	Until time.Time `json:"until"`
+}
+
+// Load reads live revocations and compacts the file, a missing file is not an error.
+func (f *FileRevocations) Load() (map[string]time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	revoked, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return revoked, f.compact(revoked)
+}
+
+// read returns live revocations of the file, the caller must hold the lock.
+func (f *FileRevocations) read() (map[string]time.Time, error) {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	revoked := make(map[string]time.Time)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r revocation
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("corrupted revocations %s: %w", f.path, err)
+		}
+		if now.Before(r.Until) {
+			revoked[r.ID] = r.Until
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// compact replaces the file with live revocations at once, the caller must hold the lock.
+func (f *FileRevocations) compact(revoked map[string]time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	encoder := json.NewEncoder(tmp)
+	for id, until := range revoked {
+		if err := encoder.Encode(revocation{ID: id, Until: until}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return err
+	}
+	f.lines, f.live = len(revoked), len(revoked)
+	return nil
+}
+
+func (f *FileRevocations) Add(id string, until time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(revocation{ID: id, Until: until}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	f.lines++
+	if f.lines < max(2*f.live, minCompactLines) {
+		return nil
+	}
+	revoked, err := f.read()
+	if err != nil {
+		return err
+	}
+	return f.compact(revoked)
+}
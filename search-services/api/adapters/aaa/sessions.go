@@ -0,0 +1,152 @@
+package aaa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Session is a live refresh token of a user.
+type Session struct {
+	ID      string    `json:"id"`
+	Expires time.Time `json:"expires"`
+}
+
+// SessionStore keeps live sessions across restarts.
+type SessionStore interface {
+	// Load returns sessions saved before per user, oldest login first.
+	Load() (map[string][]Session, error)
+	Save(byUser map[string][]Session) error
+}
+
+// sessions tracks live refresh tokens per user, oldest login first.
+type sessions struct {
+	mu     sync.Mutex
+	max    int
+	byUser map[string][]Session
+	store  SessionStore
+	log    *slog.Logger
+}
+
+func newSessions(max int) *sessions {
+	return &sessions{max: max, byUser: make(map[string][]Session)}
+}
+
+// restore loads sessions persisted by store and saves changes to it.
+func (s *sessions) restore(store SessionStore, log *slog.Logger) error {
+	byUser, err := store.Load()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for user, live := range byUser {
+		s.byUser[user] = slices.DeleteFunc(live, func(other Session) bool {
+			return now.After(other.Expires)
+		})
+	}
+	s.store = store
+	s.log = log
+	return nil
+}
+
+// save persists sessions, the caller must hold the lock.
+func (s *sessions) save() {
+	if s.store == nil {
+		return
+	}
+	// sessions are still tracked until restart
+	if err := s.store.Save(s.byUser); err != nil {
+		s.log.Error("failed to persist sessions", "error", err)
+	}
+}
+
+// add starts a session of user and returns the oldest ones over the limit.
+func (s *sessions) add(user string, started Session) []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	live := slices.DeleteFunc(s.byUser[user], func(other Session) bool {
+		return now.After(other.Expires)
+	})
+	live = append(live, started)
+	var evicted []Session
+	if over := len(live) - s.max; over > 0 {
+		evicted = slices.Clone(live[:over])
+		live = slices.Delete(live, 0, over)
+	}
+	s.byUser[user] = live
+	s.save()
+	return evicted
+}
+
+// rotate continues a session under a new refresh token, keeping its age.
+func (s *sessions) rotate(user, oldID string, rotated Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.byUser[user], func(other Session) bool {
+		return other.ID == oldID
+	})
+	if i >= 0 {
+		s.byUser[user][i] = rotated
+		s.save()
+	}
+}
+
+// end drops a session revoked before it expired.
+func (s *sessions) end(user, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byUser[user] = slices.DeleteFunc(s.byUser[user], func(other Session) bool {
+		return other.ID == id
+	})
+	s.save()
+}
+
+// FileSessions keeps sessions in a JSON file, replaced at once on every save.
+type FileSessions struct {
+	path string
+}
+
+func NewFileSessions(path string) *FileSessions {
+	return &FileSessions{path: path}
+}
+
+// Load reads saved sessions, a missing file is not an error.
+func (f *FileSessions) Load() (map[string][]Session, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]Session{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var byUser map[string][]Session
+	if err := json.Unmarshal(data, &byUser); err != nil {
+		return nil, fmt.Errorf("corrupted sessions %s: %w", f.path, err)
+	}
+	return byUser, nil
+}
+
+func (f *FileSessions) Save(byUser map[string][]Session) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(byUser); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -0,0 +1,59 @@
+package aaa
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// credential is a user password, plaintext or a bcrypt hash of it.
+type credential struct {
+	secret string
+	hashed bool
+}
+
+func (c credential) matches(password string) bool {
+	if c.hashed {
+		return bcrypt.CompareHashAndPassword([]byte(c.secret), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(c.secret), []byte(password)) == 1
+}
+
+// envCredential takes a password of the environment for a bcrypt hash
+// when it looks like one.
+func envCredential(password string) credential {
+	hashed := strings.HasPrefix(password, "$2a$") || strings.HasPrefix(password, "$2b$")
+	return credential{secret: password, hashed: hashed}
+}
+
+// loadUsers reads a YAML or JSON map of user names to bcrypt hashes,
+// no path or a missing file gives no users.
+func loadUsers(path string) (map[string]credential, error) {
+	users := make(map[string]credential)
+	if path == "" {
+		return users, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return users, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read users file %s: %w", path, err)
+	}
+	var hashes map[string]string
+	if err := yaml.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("cannot parse users file %s: %w", path, err)
+	}
+	for name, hash := range hashes {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("cannot parse users file %s: user %q: %w", path, name, err)
+		}
+		users[name] = credential{secret: hash, hashed: true}
+	}
+	return users, nil
+}
@@ -0,0 +1,106 @@
+package explainxkcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+var ErrBreakerOpen = fmt.Errorf("explainxkcd circuit breaker is open: %w", core.ErrUnavailable)
+
+// Breaker stops calls to explainxkcd after threshold consecutive failures,
+// once cooldown passes a single trial call decides whether it closes again.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) (*Breaker, error) {
+	if threshold < 1 {
+		return nil, fmt.Errorf("wrong breaker threshold specified: %d", threshold)
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown}, nil
+}
+
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state()
+}
+
+func (b *Breaker) Status() core.BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return core.BreakerStatus{
+		State: string(b.state()), Failures: b.failures, Threshold: b.threshold, OpenedAt: b.openedAt,
+	}
+}
+
+// Reset closes the breaker without waiting for the cooldown, a trial call
+// still running finishes as a regular one.
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *Breaker) state() BreakerState {
+	switch {
+	case b.failures < b.threshold:
+		return BreakerClosed
+	case time.Since(b.openedAt) < b.cooldown:
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+// Allow reserves a call, every allowed call must be finished with Done.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state() {
+	case BreakerClosed:
+		return nil
+	case BreakerHalfOpen:
+		if !b.trial {
+			b.trial = true
+			return nil
+		}
+	}
+	return ErrBreakerOpen
+}
+
+// Done records the outcome of an allowed call, missing pages and
+// cancelled requests are not upstream failures.
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	switch {
+	case err == nil, errors.Is(err, core.ErrNotFound):
+		b.failures = 0
+	case errors.Is(err, context.Canceled):
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openedAt = time.Now()
+		}
+	}
+}
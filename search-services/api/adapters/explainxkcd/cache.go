@@ -0,0 +1,168 @@
+package explainxkcd
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+type cacheEntry struct {
+	page    string
+	info    core.ExplainXKCDInfo
+	expires time.Time
+}
+
+// Cache keeps explanations for ttl, misses are passed to the explainer.
+// Past maxEntries the least recently used explanation is evicted, so any
+// page asked for cannot grow it for good.
+type Cache struct {
+	explainer  core.Explainer
+	ttl        time.Duration
+	maxEntries int
+	// ttls override ttl per page
+	ttls map[string]time.Duration
+	mu   sync.Mutex
+	// entries are keyed by page, comics pages are named by their id
+	entries map[string]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+}
+
+type CacheOption func(*Cache)
+
+// WithTTLs keeps explanations of some comics for their own ttl, so that
+// often corrected pages expire sooner. Zero bounds leave that side open.
+func WithTTLs(ttls map[int]time.Duration, minTTL, maxTTL time.Duration) CacheOption {
+	return func(c *Cache) {
+		for id, ttl := range ttls {
+			if minTTL > 0 {
+				ttl = max(ttl, minTTL)
+			}
+			if maxTTL > 0 {
+				ttl = min(ttl, maxTTL)
+			}
+			c.ttls[strconv.Itoa(id)] = ttl
+		}
+	}
+}
+
+// WithMaxEntries bounds the cached explanations, zero keeps them all.
+func WithMaxEntries(n int) CacheOption {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
+
+func NewCache(explainer core.Explainer, ttl time.Duration, opts ...CacheOption) *Cache {
+	c := &Cache{
+		explainer: explainer,
+		ttl:       ttl,
+		ttls:      make(map[string]time.Duration),
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// TTL tells how long an explanation of page is kept.
+func (c *Cache) TTL(page string) time.Duration {
+	if ttl, ok := c.ttls[page]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+func (c *Cache) Explain(ctx context.Context, id int) (core.ExplainXKCDInfo, error) {
+	return c.explain(strconv.Itoa(id), func() (core.ExplainXKCDInfo, error) {
+		return c.explainer.Explain(ctx, id)
+	})
+}
+
+func (c *Cache) ExplainPage(ctx context.Context, page string) (core.ExplainXKCDInfo, error) {
+	return c.explain(page, func() (core.ExplainXKCDInfo, error) {
+		return c.explainer.ExplainPage(ctx, page)
+	})
+}
+
+// Refresh fetches an explanation of id past the cache and keeps it, the
+// explainer still guards the fetch with its breaker.
+func (c *Cache) Refresh(ctx context.Context, id int) (core.ExplainXKCDInfo, error) {
+	return c.refresh(strconv.Itoa(id), func() (core.ExplainXKCDInfo, error) {
+		return c.explainer.Explain(ctx, id)
+	})
+}
+
+func (c *Cache) RefreshPage(ctx context.Context, page string) (core.ExplainXKCDInfo, error) {
+	return c.refresh(page, func() (core.ExplainXKCDInfo, error) {
+		return c.explainer.ExplainPage(ctx, page)
+	})
+}
+
+func (c *Cache) explain(page string, fetch func() (core.ExplainXKCDInfo, error)) (core.ExplainXKCDInfo, error) {
+	if info, ok := c.get(page); ok {
+		return info, nil
+	}
+	return c.refresh(page, fetch)
+}
+
+func (c *Cache) refresh(page string, fetch func() (core.ExplainXKCDInfo, error)) (core.ExplainXKCDInfo, error) {
+	info, err := fetch()
+	if err != nil {
+		return core.ExplainXKCDInfo{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cacheEntry{page: page, info: info, expires: time.Now().Add(c.TTL(page))}
+	if element, ok := c.entries[page]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return info, nil
+	}
+	c.entries[page] = c.order.PushFront(entry)
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return info, nil
+}
+
+// Len is the number of kept explanations, expired ones included until
+// they are evicted or asked for.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Cached tells whether a fresh explanation of id is kept.
+func (c *Cache) Cached(id int) bool {
+	_, ok := c.get(strconv.Itoa(id))
+	return ok
+}
+
+func (c *Cache) get(page string) (core.ExplainXKCDInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[page]
+	if !ok {
+		return core.ExplainXKCDInfo{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return core.ExplainXKCDInfo{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry.info, true
+}
+
+// remove drops an entry, the caller must hold the lock.
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).page)
+}
@@ -0,0 +1,94 @@
+package explainxkcd
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_TTLOverridesClamped(t *testing.T) {
+	up := newUpstream(t)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger)
+	require.NoError(t, err)
+	cache := NewCache(client, time.Hour, WithTTLs(map[int]time.Duration{
+		1: -time.Second,
+		2: time.Minute,
+		3: 48 * time.Hour,
+	}, time.Millisecond, 24*time.Hour))
+
+	assert.Equal(t, time.Millisecond, cache.TTL("1"))
+	assert.Equal(t, time.Minute, cache.TTL("2"))
+	assert.Equal(t, 24*time.Hour, cache.TTL("3"))
+	assert.Equal(t, time.Hour, cache.TTL("4"), "global TTL by default")
+
+	ctx := context.Background()
+	_, err = cache.Explain(ctx, 1)
+	require.NoError(t, err)
+	_, err = cache.Explain(ctx, 4)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	assert.False(t, cache.Cached(1), "short override expired")
+	assert.True(t, cache.Cached(4))
+	assert.EqualValues(t, 2, up.hits.Load())
+}
+
+func TestCache_RefreshFetchesAndKeeps(t *testing.T) {
+	ctx := context.Background()
+	up := newUpstream(t)
+	breaker, err := NewBreaker(1, time.Hour)
+	require.NoError(t, err)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger, WithBreaker(breaker))
+	require.NoError(t, err)
+	cache := NewCache(client, time.Hour)
+
+	_, err = cache.Explain(ctx, 1)
+	require.NoError(t, err)
+	info, err := cache.Refresh(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>explained</p>", info.HTML)
+	assert.EqualValues(t, 2, up.hits.Load(), "refresh skips the cache")
+
+	_, err = cache.RefreshPage(ctx, "Main Page")
+	require.NoError(t, err)
+	_, err = cache.ExplainPage(ctx, "Main Page")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, up.hits.Load(), "refreshed page is cached")
+
+	up.fail.Store(true)
+	_, err = cache.Refresh(ctx, 1)
+	require.Error(t, err)
+	up.fail.Store(false)
+	_, err = cache.Refresh(ctx, 1)
+	require.ErrorIs(t, err, ErrBreakerOpen)
+	assert.EqualValues(t, 4, up.hits.Load(), "open breaker stops refreshes")
+	assert.True(t, cache.Cached(1), "failed refresh keeps the cached explanation")
+}
+
+func TestCache_MaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	up := newUpstream(t)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger)
+	require.NoError(t, err)
+	cache := NewCache(client, time.Hour, WithMaxEntries(2))
+
+	for _, id := range []int{1, 2, 1, 3} {
+		_, err := cache.Explain(ctx, id)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, cache.Len())
+	assert.True(t, cache.Cached(1), "recently used explanation stays")
+	assert.False(t, cache.Cached(2))
+	assert.True(t, cache.Cached(3))
+	assert.EqualValues(t, 3, up.hits.Load())
+
+	for page := range 10 {
+		_, err := cache.ExplainPage(ctx, "Page "+strconv.Itoa(page))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, cache.Len(), "pages cannot grow the cache")
+}
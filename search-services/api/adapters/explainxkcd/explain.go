@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/liy0aay/xkcd-search/api/core"
@@ -13,20 +15,54 @@ import (
 )
 
 type Client struct {
-	client http.Client
-	url    string
-	log    *slog.Logger
+	client    http.Client
+	url       string
+	log       *slog.Logger
+	breaker   *Breaker
+	transport *http.Transport
 }
 
-func NewClient(url string, timeout time.Duration, log *slog.Logger) (*Client, error) {
-	if url == "" {
+type Option func(*Client)
+
+// WithBreaker guards explainxkcd calls with the circuit breaker.
+func WithBreaker(breaker *Breaker) Option {
+	return func(c *Client) {
+		c.breaker = breaker
+	}
+}
+
+// WithTransport sends requests through a shared transport to reuse its
+// connections, a proxy gets a copy of it.
+func WithTransport(transport *http.Transport) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// NewClient sends requests through proxy if set, otherwise HTTP_PROXY/HTTPS_PROXY are honored.
+func NewClient(baseURL, proxy string, timeout time.Duration, log *slog.Logger, opts ...Option) (*Client, error) {
+	if baseURL == "" {
 		return nil, fmt.Errorf("empty base url specified")
 	}
-	return &Client{
-		client: http.Client{},
-		url:    url,
-		log:    log,
-	}, nil
+	c := &Client{
+		url:       baseURL,
+		log:       log,
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	transport := c.transport
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %v", err)
+		}
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	c.client = http.Client{Transport: transport}
+	return c, nil
 }
 
 func (c *Client) Close() error {
@@ -34,10 +70,41 @@ func (c *Client) Close() error {
 }
 
 func (c Client) Explain(ctx context.Context, id int) (core.ExplainXKCDInfo, error) {
+	info, err := c.ExplainPage(ctx, strconv.Itoa(id))
+	// comics explanations keep their shape without a page
+	info.ID, info.Page = id, ""
+	return info, err
+}
+
+// ExplainPage explains a wiki page by its title, for comics whose page
+// is not named by their number.
+func (c Client) ExplainPage(ctx context.Context, page string) (core.ExplainXKCDInfo, error) {
+	if c.breaker == nil {
+		return c.explain(ctx, page)
+	}
+	if isBackground(ctx) {
+		// warming does not trip a closed breaker, but makes the trial call
+		// of a half-open one, so it recovers without traffic
+		switch c.breaker.State() {
+		case BreakerClosed:
+			return c.explain(ctx, page)
+		case BreakerOpen:
+			return core.ExplainXKCDInfo{}, ErrBreakerOpen
+		}
+	}
+	if err := c.breaker.Allow(); err != nil {
+		return core.ExplainXKCDInfo{}, err
+	}
+	info, err := c.explain(ctx, page)
+	c.breaker.Done(err)
+	return info, err
+}
+
+func (c Client) explain(ctx context.Context, page string) (core.ExplainXKCDInfo, error) {
 	reqURL := fmt.Sprintf(
-		"%s/wiki/api.php?action=parse&page=%d&prop=text&sectiontitle=Explanation&redirects=1&format=json",
+		"%s/wiki/api.php?action=parse&page=%s&prop=text&sectiontitle=Explanation&redirects=1&format=json",
 		c.url,
-		id,
+		url.QueryEscape(page),
 	)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
@@ -71,5 +138,5 @@ func (c Client) Explain(ctx context.Context, id int) (core.ExplainXKCDInfo, erro
 		return core.ExplainXKCDInfo{}, fmt.Errorf("no explanation found")
 	}
 
-	return core.ExplainXKCDInfo{ID: id, HTML: html}, nil
+	return core.ExplainXKCDInfo{Page: page, HTML: html}, nil
 }
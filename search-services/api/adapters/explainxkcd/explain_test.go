@@ -0,0 +1,89 @@
+package explainxkcd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ExplainByIDAndPage(t *testing.T) {
+	var pages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages = append(pages, r.URL.Query().Get("page"))
+		_, _ = w.Write([]byte(`{"parse": {"text": {"*": "<p>explained</p>"}}}`))
+	}))
+	defer srv.Close()
+	client, err := NewClient(srv.URL, "", time.Second, noopLogger)
+	require.NoError(t, err)
+
+	info, err := client.Explain(context.Background(), 353)
+	require.NoError(t, err)
+	assert.Equal(t, 353, info.ID)
+	assert.Empty(t, info.Page)
+	assert.Equal(t, "<p>explained</p>", info.HTML)
+
+	info, err = client.ExplainPage(context.Background(), "1608: Hoverboard & more")
+	require.NoError(t, err)
+	assert.Equal(t, 0, info.ID)
+	assert.Equal(t, "1608: Hoverboard & more", info.Page)
+
+	assert.Equal(t, []string{"353", "1608: Hoverboard & more"}, pages)
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host+r.URL.Path)
+		_, _ = w.Write([]byte(`{"parse": {"text": {"*": "<p>explained</p>"}}}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient("http://explainxkcd.invalid", proxy.URL, time.Second, noopLogger)
+	require.NoError(t, err)
+
+	info, err := client.Explain(context.Background(), 353)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>explained</p>", info.HTML)
+	assert.Equal(t, []string{"explainxkcd.invalid/wiki/api.php"}, proxied)
+}
+
+func TestNewClient_InvalidProxy(t *testing.T) {
+	_, err := NewClient("https://www.explainxkcd.com", "://bad", time.Second, noopLogger)
+	require.Error(t, err)
+}
+
+func TestBreaker_ResetReenablesCalls(t *testing.T) {
+	ctx := context.Background()
+	up := newUpstream(t)
+	breaker, err := NewBreaker(2, time.Hour)
+	require.NoError(t, err)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger, WithBreaker(breaker))
+	require.NoError(t, err)
+
+	up.fail.Store(true)
+	for range 2 {
+		_, err = client.Explain(ctx, 1)
+		require.Error(t, err)
+	}
+	status := breaker.Status()
+	assert.Equal(t, string(BreakerOpen), status.State)
+	assert.Equal(t, 2, status.Failures)
+	assert.Equal(t, 2, status.Threshold)
+	assert.False(t, status.OpenedAt.IsZero())
+
+	up.fail.Store(false)
+	_, err = client.Explain(ctx, 1)
+	require.ErrorIs(t, err, ErrBreakerOpen)
+	assert.EqualValues(t, 2, up.hits.Load())
+
+	breaker.Reset()
+	assert.Equal(t, string(BreakerClosed), breaker.Status().State)
+	_, err = client.Explain(ctx, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, up.hits.Load())
+}
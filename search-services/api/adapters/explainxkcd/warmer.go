@@ -0,0 +1,133 @@
+package explainxkcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+type backgroundKey struct{}
+
+// background marks warming calls, they wait for the cooldown of an open
+// breaker and only the trial call of a half-open one is counted by it.
+func background(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
+
+func isBackground(ctx context.Context) bool {
+	marked, _ := ctx.Value(backgroundKey{}).(bool)
+	return marked
+}
+
+// Warmer fills the explanation cache ahead of requests, pausing while
+// the breaker is open. Once it is half-open the warmer makes the trial call.
+type Warmer struct {
+	log         *slog.Logger
+	explainer   core.Explainer
+	breaker     *Breaker
+	concurrency int
+	poll        time.Duration
+}
+
+func NewWarmer(log *slog.Logger, explainer core.Explainer, breaker *Breaker, concurrency int) (*Warmer, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("wrong warmer concurrency specified: %d", concurrency)
+	}
+	return &Warmer{
+		log:         log,
+		explainer:   explainer,
+		breaker:     breaker,
+		concurrency: concurrency,
+		poll:        time.Second,
+	}, nil
+}
+
+// Warm explains ids and returns how many succeeded.
+func (w *Warmer) Warm(ctx context.Context, ids []int) int {
+	ctx = background(ctx)
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for _, id := range ids {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- id:
+			}
+		}
+	}()
+
+	var warmed atomic.Int64
+	var wg sync.WaitGroup
+	for range w.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ch {
+				ok, err := w.warm(ctx, id)
+				if err != nil {
+					return
+				}
+				if ok {
+					warmed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return int(warmed.Load())
+}
+
+// warm explains id once the breaker lets it, an id refused while another
+// call makes the trial of a half-open breaker is tried again.
+func (w *Warmer) warm(ctx context.Context, id int) (bool, error) {
+	for {
+		if err := w.waitCooldown(ctx); err != nil {
+			return false, err
+		}
+		_, err := w.explainer.Explain(ctx, id)
+		if errors.Is(err, ErrBreakerOpen) {
+			if err := w.pause(ctx); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if err != nil {
+			w.log.Debug("failed to warm explanation", "id", id, "error", err)
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func (w *Warmer) pause(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(w.poll):
+		return nil
+	}
+}
+
+// waitCooldown waits while the breaker is open.
+func (w *Warmer) waitCooldown(ctx context.Context) error {
+	if w.breaker == nil {
+		return ctx.Err()
+	}
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+	for w.breaker.State() == BreakerOpen {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return ctx.Err()
+}
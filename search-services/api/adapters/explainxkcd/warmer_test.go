@@ -0,0 +1,118 @@
+package explainxkcd
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var noopLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+type upstream struct {
+	*httptest.Server
+	hits atomic.Int64
+	fail atomic.Bool
+}
+
+func newUpstream(t *testing.T) *upstream {
+	u := &upstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.hits.Add(1)
+		if u.fail.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"parse": {"text": {"*": "<p>explained</p>"}}}`))
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func TestWarmer_PausesWhileBreakerOpen(t *testing.T) {
+	ctx := context.Background()
+	up := newUpstream(t)
+	breaker, err := NewBreaker(1, 100*time.Millisecond)
+	require.NoError(t, err)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger, WithBreaker(breaker))
+	require.NoError(t, err)
+	cache := NewCache(client, time.Hour)
+
+	up.fail.Store(true)
+	_, err = cache.Explain(ctx, 1)
+	require.Error(t, err)
+	require.Equal(t, BreakerOpen, breaker.State())
+	up.fail.Store(false)
+
+	warmer, err := NewWarmer(noopLogger, cache, breaker, 2)
+	require.NoError(t, err)
+	warmer.poll = time.Millisecond
+	done := make(chan int)
+	go func() {
+		done <- warmer.Warm(ctx, []int{2, 3, 4})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.EqualValues(t, 1, up.hits.Load(), "warmer must not call while the breaker is open")
+	select {
+	case <-done:
+		t.Fatal("warmer finished while breaker was open")
+	default:
+	}
+
+	// once half-open, the warmer probes and closes the breaker without requests
+	select {
+	case warmed := <-done:
+		assert.Equal(t, 3, warmed)
+	case <-time.After(time.Second):
+		t.Fatal("warmer did not resume after cooldown")
+	}
+	assert.Equal(t, BreakerClosed, breaker.State())
+	for _, id := range []int{2, 3, 4} {
+		assert.True(t, cache.Cached(id))
+	}
+}
+
+func TestWarmer_FailedTrialReopensBreaker(t *testing.T) {
+	up := newUpstream(t)
+	breaker, err := NewBreaker(1, 0)
+	require.NoError(t, err)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger, WithBreaker(breaker))
+	require.NoError(t, err)
+	cache := NewCache(client, time.Hour)
+
+	up.fail.Store(true)
+	_, err = cache.Explain(context.Background(), 1)
+	require.Error(t, err)
+	require.Equal(t, BreakerHalfOpen, breaker.State())
+	openedAt := breaker.Status().OpenedAt
+
+	warmer, err := NewWarmer(noopLogger, cache, breaker, 1)
+	require.NoError(t, err)
+	assert.Zero(t, warmer.Warm(context.Background(), []int{2}))
+	assert.True(t, breaker.Status().OpenedAt.After(openedAt), "the failed trial is counted")
+}
+
+func TestWarmer_FailuresDoNotTripBreaker(t *testing.T) {
+	up := newUpstream(t)
+	up.fail.Store(true)
+	breaker, err := NewBreaker(1, time.Hour)
+	require.NoError(t, err)
+	client, err := NewClient(up.URL, "", time.Second, noopLogger, WithBreaker(breaker))
+	require.NoError(t, err)
+	warmer, err := NewWarmer(noopLogger, NewCache(client, time.Hour), breaker, 1)
+	require.NoError(t, err)
+
+	warmed := warmer.Warm(context.Background(), []int{1, 2})
+
+	assert.Zero(t, warmed)
+	assert.EqualValues(t, 2, up.hits.Load())
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
@@ -0,0 +1,67 @@
+package grace
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clock lets tests retry without waiting.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Interceptor retries gRPC calls failing as a starting backend does, for up
+// to window after the interceptor is made at process start. Unimplemented
+// calls never reached a handler and are retried for any method, Unavailable
+// ones may have been served and are retried unless the method is mutating.
+// Waits between attempts start at backoff and double, zero window disables
+// retries.
+func Interceptor(window, backoff time.Duration, log *slog.Logger, mutating ...string) grpc.UnaryClientInterceptor {
+	return interceptor(window, backoff, log, mutating, realClock{})
+}
+
+func interceptor(
+	window, backoff time.Duration, log *slog.Logger, mutating []string, clock clock,
+) grpc.UnaryClientInterceptor {
+	deadline := clock.Now().Add(window)
+	return func(
+		ctx context.Context, method string, req, reply any,
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		retried := func(err error) bool {
+			switch status.Code(err) {
+			case codes.Unimplemented:
+				return true
+			case codes.Unavailable:
+				return !slices.Contains(mutating, method)
+			}
+			return false
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		for wait := backoff; retried(err); wait *= 2 {
+			if clock.Now().Add(wait).After(deadline) {
+				return err
+			}
+			log.Warn("backend is starting, retrying call", "method", method, "wait", wait, "error", err)
+			select {
+			case <-ctx.Done():
+				return err
+			case <-clock.After(wait):
+			}
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return err
+	}
+}
@@ -0,0 +1,122 @@
+package grace
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var noopLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// startingInvoker fails with code until it is called the given times.
+func startingInvoker(code codes.Code, failures int, calls *int) grpc.UnaryInvoker {
+	return func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return status.Error(code, "starting")
+		}
+		return nil
+	}
+}
+
+// fakeClock moves on by the waits asked for instead of sleeping.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestInterceptor_RetriesWithinWindow(t *testing.T) {
+	interceptor := Interceptor(time.Second, 10*time.Millisecond, noopLogger)
+	for _, code := range []codes.Code{codes.Unavailable, codes.Unimplemented} {
+		var calls int
+		err := interceptor(context.Background(), "/search.Search/Search", nil, nil, nil,
+			startingInvoker(code, 2, &calls))
+		assert.NoError(t, err, code)
+		assert.Equal(t, 3, calls, code)
+	}
+}
+
+func TestInterceptor_GivesUpAfterWindow(t *testing.T) {
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	retrying := interceptor(50*time.Millisecond, 10*time.Millisecond, noopLogger, nil, clock)
+	var calls int
+	err := retrying(context.Background(), "/search.Search/Search", nil, nil, nil,
+		startingInvoker(codes.Unavailable, 100, &calls))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	// waits of 10ms and 20ms fit the window, 40ms more does not
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 30*time.Millisecond, clock.now.Sub(start))
+}
+
+func TestInterceptor_WindowFromStart(t *testing.T) {
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	retrying := interceptor(50*time.Millisecond, 10*time.Millisecond, noopLogger, nil, clock)
+	clock.now = start.Add(time.Minute)
+
+	var calls int
+	err := retrying(context.Background(), "/search.Search/Search", nil, nil, nil,
+		startingInvoker(codes.Unavailable, 1, &calls))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestInterceptor_MutatingNotRetriedWhenUnavailable(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	retrying := interceptor(time.Second, 10*time.Millisecond, noopLogger, []string{"/update.Update/Drop"}, clock)
+
+	var calls int
+	err := retrying(context.Background(), "/update.Update/Drop", nil, nil, nil,
+		startingInvoker(codes.Unavailable, 1, &calls))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls)
+
+	// the backend had no handler yet, so the call was not served
+	calls = 0
+	err = retrying(context.Background(), "/update.Update/Drop", nil, nil, nil,
+		startingInvoker(codes.Unimplemented, 1, &calls))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestInterceptor_NoRetries(t *testing.T) {
+	var calls int
+	err := Interceptor(time.Second, time.Millisecond, noopLogger)(context.Background(), "/search.Search/Search",
+		nil, nil, nil, startingInvoker(codes.Internal, 1, &calls))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = Interceptor(0, time.Millisecond, noopLogger)(context.Background(), "/search.Search/Search",
+		nil, nil, nil, startingInvoker(codes.Unavailable, 1, &calls))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestInterceptor_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls int
+	err := Interceptor(time.Second, 10*time.Millisecond, noopLogger)(ctx, "/search.Search/Search",
+		nil, nil, nil, startingInvoker(codes.Unavailable, 100, &calls))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,156 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+// typeSuffix marks files holding the content type of a cached image.
+const typeSuffix = ".type"
+
+type diskCache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+	now     func() time.Time
+	lock    sync.Mutex
+}
+
+func newDiskCache(dir string, maxSize int64, ttl time.Duration) (*diskCache, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("wrong image cache size: %d", maxSize)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("wrong image cache ttl: %s", ttl)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create image cache dir: %v", err)
+	}
+	return &diskCache{dir: dir, maxSize: maxSize, ttl: ttl, now: time.Now}, nil
+}
+
+func (c *diskCache) path(imageURL string) string {
+	sum := sha256.Sum256([]byte(imageURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *diskCache) get(imageURL string) (core.Image, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.path(imageURL)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return core.Image{}, false, nil
+	}
+	if err != nil {
+		return core.Image{}, false, err
+	}
+	if c.now().Sub(info.ModTime()) > c.ttl {
+		return core.Image{}, false, c.remove(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return core.Image{}, false, err
+	}
+	contentType, err := os.ReadFile(path + typeSuffix)
+	if err != nil {
+		return core.Image{}, false, err
+	}
+	return core.Image{Data: data, ContentType: string(contentType), Cached: true}, true, nil
+}
+
+func (c *diskCache) put(imageURL string, image core.Image) error {
+	if int64(len(image.Data)) > c.maxSize {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.path(imageURL)
+	if err := writeFile(path+typeSuffix, []byte(image.ContentType)); err != nil {
+		return err
+	}
+	if err := writeFile(path, image.Data); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the oldest images until the cache fits into maxSize.
+func (c *diskCache) evict() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var images []cached
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), typeSuffix) || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		images = append(images, cached{filepath.Join(c.dir, entry.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	slices.SortFunc(images, func(a, b cached) int { return a.modTime.Compare(b.modTime) })
+	for _, image := range images {
+		if total <= c.maxSize {
+			break
+		}
+		if err := c.remove(image.path); err != nil {
+			return err
+		}
+		total -= image.size
+	}
+	return nil
+}
+
+func (c *diskCache) remove(path string) error {
+	for _, name := range []string{path, path + typeSuffix} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile replaces a file atomically so readers never see partial images.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
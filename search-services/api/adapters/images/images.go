@@ -0,0 +1,111 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+	"github.com/liy0aay/xkcd-search/closers"
+)
+
+// maxImageSize guards against unexpectedly large upstream responses.
+const maxImageSize = 10 << 20
+
+type Client struct {
+	client http.Client
+	log    *slog.Logger
+	cache  *diskCache
+}
+
+type Option func(*Client) error
+
+// WithDiskCache keeps fetched images in dir for ttl, evicting the oldest
+// ones once the cache grows over maxSize bytes.
+func WithDiskCache(dir string, maxSize int64, ttl time.Duration) Option {
+	return func(c *Client) error {
+		cache, err := newDiskCache(dir, maxSize, ttl)
+		if err != nil {
+			return err
+		}
+		c.cache = cache
+		return nil
+	}
+}
+
+func NewClient(timeout time.Duration, log *slog.Logger, opts ...Option) (*Client, error) {
+	c := &Client{
+		client: http.Client{Timeout: timeout},
+		log:    log,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Client) Image(ctx context.Context, imageURL string) (core.Image, error) {
+	if c.cache != nil {
+		image, ok, err := c.cache.get(imageURL)
+		if err != nil {
+			c.log.Warn("failed to read cached image", "url", imageURL, "error", err)
+		}
+		if ok {
+			return image, nil
+		}
+	}
+
+	image, err := c.fetch(ctx, imageURL)
+	if err != nil {
+		return core.Image{}, err
+	}
+
+	if c.cache != nil {
+		if err := c.cache.put(imageURL, image); err != nil {
+			c.log.Warn("failed to cache image", "url", imageURL, "error", err)
+		}
+	}
+	return image, nil
+}
+
+func (c *Client) fetch(ctx context.Context, imageURL string) (core.Image, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return core.Image{}, fmt.Errorf("%w: bad image url %q", core.ErrBadArguments, imageURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return core.Image{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return core.Image{}, err
+	}
+	defer closers.CloseOrLog(resp.Body, c.log)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return core.Image{}, core.ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return core.Image{}, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return core.Image{}, err
+	}
+	if len(data) > maxImageSize {
+		return core.Image{}, fmt.Errorf("image is larger than %d bytes", maxImageSize)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return core.Image{Data: data, ContentType: contentType}, nil
+}
@@ -0,0 +1,101 @@
+package images
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+var noopLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func imageServer(t *testing.T, fetches *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png:" + r.URL.Path))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_ImageServedFromCache(t *testing.T) {
+	var fetches atomic.Int32
+	srv := imageServer(t, &fetches)
+	client, err := NewClient(time.Second, noopLogger, WithDiskCache(t.TempDir(), 1<<20, time.Hour))
+	require.NoError(t, err)
+
+	first, err := client.Image(context.Background(), srv.URL+"/python.png")
+	require.NoError(t, err)
+	second, err := client.Image(context.Background(), srv.URL+"/python.png")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, fetches.Load())
+	assert.False(t, first.Cached)
+	assert.True(t, second.Cached)
+	assert.Equal(t, "image/png", second.ContentType)
+	assert.Equal(t, first.Data, second.Data)
+}
+
+func TestClient_CacheExpires(t *testing.T) {
+	var fetches atomic.Int32
+	srv := imageServer(t, &fetches)
+	client, err := NewClient(time.Second, noopLogger, WithDiskCache(t.TempDir(), 1<<20, time.Hour))
+	require.NoError(t, err)
+
+	_, err = client.Image(context.Background(), srv.URL+"/python.png")
+	require.NoError(t, err)
+	client.cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	image, err := client.Image(context.Background(), srv.URL+"/python.png")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, fetches.Load())
+	assert.False(t, image.Cached)
+}
+
+func TestClient_CacheEvictsOldest(t *testing.T) {
+	var fetches atomic.Int32
+	srv := imageServer(t, &fetches)
+	// fits a single image of 15 bytes
+	client, err := NewClient(time.Second, noopLogger, WithDiskCache(t.TempDir(), 20, time.Hour))
+	require.NoError(t, err)
+
+	_, err = client.Image(context.Background(), srv.URL+"/first.png")
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = client.Image(context.Background(), srv.URL+"/other.png")
+	require.NoError(t, err)
+
+	image, err := client.Image(context.Background(), srv.URL+"/other.png")
+	require.NoError(t, err)
+	assert.True(t, image.Cached)
+	image, err = client.Image(context.Background(), srv.URL+"/first.png")
+	require.NoError(t, err)
+	assert.False(t, image.Cached)
+}
+
+func TestClient_ImageErrors(t *testing.T) {
+	var fetches atomic.Int32
+	srv := imageServer(t, &fetches)
+	client, err := NewClient(time.Second, noopLogger)
+	require.NoError(t, err)
+
+	_, err = client.Image(context.Background(), srv.URL+"/missing.png")
+	assert.ErrorIs(t, err, core.ErrNotFound)
+	_, err = client.Image(context.Background(), "file:///etc/passwd")
+	assert.ErrorIs(t, err, core.ErrBadArguments)
+}
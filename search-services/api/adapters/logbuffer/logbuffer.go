@@ -0,0 +1,137 @@
+package logbuffer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+// Buffer keeps the latest log records of level or above in memory.
+type Buffer struct {
+	level slog.Leveler
+
+	mu      sync.Mutex
+	records []core.LogRecord
+	// next is the oldest record once the buffer is full
+	next int
+}
+
+func New(size int, level slog.Leveler) (*Buffer, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("wrong log buffer size specified: %d", size)
+	}
+	return &Buffer{level: level, records: make([]core.LogRecord, 0, size)}, nil
+}
+
+// Recent returns buffered records, oldest first.
+func (b *Buffer) Recent() []core.LogRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append(slices.Clone(b.records[b.next:]), b.records[:b.next]...)
+}
+
+// add only swaps a slot under the lock, so logging never waits for readers
+// longer than a copy of the buffer.
+func (b *Buffer) add(record core.LogRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.records) < cap(b.records) {
+		b.records = append(b.records, record)
+		return
+	}
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+}
+
+// Handler passes records on to next, keeping a copy of them in the buffer.
+func (b *Buffer) Handler(next slog.Handler) slog.Handler {
+	return &handler{buffer: b, next: next}
+}
+
+type handler struct {
+	buffer *Buffer
+	next   slog.Handler
+	// attrs are added by WithAttrs, their keys qualified by groups
+	attrs []attr
+	group string
+}
+
+type attr struct {
+	key   string
+	value any
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.buffer.level.Level() || h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.buffer.level.Level() {
+		h.buffer.add(h.record(r))
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *handler) record(r slog.Record) core.LogRecord {
+	record := core.LogRecord{Time: r.Time, Level: r.Level.String(), Message: r.Message}
+	attrs := slices.Clone(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = flatten(attrs, h.group, a)
+		return true
+	})
+	if len(attrs) > 0 {
+		record.Attrs = make(map[string]any, len(attrs))
+		for _, a := range attrs {
+			record.Attrs[a.key] = a.value
+		}
+	}
+	return record
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = slices.Clone(h.attrs)
+	for _, a := range attrs {
+		clone.attrs = flatten(clone.attrs, h.group, a)
+	}
+	clone.next = h.next.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
+
+// flatten appends a with its key prefixed by group, group values are
+// flattened into dotted keys and values not encoded as JSON into strings.
+func flatten(attrs []attr, group string, a slog.Attr) []attr {
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		prefix := group
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range value.Group() {
+			attrs = flatten(attrs, prefix, member)
+		}
+		return attrs
+	case slog.KindString, slog.KindInt64, slog.KindUint64, slog.KindFloat64, slog.KindBool:
+		return append(attrs, attr{key: group + a.Key, value: value.Any()})
+	default:
+		return append(attrs, attr{key: group + a.Key, value: value.String()})
+	}
+}
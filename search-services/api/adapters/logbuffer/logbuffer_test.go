@@ -0,0 +1,42 @@
+package logbuffer
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer_KeepsLatestRecords(t *testing.T) {
+	buffer, err := New(3, slog.LevelWarn)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	log := slog.New(buffer.Handler(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	log.Info("ignored")
+	log.Warn("kept but not printed")
+	log.With("service", "search").WithGroup("call").
+		Error("search failed", "error", errors.New("unavailable"), "attempt", 2)
+	for _, msg := range []string{"second", "third"} {
+		log.Error(msg)
+	}
+
+	recent := buffer.Recent()
+	require.Len(t, recent, 3, "buffer caps at its size")
+	assert.Equal(t, "search failed", recent[0].Message)
+	assert.Equal(t, "ERROR", recent[0].Level)
+	assert.Equal(t, map[string]any{"service": "search", "call.error": "unavailable", "call.attempt": int64(2)},
+		recent[0].Attrs)
+	assert.Equal(t, "third", recent[2].Message)
+
+	assert.NotContains(t, out.String(), "kept but not printed", "next handler keeps its level")
+	assert.Contains(t, out.String(), "search failed")
+}
+
+func TestNew_WrongSize(t *testing.T) {
+	_, err := New(0, slog.LevelWarn)
+	assert.Error(t, err)
+}
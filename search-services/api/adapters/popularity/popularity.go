@@ -0,0 +1,114 @@
+package popularity
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+// Counter counts clicks on comics found by searches. A client clicking a
+// comics again within window is counted once, up to maxSeen recent clicks
+// are remembered for it. Counts are kept in memory only and start over
+// when the service restarts.
+type Counter struct {
+	window  time.Duration
+	maxSeen int
+	now     func() time.Time
+
+	mu     sync.Mutex
+	clicks map[int]int
+	seen   map[click]time.Time
+}
+
+type click struct {
+	client string
+	id     int
+}
+
+func New(window time.Duration, maxSeen int) *Counter {
+	return &Counter{
+		window:  window,
+		maxSeen: max(maxSeen, 1),
+		now:     time.Now,
+		clicks:  make(map[int]int),
+		seen:    make(map[click]time.Time),
+	}
+}
+
+// Click counts a click of client on comics id, false when it is not counted
+// being a repeated click or while too many recent clicks are remembered.
+func (c *Counter) Click(client string, id int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	key := click{client: client, id: id}
+	if at, ok := c.seen[key]; ok && now.Sub(at) < c.window {
+		return false
+	}
+	if len(c.seen) >= c.maxSeen {
+		for key, at := range c.seen {
+			if now.Sub(at) >= c.window {
+				delete(c.seen, key)
+			}
+		}
+		// a flood of clients must not make repeated clicks count again
+		if len(c.seen) >= c.maxSeen {
+			return false
+		}
+	}
+	c.seen[key] = now
+	c.clicks[id]++
+	return true
+}
+
+func (c *Counter) Clicks(id int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clicks[id]
+}
+
+// Searcher adds clicks to scores of the wrapped searcher results
+// and reorders them when a search asks to boost popular comics.
+type Searcher struct {
+	core.Searcher
+	Popularity *Counter
+}
+
+func (s Searcher) Search(
+	ctx context.Context, phrase string, limit int, opts core.SearchOptions,
+) (core.SearchResult, error) {
+	result, err := s.Searcher.Search(ctx, phrase, limit, opts)
+	if err != nil || !opts.BoostPopular {
+		return result, err
+	}
+	result.Comics = s.boost(result.Comics)
+	return result, nil
+}
+
+func (s Searcher) SearchIndex(
+	ctx context.Context, phrase string, limit int, opts core.SearchOptions,
+) (core.SearchResult, error) {
+	result, err := s.Searcher.SearchIndex(ctx, phrase, limit, opts)
+	if err != nil || !opts.BoostPopular {
+		return result, err
+	}
+	result.Comics = s.boost(result.Comics)
+	return result, nil
+}
+
+// boost counts every click as one more keyword found, ties are ordered by
+// ID like the searcher orders them.
+func (s Searcher) boost(comics []core.Comics) []core.Comics {
+	boosted := slices.Clone(comics)
+	for i := range boosted {
+		boosted[i].Score += s.Popularity.Clicks(boosted[i].ID)
+	}
+	slices.SortFunc(boosted, func(a, b core.Comics) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.ID, b.ID))
+	})
+	return boosted
+}
@@ -0,0 +1,89 @@
+package popularity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+type fakeSearcher struct {
+	core.Searcher
+	comics []core.Comics
+}
+
+func (f fakeSearcher) Search(context.Context, string, int, core.SearchOptions) (core.SearchResult, error) {
+	return core.SearchResult{Comics: f.comics, Total: len(f.comics)}, nil
+}
+
+func (f fakeSearcher) SearchIndex(context.Context, string, int, core.SearchOptions) (core.SearchResult, error) {
+	return core.SearchResult{Comics: f.comics, Total: len(f.comics)}, nil
+}
+
+func ids(comics []core.Comics) []int {
+	result := make([]int, 0, len(comics))
+	for _, c := range comics {
+		result = append(result, c.ID)
+	}
+	return result
+}
+
+func TestSearcher_BoostPopular(t *testing.T) {
+	clicks := New(time.Hour, 10)
+	searcher := Searcher{
+		Searcher: fakeSearcher{comics: []core.Comics{
+			{ID: 1, Score: 3}, {ID: 2, Score: 2}, {ID: 3, Score: 2},
+		}},
+		Popularity: clicks,
+	}
+	clicks.Click("10.0.0.1", 3)
+	clicks.Click("10.0.0.2", 3)
+
+	found, err := searcher.SearchIndex(context.Background(), "linux", 10, core.SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids(found.Comics), "no boost asked")
+
+	found, err = searcher.SearchIndex(context.Background(), "linux", 10, core.SearchOptions{BoostPopular: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 2}, ids(found.Comics))
+	assert.Equal(t, 4, found.Comics[0].Score)
+
+	found, err = searcher.Search(context.Background(), "linux", 10, core.SearchOptions{BoostPopular: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 2}, ids(found.Comics))
+}
+
+func TestCounter_DedupesClients(t *testing.T) {
+	clicks := New(time.Hour, 10)
+	now := time.Now()
+	clicks.now = func() time.Time { return now }
+
+	assert.True(t, clicks.Click("10.0.0.1", 1))
+	assert.False(t, clicks.Click("10.0.0.1", 1), "repeated click")
+	assert.True(t, clicks.Click("10.0.0.1", 2))
+	assert.True(t, clicks.Click("10.0.0.2", 1))
+	assert.Equal(t, 2, clicks.Clicks(1))
+
+	now = now.Add(time.Hour)
+	assert.True(t, clicks.Click("10.0.0.1", 1), "window is over")
+	assert.Equal(t, 3, clicks.Clicks(1))
+}
+
+func TestCounter_MaxSeen(t *testing.T) {
+	clicks := New(time.Hour, 2)
+	now := time.Now()
+	clicks.now = func() time.Time { return now }
+
+	assert.True(t, clicks.Click("10.0.0.1", 1))
+	assert.True(t, clicks.Click("10.0.0.2", 1))
+	assert.False(t, clicks.Click("10.0.0.3", 1), "too many recent clicks")
+	assert.False(t, clicks.Click("10.0.0.1", 1), "still a repeated click")
+
+	now = now.Add(time.Hour)
+	assert.True(t, clicks.Click("10.0.0.3", 1), "expired clicks are forgotten")
+	assert.Equal(t, 3, clicks.Clicks(1))
+}
@@ -0,0 +1,207 @@
+package querylog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+// Log keeps the latest searches up to its size, optionally dropping
+// entries older than the retention and flushing them to a file.
+type Log struct {
+	log       *slog.Logger
+	size      int
+	retention time.Duration
+	path      string
+
+	mu      sync.Mutex
+	entries []core.Query
+}
+
+type Option func(*Log)
+
+// WithRetention drops entries older than age.
+func WithRetention(age time.Duration) Option {
+	return func(l *Log) {
+		l.retention = age
+	}
+}
+
+// WithFile persists entries to path on Flush, Load reads them back.
+func WithFile(path string) Option {
+	return func(l *Log) {
+		l.path = path
+	}
+}
+
+func New(log *slog.Logger, size int, opts ...Option) (*Log, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("wrong query log size specified: %d", size)
+	}
+	l := &Log{log: log, size: size}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+func (l *Log) Record(query core.Query) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, query)
+	l.trim(time.Now())
+}
+
+// Recent returns retained entries, oldest first.
+func (l *Log) Recent() []core.Query {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.trim(time.Now())
+	return slices.Clone(l.entries)
+}
+
+// Top returns up to n retained phrases, the most searched first.
+func (l *Log) Top(n int) []string {
+	counts := make(map[string]int)
+	var phrases []string
+	for _, q := range l.Recent() {
+		if counts[q.Phrase] == 0 {
+			phrases = append(phrases, q.Phrase)
+		}
+		counts[q.Phrase]++
+	}
+	slices.SortStableFunc(phrases, func(a, b string) int {
+		return counts[b] - counts[a]
+	})
+	return phrases[:min(n, len(phrases))]
+}
+
+// trim drops expired entries and those over size, the caller must hold the lock.
+func (l *Log) trim(now time.Time) {
+	if l.retention > 0 {
+		cutoff := now.Add(-l.retention)
+		l.entries = slices.DeleteFunc(l.entries, func(q core.Query) bool {
+			return q.At.Before(cutoff)
+		})
+	}
+	if over := len(l.entries) - l.size; over > 0 {
+		l.entries = slices.Delete(l.entries, 0, over)
+	}
+}
+
+// entry is the on-disk form of a query.
+type entry struct {
+	Phrase  string    `json:"phrase"`
+	Results int       `json:"results"`
+	At      time.Time `json:"at"`
+}
+
+// Load restores entries flushed before, a missing file is not an error.
+func (l *Log) Load() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored []entry
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("corrupted query log %s: %w", l.path, err)
+	}
+	entries := make([]core.Query, 0, len(stored))
+	for _, e := range stored {
+		entries = append(entries, core.Query{Phrase: e.Phrase, Results: e.Results, At: e.At})
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(entries, l.entries...)
+	l.trim(time.Now())
+	return nil
+}
+
+// Flush writes retained entries to the file, replacing it at once.
+func (l *Log) Flush() error {
+	if l.path == "" {
+		return nil
+	}
+	recent := l.Recent()
+	stored := make([]entry, 0, len(recent))
+	for _, q := range recent {
+		stored = append(stored, entry{Phrase: q.Phrase, Results: q.Results, At: q.At})
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// Run flushes entries every interval until ctx is done.
+func (l *Log) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Flush(); err != nil {
+				l.log.Error("failed to flush query log", "error", err)
+			}
+		}
+	}
+}
+
+// Searcher records searches passed to the wrapped searcher.
+type Searcher struct {
+	core.Searcher
+	Queries *Log
+}
+
+func (s Searcher) Search(
+	ctx context.Context, phrase string, limit int, opts core.SearchOptions,
+) (core.SearchResult, error) {
+	result, err := s.Searcher.Search(ctx, phrase, limit, opts)
+	s.record(phrase, result.Comics, err)
+	return result, err
+}
+
+func (s Searcher) SearchIndex(
+	ctx context.Context, phrase string, limit int, opts core.SearchOptions,
+) (core.SearchResult, error) {
+	result, err := s.Searcher.SearchIndex(ctx, phrase, limit, opts)
+	s.record(phrase, result.Comics, err)
+	return result, err
+}
+
+func (s Searcher) record(phrase string, comics []core.Comics, err error) {
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		return
+	}
+	s.Queries.Record(core.Query{Phrase: phrase, Results: len(comics), At: time.Now()})
+}
@@ -0,0 +1,75 @@
+package querylog
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+var noopLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+func phrases(queries []core.Query) []string {
+	result := make([]string, 0, len(queries))
+	for _, q := range queries {
+		result = append(result, q.Phrase)
+	}
+	return result
+}
+
+func TestLog_RetentionPrunesOldEntries(t *testing.T) {
+	queries, err := New(noopLogger, 10, WithRetention(time.Hour))
+	require.NoError(t, err)
+
+	queries.Record(core.Query{Phrase: "stale", At: time.Now().Add(-2 * time.Hour)})
+	queries.Record(core.Query{Phrase: "fresh", At: time.Now()})
+
+	assert.Equal(t, []string{"fresh"}, phrases(queries.Recent()))
+}
+
+func TestLog_SizeCap(t *testing.T) {
+	queries, err := New(noopLogger, 2)
+	require.NoError(t, err)
+
+	for _, phrase := range []string{"a", "b", "c"} {
+		queries.Record(core.Query{Phrase: phrase, At: time.Now()})
+	}
+
+	assert.Equal(t, []string{"b", "c"}, phrases(queries.Recent()))
+}
+
+func TestLog_FlushedEntriesReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.json")
+	queries, err := New(noopLogger, 10, WithFile(path), WithRetention(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, queries.Load(), "missing file is fine")
+	queries.Record(core.Query{Phrase: "linux", Results: 3, At: time.Now()})
+	require.NoError(t, queries.Flush())
+
+	restarted, err := New(noopLogger, 10, WithFile(path), WithRetention(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, restarted.Load())
+
+	recent := restarted.Recent()
+	require.Len(t, recent, 1)
+	assert.Equal(t, "linux", recent[0].Phrase)
+	assert.Equal(t, 3, recent[0].Results)
+}
+
+func TestLog_TopMostSearchedFirst(t *testing.T) {
+	queries, err := New(noopLogger, 10)
+	require.NoError(t, err)
+	for _, phrase := range []string{"linux", "python", "cat", "python", "cat", "python"} {
+		queries.Record(core.Query{Phrase: phrase, At: time.Now()})
+	}
+
+	assert.Equal(t, []string{"python", "cat"}, queries.Top(2))
+	assert.Equal(t, []string{"python", "cat", "linux"}, queries.Top(10))
+	assert.Empty(t, queries.Top(0))
+}
@@ -1,15 +1,21 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log/slog"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/liy0aay/xkcd-search/api/adapters/explainxkcd"
+	"github.com/liy0aay/xkcd-search/api/adapters/rest/middleware"
 	"github.com/liy0aay/xkcd-search/api/core"
 )
 
@@ -32,7 +38,13 @@ func NewPingHandler(log *slog.Logger, pingers map[string]core.Pinger) http.Handl
 			Replies: make(map[string]string),
 		}
 		for name, pinger := range pingers {
-			if err := pinger.Ping(r.Context()); err != nil {
+			err := pinger.Ping(r.Context())
+			if errors.Is(err, core.ErrDegraded) {
+				reply.Replies[name] = "degraded"
+				log.Warn("one of services is degraded", "service", name)
+				continue
+			}
+			if err != nil {
 				reply.Replies[name] = "unavailable"
 				log.Error("one of services is not available", "service", name, "error", err)
 				continue
@@ -45,10 +57,160 @@ func NewPingHandler(log *slog.Logger, pingers map[string]core.Pinger) http.Handl
 	}
 }
 
+const (
+	readyOK          = "ok"
+	readyStarting    = "starting"
+	readyDegraded    = "degraded"
+	readyUnavailable = "unavailable"
+)
+
+type ReadyReply struct {
+	Status   string     `json:"status"`
+	Comics   int        `json:"comics"`
+	Keywords int        `json:"keywords"`
+	BuiltAt  *time.Time `json:"built_at,omitempty"`
+}
+
+// NewReadyHandler replies 503 until the search index is first built and
+// while it is empty or, with a positive maxAge, not rebuilt for longer.
+func NewReadyHandler(log *slog.Logger, searcher core.Searcher, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := searcher.IndexStats(r.Context())
+		reply := ReadyReply{Comics: stats.Comics, Keywords: stats.Keywords}
+		if !stats.BuiltAt.IsZero() {
+			reply.BuiltAt = &stats.BuiltAt
+		}
+		switch {
+		case err != nil:
+			log.Error("cannot get index stats", "error", err)
+			reply.Status = readyUnavailable
+		case stats.Disabled:
+			reply.Status = readyOK
+		case stats.BuiltAt.IsZero():
+			reply.Status = readyStarting
+		case stats.Comics == 0:
+			log.Warn("search index is empty")
+			reply.Status = readyDegraded
+		case maxAge > 0 && time.Since(stats.BuiltAt) > maxAge:
+			log.Warn("search index is stale", "built_at", stats.BuiltAt)
+			reply.Status = readyDegraded
+		default:
+			reply.Status = readyOK
+		}
+		if reply.Status != readyOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+type SelfTestStep struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type SelfTestReply struct {
+	Status   string         `json:"status"`
+	Phrase   string         `json:"phrase"`
+	Duration string         `json:"duration"`
+	Steps    []SelfTestStep `json:"steps"`
+}
+
+// NewSelfTestHandler probes normalization and an index search with a
+// known phrase, replying 503 unless both succeed with results.
+func NewSelfTestHandler(
+	log *slog.Logger, normalizer core.Normalizer, searcher core.Searcher, phrase string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		reply := SelfTestReply{Status: "ok", Phrase: phrase}
+		step := func(name string, probe func() error) bool {
+			stepStart := time.Now()
+			err := probe()
+			result := SelfTestStep{Name: name, OK: err == nil, Duration: time.Since(stepStart).String()}
+			if err != nil {
+				result.Error = err.Error()
+				reply.Status = "fail"
+				log.Error("self test failed", "step", name, "error", err)
+			}
+			reply.Steps = append(reply.Steps, result)
+			return err == nil
+		}
+
+		normalized := step("normalize", func() error {
+			keywords, err := normalizer.Norm(r.Context(), phrase)
+			if err == nil && len(keywords) == 0 {
+				err = errors.New("no keywords")
+			}
+			return err
+		})
+		if normalized {
+			step("search", func() error {
+				found, err := searcher.SearchIndex(r.Context(), phrase, 1, core.SearchOptions{})
+				if err == nil && len(found.Comics) == 0 {
+					err = errors.New("no comics found")
+				}
+				if err == nil && found.Comics[0].URL == "" {
+					err = fmt.Errorf("comics %d has no metadata", found.Comics[0].ID)
+				}
+				return err
+			})
+		}
+
+		reply.Duration = time.Since(start).String()
+		if reply.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
 type Authenticator interface {
 	Login(user, password string) (accessToken string, refreshToken string, err error)
 	Verify(token string) error
-	RefreshAccessToken(refreshToken string) (string, error)
+	RefreshAccessToken(refreshToken string) (accessToken string, newRefreshToken string, err error)
+	RefreshTTL() time.Duration
+}
+
+type Introspector interface {
+	Introspect(token string) core.TokenInfo
+}
+
+type IntrospectReply struct {
+	Active bool   `json:"active"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Exp    int64  `json:"exp,omitempty"`
+	Iat    int64  `json:"iat,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// NewIntrospectHandler reports claims of the token form value to an
+// authenticated caller, invalid tokens are replied as not active rather
+// than an error.
+func NewIntrospectHandler(log *slog.Logger, introspector Introspector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.FormValue("token")
+		if token == "" {
+			http.Error(w, "no token", http.StatusBadRequest)
+			return
+		}
+		info := introspector.Introspect(token)
+		reply := IntrospectReply{Active: info.Active}
+		if info.Active {
+			reply.Name, reply.Role, reply.Type = info.Name, info.Role, info.Type
+			reply.Exp, reply.Iat = info.ExpiresAt.Unix(), info.IssuedAt.Unix()
+		}
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
 }
 
 type Login struct {
@@ -71,15 +233,7 @@ func NewLoginHandler(log *slog.Logger, auth Authenticator) http.HandlerFunc {
 			return
 		}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    refreshToken,
-			Path:     "/",
-			MaxAge:   30 * 24 * 3600,
-			HttpOnly: true,
-			Secure:   false,
-			SameSite: http.SameSiteLaxMode,
-		})
+		middleware.SetRefreshCookie(w, refreshToken, auth.RefreshTTL())
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(map[string]string{
@@ -103,11 +257,34 @@ func NewUpdateHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
 	}
 }
 
+// NewUpdateComicHandler fetches a single comics from xkcd again, replacing
+// the stored one.
+func NewUpdateComicHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		if err := updater.UpdateOne(r.Context(), id); err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while updating comics", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
 type UpdateStats struct {
 	WordsTotal    int `json:"words_total"`
 	WordsUnique   int `json:"words_unique"`
 	ComicsFetched int `json:"comics_fetched"`
 	ComicsTotal   int `json:"comics_total"`
+	ComicsAdded   int `json:"comics_added"`
+	ComicsSkipped int `json:"comics_skipped"`
+	ComicsFailed  int `json:"comics_failed"`
 }
 
 func NewUpdateStatsHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
@@ -123,6 +300,9 @@ func NewUpdateStatsHandler(log *slog.Logger, updater core.Updater) http.HandlerF
 			WordsUnique:   stats.WordsUnique,
 			ComicsFetched: stats.ComicsFetched,
 			ComicsTotal:   stats.ComicsTotal,
+			ComicsAdded:   stats.ComicsAdded,
+			ComicsSkipped: stats.ComicsSkipped,
+			ComicsFailed:  stats.ComicsFailed,
 		}
 		if err := encodeReply(w, reply); err != nil {
 			log.Error("cannot encode reply", "error", err)
@@ -149,6 +329,15 @@ func NewUpdateStatusHandler(log *slog.Logger, updater core.Updater) http.Handler
 	}
 }
 
+func NewResetStatsHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := updater.ResetStats(r.Context()); err != nil {
+			log.Error("error while stats reset", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
 func NewDropHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := updater.Drop(r.Context()); err != nil {
@@ -158,191 +347,1149 @@ func NewDropHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
 	}
 }
 
-type Comics struct {
-	ID    int    `json:"id"`
-	URL   string `json:"url"`
-	Title string `json:"title"`
-	Alt   string `json:"alt"`
-	Score int    `json:"score"`
+type BrokenLink struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Reason    string    `json:"reason"`
+	CheckedAt time.Time `json:"checked_at"`
 }
 
-type ComicsReply struct {
-	Comics []Comics `json:"comics"`
-	Total  int      `json:"total"`
+type BrokenLinksReply struct {
+	Links []BrokenLink `json:"links"`
+	Total int          `json:"total"`
 }
 
-func NewSearchHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+func NewBrokenLinksHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var limit int
-		var err error
-		limitStr := r.URL.Query().Get("limit")
-		if limitStr != "" {
-			limit, err = strconv.Atoi(limitStr)
-			if err != nil {
-				log.Error("wrong limit", "value", limitStr)
-				http.Error(w, "bad limit", http.StatusBadRequest)
-				return
-			}
-			if limit < 0 {
-				log.Error("wrong limit", "value", limit)
-				http.Error(w, "bad limit", http.StatusBadRequest)
-				return
-			}
-		}
-		phrase := r.URL.Query().Get("phrase")
-		if phrase == "" {
-			log.Error("no phrase")
-			http.Error(w, "no phrase", http.StatusBadRequest)
-			return
-		}
-
-		comics, err := searcher.Search(r.Context(), phrase, limit)
+		links, err := updater.BrokenLinks(r.Context())
 		if err != nil {
-			if errors.Is(err, core.ErrNotFound) {
-				http.Error(w, "no comics found", http.StatusNotFound)
-				return
-			}
-			log.Error("error while seaching", "error", err)
+			log.Error("error while broken links", "error", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		reply := ComicsReply{
-			Comics: make([]Comics, 0, len(comics)),
-			Total:  len(comics),
+		reply := BrokenLinksReply{
+			Links: make([]BrokenLink, 0, len(links)),
+			Total: len(links),
 		}
-		for _, c := range comics {
-			reply.Comics = append(reply.Comics, Comics{ID: c.ID, URL: c.URL, Title: c.Title, Alt: c.Alt, Score: c.Score})
+		for _, l := range links {
+			reply.Links = append(reply.Links, BrokenLink{ID: l.ID, URL: l.URL, Reason: l.Reason, CheckedAt: l.CheckedAt})
 		}
-
 		if err := encodeReply(w, reply); err != nil {
 			log.Error("cannot encode reply", "error", err)
 		}
 	}
 }
 
-func NewSearchIndexHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var limit int
-		var err error
-		limitStr := r.URL.Query().Get("limit")
-		if limitStr != "" {
-			limit, err = strconv.Atoi(limitStr)
-			if err != nil {
-				log.Error("wrong limit", "value", limitStr)
-				http.Error(w, "bad limit", http.StatusBadRequest)
-				return
-			}
-			if limit < 0 {
-				log.Error("wrong limit", "value", limit)
-				http.Error(w, "bad limit", http.StatusBadRequest)
-				return
-			}
-		}
-		phrase := r.URL.Query().Get("phrase")
-		if phrase == "" {
-			log.Error("no phrase")
-			http.Error(w, "no phrase", http.StatusBadRequest)
-			return
-		}
+type BuildRun struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	Comics    int       `json:"comics"`
+	Error     string    `json:"error,omitempty"`
+}
 
-		comics, err := searcher.SearchIndex(r.Context(), phrase, limit)
+type BuildHistoryReply struct {
+	Runs []BuildRun `json:"runs"`
+}
+
+func NewBuildHistoryHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, err := searcher.BuildHistory(r.Context())
 		if err != nil {
-			if errors.Is(err, core.ErrNotFound) {
-				http.Error(w, "no comics found", http.StatusNotFound)
-				return
-			}
-			log.Error("error while seaching", "error", err)
+			log.Error("error while build history", "error", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		reply := ComicsReply{
-			Comics: make([]Comics, 0, len(comics)),
-			Total:  len(comics),
+		reply := BuildHistoryReply{Runs: make([]BuildRun, 0, len(runs))}
+		for _, run := range runs {
+			reply.Runs = append(reply.Runs, BuildRun{
+				StartedAt: run.StartedAt,
+				Duration:  run.Duration.String(),
+				Comics:    run.Comics,
+				Error:     run.Error,
+			})
 		}
-		for _, c := range comics {
-			reply.Comics = append(reply.Comics, Comics{ID: c.ID, URL: c.URL, Title: c.Title, Alt: c.Alt, Score: c.Score})
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
 		}
+	}
+}
+
+type QueryLogEntry struct {
+	Phrase  string    `json:"phrase"`
+	Results int       `json:"results"`
+	At      time.Time `json:"at"`
+}
+
+type QueryLogReply struct {
+	Queries []QueryLogEntry `json:"queries"`
+}
 
+// NewQueryLogHandler lists recent searches, oldest first.
+func NewQueryLogHandler(log *slog.Logger, queries core.QueryLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recent := queries.Recent()
+		reply := QueryLogReply{Queries: make([]QueryLogEntry, 0, len(recent))}
+		for _, q := range recent {
+			reply.Queries = append(reply.Queries, QueryLogEntry{Phrase: q.Phrase, Results: q.Results, At: q.At})
+		}
 		if err := encodeReply(w, reply); err != nil {
 			log.Error("cannot encode reply", "error", err)
 		}
 	}
 }
-func NewExplainHandler(log *slog.Logger, client *explainxkcd.Client) http.HandlerFunc {
+
+type LogRecordEntry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+type RecentLogsReply struct {
+	Records []LogRecordEntry `json:"records"`
+}
+
+// NewRecentLogsHandler lists buffered log records, oldest first.
+func NewRecentLogsHandler(log *slog.Logger, logs core.LogBuffer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := r.URL.Query().Get("id")
-		if idStr == "" {
-			http.Error(w, "missing id", http.StatusBadRequest)
-			return
+		recent := logs.Recent()
+		reply := RecentLogsReply{Records: make([]LogRecordEntry, 0, len(recent))}
+		for _, record := range recent {
+			reply.Records = append(reply.Records, LogRecordEntry{
+				Time: record.Time, Level: record.Level, Message: record.Message, Attrs: record.Attrs,
+			})
 		}
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			http.Error(w, "invalid id", http.StatusBadRequest)
-			return
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
 		}
+	}
+}
 
-		explanation, err := client.Explain(r.Context(), id)
-		if err != nil {
-			log.Error("explain failed", "error", err)
-			if errors.Is(err, core.ErrNotFound) {
-				http.Error(w, "not found", http.StatusNotFound)
-			} else {
-				http.Error(w, "internal error", http.StatusInternalServerError)
-			}
-			return
+type BreakerReply struct {
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	Threshold int        `json:"threshold"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+}
+
+func newBreakerReply(status core.BreakerStatus) BreakerReply {
+	reply := BreakerReply{State: status.State, Failures: status.Failures, Threshold: status.Threshold}
+	if !status.OpenedAt.IsZero() {
+		reply.OpenedAt = &status.OpenedAt
+	}
+	return reply
+}
+
+func NewBreakerHandler(log *slog.Logger, breaker core.Breaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := encodeReply(w, newBreakerReply(breaker.Status())); err != nil {
+			log.Error("cannot encode reply", "error", err)
 		}
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(explanation); err != nil {
-			log.Error("failed to encode explanation response", "error", err)
+// NewResetBreakerHandler closes the breaker and replies its new status.
+func NewResetBreakerHandler(log *slog.Logger, breaker core.Breaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		breaker.Reset()
+		log.Info("breaker reset by admin")
+		if err := encodeReply(w, newBreakerReply(breaker.Status())); err != nil {
+			log.Error("cannot encode reply", "error", err)
 		}
 	}
 }
 
-func NewRefreshTokenHandler(log *slog.Logger, auth Authenticator) http.HandlerFunc {
+type LatestIDReply struct {
+	LatestID int `json:"latest_id"`
+}
+
+// NewLatestIDHandler reports the newest stored comics ID, 0 for an empty DB.
+func NewLatestIDHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("refresh_token")
+		ID, err := searcher.LastID(r.Context())
 		if err != nil {
-			log.Error("refresh token not found in cookie", "error", err)
-			http.Error(w, "refresh token not found", http.StatusUnauthorized)
+			log.Error("error while getting latest id", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if err := encodeReply(w, LatestIDReply{LatestID: ID}); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+// xkcdPageURL is the permalink of a comics page on xkcd.com.
+const xkcdPageURL = "https://xkcd.com/%d/"
+
+// Comics keeps url as the image URL for older clients. Optional fields
+// are left out when empty, id, url, title, alt and score are always present.
+type Comics struct {
+	ID              int      `json:"id"`
+	URL             string   `json:"url"`
+	ImageURL        string   `json:"image_url,omitempty"`
+	PageURL         string   `json:"page_url,omitempty"`
+	Title           string   `json:"title"`
+	Alt             string   `json:"alt"`
+	Score           int      `json:"score"`
+	NormalizedScore *float64 `json:"normalized_score,omitempty"`
+	Keywords        []string `json:"keywords,omitempty"`
+}
+
+// ComicsReply counts in Errors matching comics that could not be fetched,
+// so the page misses them.
+type ComicsReply struct {
+	Comics     []Comics `json:"comics"`
+	Total      int      `json:"total"`
+	Errors     int      `json:"errors,omitempty"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func newComics(c core.Comics) Comics {
+	return Comics{
+		ID: c.ID, URL: c.URL, ImageURL: c.URL, PageURL: fmt.Sprintf(xkcdPageURL, c.ID),
+		Title: c.Title, Alt: c.Alt, Score: c.Score, Keywords: c.Keywords,
+	}
+}
+
+// newComicsReply optionally rescales scores relative to the top result,
+// so the best match gets 1.
+func newComicsReply(comics []core.Comics, normalize bool) ComicsReply {
+	reply := ComicsReply{
+		Comics: make([]Comics, 0, len(comics)),
+		Total:  len(comics),
+	}
+	var top int
+	for _, c := range comics {
+		top = max(top, c.Score)
+	}
+	for _, c := range comics {
+		comic := newComics(c)
+		if normalize {
+			var normalized float64
+			if top > 0 {
+				normalized = float64(c.Score) / float64(top)
+			}
+			comic.NormalizedScore = &normalized
+		}
+		reply.Comics = append(reply.Comics, comic)
+	}
+	return reply
+}
+
+// aboveScore drops comics ranked below minScore, the threshold applies
+// to raw scores even when they are normalized in the reply. Comics rank by
+// score, so once the page drops one every later comics is dropped as well
+// and the total ends with the page. After a cursor the comics before the
+// page are unknown and the total is left as is.
+func aboveScore(comics []core.Comics, total int, opts core.SearchOptions, minScore int) ([]core.Comics, int) {
+	if minScore <= 0 {
+		return comics, total
+	}
+	above := slices.DeleteFunc(slices.Clone(comics), func(c core.Comics) bool {
+		return c.Score < minScore
+	})
+	if len(above) < len(comics) && opts.After == (core.Cursor{}) {
+		total = opts.Offset + len(above)
+	}
+	return above, total
+}
+
+// searchLanguages are the languages the words service can normalize.
+var searchLanguages = []string{"en", "es", "fr", "hu", "no", "ru", "sv"}
+
+// searchLanguage picks the most preferred supported language of the
+// Accept-Language header, empty leaves the words service default.
+func searchLanguage(r *http.Request) string {
+	var language string
+	best := 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !slices.Contains(searchLanguages, primary) {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > best {
+			language, best = primary, q
+		}
+	}
+	return language
+}
 
-		newAccessToken, err := auth.RefreshAccessToken(cookie.Value)
+// boostFields are the comics fields search can boost.
+var boostFields = []string{"title", "alt"}
+
+const maxBoost = 100
+
+// parseBoosts reads per-field weights like boost=title:3,alt:2.
+func parseBoosts(r *http.Request) (map[string]int, error) {
+	value := r.URL.Query().Get("boost")
+	if value == "" {
+		return nil, nil
+	}
+	boosts := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		field, weight, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("expected field:weight, got %q", part)
+		}
+		if !slices.Contains(boostFields, field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		boost, err := strconv.Atoi(weight)
 		if err != nil {
-			log.Error("could not refresh access token", "error", err)
-			http.Error(w, "could not refresh token", http.StatusUnauthorized)
-			return
+			return nil, err
 		}
+		if boost < 1 || boost > maxBoost {
+			return nil, fmt.Errorf("boost of %s out of range: %d", field, boost)
+		}
+		boosts[field] = boost
+	}
+	return boosts, nil
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]string{
-			"access_token": newAccessToken,
-		}); err != nil {
-			log.Error("failed to write refresh response", "error", err)
+func parseMinScore(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("min_score")
+	if value == "" {
+		return 0, nil
+	}
+	minScore, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if minScore < 0 {
+		return 0, fmt.Errorf("negative min_score: %d", minScore)
+	}
+	return minScore, nil
+}
+
+// parseInclude tells whether include asks for keywords, the only optional
+// part of results so far.
+func parseInclude(r *http.Request) (keywords bool, err error) {
+	value := r.URL.Query().Get("include")
+	if value == "" {
+		return false, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) != "keywords" {
+			return false, fmt.Errorf("unknown include: %q", part)
 		}
 	}
+	return true, nil
 }
 
-func NewLogoutHandler(log *slog.Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		http.SetCookie(w, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    "",
-			Path:     "/",
-			MaxAge:   -1,
-			HttpOnly: true,
-			Secure:   false,
-			SameSite: http.SameSiteLaxMode,
-		})
+func parseOffset(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("offset")
+	if value == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+	return offset, nil
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]string{
-			"message": "logged out",
-		}); err != nil {
+// writeComicsReply duplicates the total in X-Total-Count, so HEAD
+// requests get the count without a body.
+func writeComicsReply(log *slog.Logger, w http.ResponseWriter, reply ComicsReply) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(reply.Total))
+	if err := encodeReply(w, reply); err != nil {
+		log.Error("cannot encode reply", "error", err)
+	}
+}
+
+func parseNormalizeScore(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("normalize_score")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func parseBoostPopular(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("boost_popular")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func parseEmptyOK(r *http.Request, emptyOK bool) (bool, error) {
+	value := r.URL.Query().Get("empty_ok")
+	if value == "" {
+		return emptyOK, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// searchRequest is a search read from the query of a request.
+type searchRequest struct {
+	phrase    string
+	limit     int
+	opts      core.SearchOptions
+	normalize bool
+	minScore  int
+	emptyOK   bool
+}
+
+// parseSearchOptions reads the search of a request, emptyOK is the default
+// of empty_ok. Errors are meant to be replied to the client.
+func parseSearchOptions(r *http.Request, emptyOK bool) (searchRequest, error) {
+	query := searchRequest{phrase: r.URL.Query().Get("phrase")}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return searchRequest{}, fmt.Errorf("bad limit: %q", limitStr)
+		}
+		query.limit = limit
+	}
+	if query.phrase == "" {
+		return searchRequest{}, errors.New("no phrase")
+	}
+	var err error
+	if query.normalize, err = parseNormalizeScore(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad normalize_score: %w", err)
+	}
+	if query.minScore, err = parseMinScore(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad min_score: %w", err)
+	}
+	if query.emptyOK, err = parseEmptyOK(r, emptyOK); err != nil {
+		return searchRequest{}, fmt.Errorf("bad empty_ok: %w", err)
+	}
+
+	opts := core.SearchOptions{Tag: r.URL.Query().Get("tag"), Language: searchLanguage(r)}
+	if opts.Offset, err = parseOffset(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad offset: %w", err)
+	}
+	if opts.After, err = parseCursor(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad cursor: %w", err)
+	}
+	if opts.Boosts, err = parseBoosts(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad boost: %w", err)
+	}
+	if opts.BoostPopular, err = parseBoostPopular(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad boost_popular: %w", err)
+	}
+	if opts.IncludeKeywords, err = parseInclude(r); err != nil {
+		return searchRequest{}, fmt.Errorf("bad include: %w", err)
+	}
+	// popular comics are reordered within the first page only, paging or
+	// thresholds on the boosted scores would repeat or skip comics
+	if opts.BoostPopular && (opts.After != (core.Cursor{}) || opts.Offset > 0 || query.minScore > 0) {
+		return searchRequest{}, errors.New("bad boost_popular: cannot page with cursor, offset or min_score")
+	}
+	// scores are normalized by the top of the page, which is the top result
+	// of the query on the first page only
+	if query.normalize && (opts.After != (core.Cursor{}) || opts.Offset > 0) {
+		return searchRequest{}, errors.New("bad normalize_score: cannot page with cursor or offset")
+	}
+	query.opts = opts
+	return query, nil
+}
+
+type searchFunc func(ctx context.Context, phrase string, limit int, opts core.SearchOptions) (core.SearchResult, error)
+
+// NewSearchHandler replies 404 when nothing is found unless emptyOK,
+// or empty_ok in the query, asks for an empty 200 reply.
+func NewSearchHandler(log *slog.Logger, searcher core.Searcher, emptyOK bool) http.HandlerFunc {
+	return newSearchHandler(log, searcher.Search, emptyOK)
+}
+
+// NewSearchIndexHandler replies like NewSearchHandler searching the index.
+func NewSearchIndexHandler(log *slog.Logger, searcher core.Searcher, emptyOK bool) http.HandlerFunc {
+	return newSearchHandler(log, searcher.SearchIndex, emptyOK)
+}
+
+func newSearchHandler(log *slog.Logger, search searchFunc, emptyOK bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseSearchOptions(r, emptyOK)
+		if err != nil {
+			log.Error("wrong search", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		found, err := search(r.Context(), query.phrase, query.limit, query.opts)
+		if err != nil {
+			switch {
+			case errors.Is(err, core.ErrNotFound) && query.emptyOK:
+				writeComicsReply(log, w, newComicsReply(nil, query.normalize))
+				return
+			case errors.Is(err, core.ErrNotFound):
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			case errors.Is(err, core.ErrStopWords):
+				w.Header().Set(middleware.ErrorCodeHeader, CodeStopWords)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case errors.Is(err, core.ErrBadArguments):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error("error while searching", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		above, total := aboveScore(found.Comics, found.Total, query.opts, query.minScore)
+		reply := newComicsReply(above, query.normalize)
+		reply.Total, reply.Errors = total, found.Errors
+		if !query.opts.BoostPopular {
+			reply.NextCursor = nextCursor(above)
+		}
+		writeComicsReply(log, w, reply)
+	}
+}
+
+type SearchFilters struct {
+	Tag string `json:"tag"`
+}
+
+type SearchQuery struct {
+	Phrase  string        `json:"phrase"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+	Op      string        `json:"op"`
+	Filters SearchFilters `json:"filters"`
+
+	NormalizeScore bool `json:"normalize_score"`
+	MinScore       int  `json:"min_score"`
+}
+
+// CodeStopWords marks replies rejecting a query of stop words only.
+const CodeStopWords = "stop_words_only"
+
+// ErrorReply has a Code for errors clients may need to tell apart.
+type ErrorReply struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+func replyError(log *slog.Logger, w http.ResponseWriter, status int, msg string) {
+	replyErrorCode(log, w, status, "", msg)
+}
+
+// replyErrorCode replies an error with code, also set in the error code
+// header for the metrics.
+func replyErrorCode(log *slog.Logger, w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	if code != "" {
+		w.Header().Set(middleware.ErrorCodeHeader, code)
+	}
+	w.WriteHeader(status)
+	if err := encodeReply(w, ErrorReply{Error: msg, Code: code}); err != nil {
+		log.Error("cannot encode reply", "error", err)
+	}
+}
+
+func (q SearchQuery) validate() error {
+	switch {
+	case q.Phrase == "":
+		return errors.New("no phrase")
+	case q.Limit < 0:
+		return errors.New("bad limit")
+	case q.Offset < 0:
+		return errors.New("bad offset")
+	case q.MinScore < 0:
+		return errors.New("bad min_score")
+	case q.NormalizeScore && q.Offset > 0:
+		return errors.New("bad normalize_score: cannot page with offset")
+	case q.Op != "" && q.Op != "and" && q.Op != "or" && q.Op != "phrase":
+		return errors.New("bad op, expected and/or/phrase")
+	}
+	return nil
+}
+
+// NewSearchQueryHandler searches by a JSON query, op "and" requires every keyword to match
+// and op "phrase" the words of the phrase next to each other, in order.
+func NewSearchQueryHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query SearchQuery
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&query); err != nil {
+			log.Error("malformed query", "error", err)
+			replyError(log, w, http.StatusBadRequest, "malformed query: "+err.Error())
+			return
+		}
+		if err := query.validate(); err != nil {
+			log.Error("invalid query", "error", err)
+			replyError(log, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		opts := core.SearchOptions{
+			Tag:      query.Filters.Tag,
+			Offset:   query.Offset,
+			MatchAll: query.Op == "and",
+			Phrase:   query.Op == "phrase",
+			Language: searchLanguage(r),
+		}
+		found, err := searcher.Search(r.Context(), query.Phrase, query.Limit, opts)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				replyError(log, w, http.StatusNotFound, "no comics found")
+				return
+			}
+			if errors.Is(err, core.ErrStopWords) {
+				replyErrorCode(log, w, http.StatusBadRequest, CodeStopWords, err.Error())
+				return
+			}
+			if errors.Is(err, core.ErrBadArguments) {
+				replyError(log, w, http.StatusBadRequest, err.Error())
+				return
+			}
+			log.Error("error while searching", "error", err)
+			replyError(log, w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		above, total := aboveScore(found.Comics, found.Total, opts, query.MinScore)
+		reply := newComicsReply(above, query.NormalizeScore)
+		reply.Total, reply.Errors = total, found.Errors
+		writeComicsReply(log, w, reply)
+	}
+}
+
+type ComicReply struct {
+	Comics
+	PrevID int `json:"prev_id,omitempty"`
+	NextID int `json:"next_id,omitempty"`
+}
+
+func NewComicHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		var nav bool
+		if navStr := r.URL.Query().Get("nav"); navStr != "" {
+			nav, err = strconv.ParseBool(navStr)
+			if err != nil {
+				http.Error(w, "invalid nav", http.StatusBadRequest)
+				return
+			}
+		}
+
+		comics, err := searcher.Comic(r.Context(), id, nav)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while getting comics", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		reply := ComicReply{
+			Comics: newComics(comics.Comics),
+			PrevID: comics.PrevID,
+			NextID: comics.NextID,
+		}
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+type DocumentFrequencyReply struct {
+	Term        string         `json:"term"`
+	Frequencies map[string]int `json:"frequencies"`
+}
+
+func NewDocumentFrequencyHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		term := r.URL.Query().Get("term")
+		if term == "" {
+			log.Error("no term")
+			http.Error(w, "no term", http.StatusBadRequest)
+			return
+		}
+
+		frequencies, err := searcher.DocumentFrequency(r.Context(), term)
+		if err != nil {
+			if errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, "bad term", http.StatusBadRequest)
+				return
+			}
+			log.Error("error while getting document frequency", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		reply := DocumentFrequencyReply{Term: term, Frequencies: frequencies}
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+// imageMaxAge lets clients keep comics images, they never change once published.
+const imageMaxAge = 24 * time.Hour
+
+func NewComicImageHandler(log *slog.Logger, searcher core.Searcher, images core.Images) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		comics, err := searcher.Comic(r.Context(), id, false)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while getting comics", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		image, err := images.Image(r.Context(), comics.Comics.URL)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) || errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, "no image found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while getting image", "id", id, "error", err)
+			http.Error(w, "cannot get image", http.StatusBadGateway)
+			return
+		}
+
+		cache := "MISS"
+		if image.Cached {
+			cache = "HIT"
+		}
+		w.Header().Set("Content-Type", image.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(image.Data)))
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(imageMaxAge.Seconds())))
+		w.Header().Set("X-Cache", cache)
+		if _, err := w.Write(image.Data); err != nil {
+			log.Error("cannot write image", "error", err)
+		}
+	}
+}
+
+// Explain formats, text drops the markup of explanations.
+const (
+	ExplainHTML = "html"
+	ExplainText = "text"
+)
+
+// ExplainRefresher also fetches explanations past its cache, keeping them.
+type ExplainRefresher interface {
+	core.Explainer
+	Refresh(ctx context.Context, id int) (core.ExplainXKCDInfo, error)
+	RefreshPage(ctx context.Context, page string) (core.ExplainXKCDInfo, error)
+}
+
+var (
+	markupTags = regexp.MustCompile(`<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+func explainText(markup string) string {
+	text := html.UnescapeString(markupTags.ReplaceAllString(markup, ""))
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
+
+// NewExplainHandler replies explanations in format unless the format
+// parameter asks for another, nocache=true fetches a fresh one. Only
+// admins verified by verifier may skip the cache, as fresh fetches count
+// towards the explainxkcd breaker.
+func NewExplainHandler(
+	log *slog.Logger, explainer ExplainRefresher, format string, verifier middleware.TokenVerifier,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		replyFormat := format
+		if f := r.URL.Query().Get("format"); f != "" {
+			replyFormat = f
+		}
+		if replyFormat != ExplainHTML && replyFormat != ExplainText {
+			http.Error(w, "bad format", http.StatusBadRequest)
+			return
+		}
+		var noCache bool
+		if noCacheStr := r.URL.Query().Get("nocache"); noCacheStr != "" {
+			var err error
+			if noCache, err = strconv.ParseBool(noCacheStr); err != nil {
+				http.Error(w, "bad nocache", http.StatusBadRequest)
+				return
+			}
+		}
+		if noCache && verifier.Verify(middleware.AccessToken(r)) != nil {
+			http.Error(w, "nocache needs authorization", http.StatusUnauthorized)
+			return
+		}
+		explain, explainPage := explainer.Explain, explainer.ExplainPage
+		if noCache {
+			explain, explainPage = explainer.Refresh, explainer.RefreshPage
+		}
+
+		var explanation core.ExplainXKCDInfo
+		var err error
+		if page := r.URL.Query().Get("page"); page != "" {
+			explanation, err = explainPage(r.Context(), page)
+		} else {
+			idStr := r.URL.Query().Get("id")
+			if idStr == "" {
+				http.Error(w, "missing id or page", http.StatusBadRequest)
+				return
+			}
+			id, convErr := strconv.Atoi(idStr)
+			if convErr != nil {
+				http.Error(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+			explanation, err = explain(r.Context(), id)
+		}
+		if err != nil {
+			log.Error("explain failed", "error", err)
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "not found", http.StatusNotFound)
+			} else if errors.Is(err, core.ErrUnavailable) {
+				http.Error(w, "explainxkcd is unavailable", http.StatusServiceUnavailable)
+			} else {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+			return
+		}
+		if replyFormat == ExplainText {
+			explanation.Text, explanation.HTML = explainText(explanation.HTML), ""
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(explanation); err != nil {
+			log.Error("failed to encode explanation response", "error", err)
+		}
+	}
+}
+
+func NewRefreshTokenHandler(log *slog.Logger, auth Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("refresh_token")
+		if err != nil {
+			log.Error("refresh token not found in cookie", "error", err)
+			http.Error(w, "refresh token not found", http.StatusUnauthorized)
+			return
+		}
+
+		newAccessToken, newRefreshToken, err := auth.RefreshAccessToken(cookie.Value)
+		if err != nil {
+			log.Error("could not refresh access token", "error", err)
+			http.Error(w, "could not refresh token", http.StatusUnauthorized)
+			return
+		}
+		if newRefreshToken != "" {
+			middleware.SetRefreshCookie(w, newRefreshToken, auth.RefreshTTL())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"access_token": newAccessToken,
+		}); err != nil {
+			log.Error("failed to write refresh response", "error", err)
+		}
+	}
+}
+
+type Revoker interface {
+	Revoke(token string) error
+}
+
+// NewLogoutHandler revokes the access token of the request and the refresh
+// token of the cookie. Tokens failing to be revoked are not valid anyway,
+// so the logout still succeeds.
+func NewLogoutHandler(log *slog.Logger, revoker Revoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := middleware.AccessToken(r); token != "" {
+			if err := revoker.Revoke(token); err != nil {
+				log.Warn("cannot revoke access token", "error", err)
+			}
+		}
+		if cookie, err := r.Cookie("refresh_token"); err == nil && cookie.Value != "" {
+			if err := revoker.Revoke(cookie.Value); err != nil {
+				log.Warn("cannot revoke refresh token", "error", err)
+			}
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     "refresh_token",
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   false,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"message": "logged out",
+		}); err != nil {
 			log.Error("failed to write logout response", "error", err)
 		}
 	}
 }
+
+type Tags struct {
+	Tags []string `json:"tags"`
+}
+
+func NewSetTagsHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		var tags Tags
+		if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+			http.Error(w, "invalid tags", http.StatusBadRequest)
+			return
+		}
+		if err := updater.SetTags(r.Context(), id, tags.Tags); err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while setting tags", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func NewComicByTitleHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		title := r.URL.Query().Get("title")
+		if strings.TrimSpace(title) == "" {
+			http.Error(w, "missing title", http.StatusBadRequest)
+			return
+		}
+		comics, err := searcher.ByTitle(r.Context(), title)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, "bad title", http.StatusBadRequest)
+				return
+			}
+			log.Error("error while finding comics by title", "title", title, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeComicsReply(log, w, newComicsReply(comics, false))
+	}
+}
+
+// NewClickHandler counts a click on a found comics for popularity ranking,
+// once per client IP and comics however often it is posted.
+func NewClickHandler(
+	log *slog.Logger, searcher core.Searcher, popularity core.Popularity, ips middleware.ClientIPs,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		// only stored comics are counted, so clicks cannot grow without bound
+		if _, err := searcher.Comic(r.Context(), id, false); err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while getting comics", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !popularity.Click(ips.IP(r), id) {
+			log.Debug("click not counted", "id", id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type ReindexReply struct {
+	Comics int `json:"comics"`
+}
+
+// NewReindexComicHandler reindexes the comics of id, or the comics with
+// IDs from from to to inclusive when a range is given.
+func NewReindexComicHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("from") || r.URL.Query().Has("to") {
+			reindexRange(log, searcher, w, r)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		if err := searcher.ReindexComic(r.Context(), id); err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while reindexing comics", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func reindexRange(log *slog.Logger, searcher core.Searcher, w http.ResponseWriter, r *http.Request) {
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from < 1 {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil || to < from {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+	comicsCount, err := searcher.ReindexRange(r.Context(), from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrBadArguments):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, core.ErrAlreadyExists):
+			http.Error(w, "range reindex already runs", http.StatusConflict)
+		default:
+			log.Error("error while reindexing comics", "from", from, "to", to, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := encodeReply(w, ReindexReply{Comics: comicsCount}); err != nil {
+		log.Error("cannot encode reply", "error", err)
+	}
+}
+
+func NewTagsHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id < 1 {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		tags, err := updater.GetTags(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, core.ErrNotFound) {
+				http.Error(w, "no comics found", http.StatusNotFound)
+				return
+			}
+			log.Error("error while getting tags", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+		if err := encodeReply(w, Tags{Tags: tags}); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
+
+type ChangedComics struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Alt       string    `json:"alt"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+type ChangesReply struct {
+	Comics      []ChangedComics `json:"comics"`
+	NextSince   time.Time       `json:"next_since"`
+	NextAfterID int             `json:"next_after_id"`
+}
+
+// NewChangesHandler lists comics changed after since, next_since and
+// next_after_id continue from the last returned one as since and after_id,
+// so comics changed at the same time are not skipped between pages, since
+// alone starts with comics changed at it. With
+// tombstones=true deleted comics are listed as well, marked deleted.
+func NewChangesHandler(log *slog.Logger, searcher core.Searcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+		if err != nil {
+			http.Error(w, "bad since, expected RFC3339 time", http.StatusBadRequest)
+			return
+		}
+		var limit int
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit < 0 {
+				http.Error(w, "bad limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var afterID int
+		if afterStr := r.URL.Query().Get("after_id"); afterStr != "" {
+			afterID, err = strconv.Atoi(afterStr)
+			if err != nil || afterID < 0 {
+				http.Error(w, "bad after_id", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var tombstones bool
+		if value := r.URL.Query().Get("tombstones"); value != "" {
+			tombstones, err = strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "bad tombstones", http.StatusBadRequest)
+				return
+			}
+		}
+
+		comics, err := searcher.Changes(r.Context(), since, afterID, limit, tombstones)
+		if err != nil {
+			if errors.Is(err, core.ErrBadArguments) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error("error while getting changes", "since", since, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		reply := ChangesReply{
+			Comics:      make([]ChangedComics, 0, len(comics)),
+			NextSince:   since,
+			NextAfterID: afterID,
+		}
+		for _, c := range comics {
+			reply.Comics = append(reply.Comics, ChangedComics{
+				ID: c.ID, URL: c.URL, Title: c.Title, Alt: c.Alt, UpdatedAt: c.UpdatedAt, Deleted: c.Deleted,
+			})
+			reply.NextSince, reply.NextAfterID = c.UpdatedAt, c.ID
+		}
+		if err := encodeReply(w, reply); err != nil {
+			log.Error("cannot encode reply", "error", err)
+		}
+	}
+}
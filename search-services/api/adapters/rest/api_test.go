@@ -0,0 +1,976 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/api/adapters/rest/middleware"
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+var noopLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+type fakeSearcher struct {
+	core.Searcher
+	phrase string
+	limit  int
+	opts   core.SearchOptions
+	comics []core.Comics
+	total  int
+	errors int
+}
+
+func (f *fakeSearcher) Search(_ context.Context, phrase string, limit int, opts core.SearchOptions) (core.SearchResult, error) {
+	f.phrase, f.limit, f.opts = phrase, limit, opts
+	return core.SearchResult{Comics: f.comics, Total: max(f.total, len(f.comics)), Errors: f.errors}, nil
+}
+
+func TestSearchQueryHandler(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 55, Title: "Useless", Score: 2}}}
+	body := `{"phrase": "math love", "limit": 5, "offset": 10, "op": "and", "filters": {"tag": "math"}}`
+
+	rec := httptest.NewRecorder()
+	NewSearchQueryHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "math love", searcher.phrase)
+	assert.Equal(t, 5, searcher.limit)
+	assert.Equal(t, core.SearchOptions{Tag: "math", Offset: 10, MatchAll: true}, searcher.opts)
+
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, 1, reply.Total)
+	assert.Equal(t, 55, reply.Comics[0].ID)
+}
+
+func TestSearchQueryHandler_Phrase(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 55, Score: 2}}}
+	body := `{"phrase": "self driving", "op": "phrase"}`
+
+	rec := httptest.NewRecorder()
+	NewSearchQueryHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, core.SearchOptions{Phrase: true}, searcher.opts)
+}
+
+func TestSearchHandler_AcceptLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"fr-CH, fr;q=0.9, en;q=0.8": "fr",
+		"de, ru;q=0.5, en;q=0.7":    "en",
+		"de-DE, ja":                 "",
+		"es;q=0, sv;q=0.2":          "sv",
+	}
+	for header, want := range cases {
+		searcher := &fakeSearcher{comics: []core.Comics{{ID: 1, Score: 1}}}
+		req := httptest.NewRequest(http.MethodGet, "/api/search?phrase=chats", nil)
+		req.Header.Set("Accept-Language", header)
+		rec := httptest.NewRecorder()
+
+		NewSearchHandler(noopLogger, searcher, false)(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, header)
+		assert.Equal(t, want, searcher.opts.Language, header)
+	}
+}
+
+func TestSearchQueryHandler_BadRequest(t *testing.T) {
+	bodies := []string{
+		`{"phrase": "math"`,
+		`{"phrase": "math", "unknown": 1}`,
+		`{"limit": 5}`,
+		`{"phrase": "math", "offset": -1}`,
+		`{"phrase": "math", "op": "xor"}`,
+	}
+	for _, body := range bodies {
+		rec := httptest.NewRecorder()
+		NewSearchQueryHandler(noopLogger, &fakeSearcher{})(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var reply ErrorReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+		assert.NotEmpty(t, reply.Error)
+	}
+}
+
+func TestSearchHandler_NormalizeScore(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{
+		{ID: 1, Score: 4},
+		{ID: 2, Score: 2},
+		{ID: 3, Score: 1},
+	}}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&normalize_score=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 3)
+	for i, want := range []float64{1, 0.5, 0.25} {
+		require.NotNil(t, reply.Comics[i].NormalizedScore)
+		assert.InDelta(t, want, *reply.Comics[i].NormalizedScore, 1e-9)
+	}
+	assert.Equal(t, 2, reply.Comics[1].Score)
+
+	rec = httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux", nil))
+	assert.NotContains(t, rec.Body.String(), "normalized_score")
+}
+
+func TestSearchHandler_NormalizeScorePaged(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{
+		{ID: 1, Score: 4},
+		{ID: 2, Score: 2},
+		{ID: 3, Score: 1},
+	}}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=1&normalize_score=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.NotEmpty(t, reply.Comics)
+	assert.InDelta(t, 1, *reply.Comics[0].NormalizedScore, 1e-9)
+
+	// later pages do not know the top score of the query
+	for _, paging := range []string{"offset=1", "cursor=" + encodeCursor(core.Comics{ID: 1, Score: 4})} {
+		rec := httptest.NewRecorder()
+		NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=1&normalize_score=true&"+paging, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, paging)
+	}
+
+	rec = httptest.NewRecorder()
+	body := `{"phrase": "linux", "limit": 1, "offset": 1, "normalize_score": true}`
+	NewSearchQueryHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func (f *fakeSearcher) Changes(
+	_ context.Context, since time.Time, afterID, limit int, tombstones bool,
+) ([]core.Comics, error) {
+	var changed []core.Comics
+	for _, c := range f.comics {
+		after := c.UpdatedAt.After(since) || c.UpdatedAt.Equal(since) && c.ID > afterID
+		if after && (tombstones || !c.Deleted) && len(changed) < limit {
+			changed = append(changed, c)
+		}
+	}
+	return changed, nil
+}
+
+func (f *fakeSearcher) LastID(context.Context) (int, error) {
+	var ID int
+	for _, c := range f.comics {
+		ID = max(ID, c.ID)
+	}
+	return ID, nil
+}
+
+func TestLatestIDHandler(t *testing.T) {
+	for want, comics := range map[int][]core.Comics{
+		0:    nil,
+		2950: {{ID: 12}, {ID: 2950}, {ID: 7}},
+	} {
+		rec := httptest.NewRecorder()
+		NewLatestIDHandler(noopLogger, &fakeSearcher{comics: comics})(rec,
+			httptest.NewRequest(http.MethodGet, "/api/comics/latest-id", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var reply LatestIDReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+		assert.Equal(t, want, reply.LatestID)
+	}
+}
+
+func TestChangesHandler(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	searcher := &fakeSearcher{comics: []core.Comics{
+		{ID: 1, UpdatedAt: base},
+		{ID: 2, UpdatedAt: base.Add(time.Hour)},
+		{ID: 3, UpdatedAt: base.Add(2 * time.Hour)},
+	}}
+
+	rec := httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet,
+		"/api/comics/changes?limit=1&after_id=1&since="+base.Format(time.RFC3339), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var reply ChangesReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 1)
+	assert.Equal(t, 2, reply.Comics[0].ID)
+	assert.True(t, base.Add(time.Hour).Equal(reply.NextSince))
+	assert.Equal(t, 2, reply.NextAfterID)
+
+	rec = httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet,
+		"/api/comics/changes?limit=1&after_id=0&since="+base.Add(time.Hour).Format(time.RFC3339), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 1)
+	assert.Equal(t, 2, reply.Comics[0].ID, "comics changed at since come after after_id")
+
+	rec = httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet,
+		"/api/comics/changes?after_id=-1&since="+base.Format(time.RFC3339), nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, "/api/comics/changes?since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestChangesHandler_Tombstones(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	searcher := &fakeSearcher{comics: []core.Comics{
+		{ID: 1, UpdatedAt: base.Add(time.Hour)},
+		{ID: 2, UpdatedAt: base.Add(2 * time.Hour), Deleted: true},
+	}}
+	url := "/api/comics/changes?limit=10&since=" + base.Format(time.RFC3339)
+
+	rec := httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ChangesReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 1)
+	assert.NotContains(t, rec.Body.String(), "deleted")
+
+	rec = httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, url+"&tombstones=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 2)
+	assert.Equal(t, 2, reply.Comics[1].ID)
+	assert.True(t, reply.Comics[1].Deleted)
+	assert.True(t, base.Add(2*time.Hour).Equal(reply.NextSince))
+
+	rec = httptest.NewRecorder()
+	NewChangesHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, url+"&tombstones=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSearchHandlers_TotalCountHeader(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 1}, {ID: 2}}}
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux", nil),
+		httptest.NewRequest(http.MethodHead, "/api/search?phrase=linux", nil),
+		httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(`{"phrase": "linux"}`)),
+	}
+	for _, r := range requests {
+		handler := NewSearchHandler(noopLogger, searcher, false)
+		if r.Method == http.MethodPost {
+			handler = NewSearchQueryHandler(noopLogger, searcher)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		require.Equal(t, http.StatusOK, rec.Code, r.Method)
+
+		var reply ComicsReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply), r.Method)
+		assert.Equal(t, strconv.Itoa(reply.Total), rec.Header().Get("X-Total-Count"), r.Method)
+		assert.Equal(t, "2", rec.Header().Get("X-Total-Count"), r.Method)
+	}
+}
+
+func TestSearchHandler_ComicsURLs(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{
+		{ID: 353, URL: "https://imgs.xkcd.com/comics/python.png"},
+	}}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=python", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 1)
+	assert.Equal(t, "https://imgs.xkcd.com/comics/python.png", reply.Comics[0].URL)
+	assert.Equal(t, "https://imgs.xkcd.com/comics/python.png", reply.Comics[0].ImageURL)
+	assert.Equal(t, "https://xkcd.com/353/", reply.Comics[0].PageURL)
+}
+
+func TestSearchHandler_MinScore(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{
+		{ID: 1, Score: 4},
+		{ID: 2, Score: 2},
+		{ID: 3, Score: 1},
+	}}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&min_score=2&normalize_score=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 2)
+	assert.Equal(t, 2, reply.Total)
+	assert.Equal(t, "2", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, 2, reply.Comics[1].ID)
+	assert.InDelta(t, 0.5, *reply.Comics[1].NormalizedScore, 1e-9)
+	assert.Len(t, searcher.comics, 3)
+
+	rec = httptest.NewRecorder()
+	body := `{"phrase": "linux", "min_score": 3}`
+	NewSearchQueryHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 1)
+	assert.Equal(t, 1, reply.Total)
+
+	rec = httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&min_score=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSearchHandlers_PartialResults(t *testing.T) {
+	searcher := &fakeSearcher{total: 3, errors: 1, comics: []core.Comics{{ID: 1, Score: 2}, {ID: 3, Score: 1}}}
+	for name, handler := range map[string]func(*slog.Logger, core.Searcher, bool) http.HandlerFunc{
+		"search": NewSearchHandler, "isearch": NewSearchIndexHandler,
+	} {
+		rec := httptest.NewRecorder()
+		handler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=linux", nil))
+		require.Equal(t, http.StatusOK, rec.Code, name)
+		var reply ComicsReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply), name)
+		assert.Len(t, reply.Comics, 2, name)
+		assert.Equal(t, 3, reply.Total, name)
+		assert.Equal(t, 1, reply.Errors, name)
+	}
+
+	rec := httptest.NewRecorder()
+	NewSearchQueryHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodPost, "/api/search",
+		strings.NewReader(`{"phrase": "linux"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"errors": 1`)
+}
+
+func TestSearchHandler_MinScoreTotalAcrossPages(t *testing.T) {
+	// the third page of 3 out of 20 matching comics
+	searcher := &fakeSearcher{total: 20, comics: []core.Comics{
+		{ID: 7, Score: 3},
+		{ID: 8, Score: 2},
+		{ID: 9, Score: 1},
+	}}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=3&offset=6&min_score=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 2)
+	assert.Equal(t, 8, reply.Total, "comics after the page rank lower still")
+
+	searcher.comics = []core.Comics{{ID: 1, Score: 5}, {ID: 2, Score: 4}, {ID: 3, Score: 4}}
+	rec = httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=3&min_score=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, 20, reply.Total, "nothing dropped from the page leaves the total")
+}
+
+func (f *fakeSearcher) Comic(_ context.Context, id int, _ bool) (core.ComicsNav, error) {
+	for _, c := range f.comics {
+		if c.ID == id {
+			return core.ComicsNav{Comics: c}, nil
+		}
+	}
+	return core.ComicsNav{}, core.ErrNotFound
+}
+
+type fakeImages struct {
+	url string
+}
+
+func (f *fakeImages) Image(_ context.Context, url string) (core.Image, error) {
+	f.url = url
+	return core.Image{Data: []byte("png"), ContentType: "image/png", Cached: true}, nil
+}
+
+func TestComicImageHandler(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 353, URL: "https://imgs.xkcd.com/comics/python.png"}}}
+	images := &fakeImages{}
+
+	rec := httptest.NewRecorder()
+	NewComicImageHandler(noopLogger, searcher, images)(rec, httptest.NewRequest(http.MethodGet, "/api/comic/image?id=353", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://imgs.xkcd.com/comics/python.png", images.url)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=86400", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "HIT", rec.Header().Get("X-Cache"))
+	assert.Equal(t, "png", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	NewComicImageHandler(noopLogger, searcher, images)(rec, httptest.NewRequest(http.MethodGet, "/api/comic/image?id=1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func (f *fakeSearcher) ByTitle(_ context.Context, title string) ([]core.Comics, error) {
+	var found []core.Comics
+	for _, c := range f.comics {
+		if strings.EqualFold(c.Title, title) {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 0 {
+		return nil, core.ErrNotFound
+	}
+	return found, nil
+}
+
+func TestComicByTitleHandler(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 353, Title: "Python"}}}
+
+	rec := httptest.NewRecorder()
+	NewComicByTitleHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, "/api/comic/by-title?title=PYTHON", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.Len(t, reply.Comics, 1)
+	assert.Equal(t, 353, reply.Comics[0].ID)
+
+	rec = httptest.NewRecorder()
+	NewComicByTitleHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, "/api/comic/by-title?title=Pythons", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	NewComicByTitleHandler(noopLogger, searcher)(rec, httptest.NewRequest(http.MethodGet, "/api/comic/by-title", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func (f *fakeSearcher) SearchIndex(_ context.Context, phrase string, limit int, opts core.SearchOptions) (core.SearchResult, error) {
+	f.phrase, f.limit, f.opts = phrase, limit, opts
+	return core.SearchResult{Comics: f.comics, Total: max(f.total, len(f.comics)), Errors: f.errors}, nil
+}
+
+type fakeNormalizer struct {
+	err error
+}
+
+func (f fakeNormalizer) Norm(_ context.Context, phrase string) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return strings.Fields(phrase), nil
+}
+
+func TestSelfTestHandler(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 1, URL: "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg"}}}
+
+	rec := httptest.NewRecorder()
+	NewSelfTestHandler(noopLogger, fakeNormalizer{}, searcher, "linux")(rec, httptest.NewRequest(http.MethodGet, "/api/selftest", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply SelfTestReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, "ok", reply.Status)
+	require.Len(t, reply.Steps, 2)
+	assert.True(t, reply.Steps[1].OK)
+	assert.Equal(t, "linux", searcher.phrase)
+}
+
+func TestSelfTestHandler_Failures(t *testing.T) {
+	cases := map[string]struct {
+		normalizer fakeNormalizer
+		searcher   *fakeSearcher
+		steps      int
+	}{
+		"words down":  {fakeNormalizer{err: errors.New("unavailable")}, &fakeSearcher{}, 1},
+		"no results":  {fakeNormalizer{}, &fakeSearcher{}, 2},
+		"no metadata": {fakeNormalizer{}, &fakeSearcher{comics: []core.Comics{{ID: 1}}}, 2},
+	}
+	for name, tc := range cases {
+		rec := httptest.NewRecorder()
+		NewSelfTestHandler(noopLogger, tc.normalizer, tc.searcher, "linux")(rec, httptest.NewRequest(http.MethodGet, "/api/selftest", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, name)
+		var reply SelfTestReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply), name)
+		assert.Equal(t, "fail", reply.Status, name)
+		require.Len(t, reply.Steps, tc.steps, name)
+		assert.NotEmpty(t, reply.Steps[tc.steps-1].Error, name)
+	}
+}
+
+func TestSearchHandler_Cursor(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 7, Score: 3}, {ID: 2, Score: 1}}}
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=2", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	require.NotEmpty(t, reply.NextCursor)
+	assert.Equal(t, core.Cursor{}, searcher.opts.After)
+
+	rec = httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=2&cursor="+reply.NextCursor, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, core.Cursor{Score: 1, ID: 2}, searcher.opts.After)
+
+	rec = httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&cursor=garbage", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSearchHandler_Boost(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 1, Score: 1}}}
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&boost=title:3,alt:2", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]int{"title": 3, "alt": 2}, searcher.opts.Boosts)
+
+	for _, boost := range []string{"title", "title:x", "body:2", "title:0", "title:3,"} {
+		rec := httptest.NewRecorder()
+		NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&boost="+boost, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, boost)
+	}
+}
+
+func TestSearchHandler_BoostPopularFirstPageOnly(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 7, Score: 3}, {ID: 2, Score: 1}}}
+	rec := httptest.NewRecorder()
+	NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&limit=2&boost_popular=true", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ComicsReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Empty(t, reply.NextCursor, "boosted scores cannot be paged")
+
+	for _, paging := range []string{"offset=2", "min_score=1", "cursor=" + encodeCursor(core.Comics{ID: 2, Score: 1})} {
+		rec := httptest.NewRecorder()
+		NewSearchHandler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux&boost_popular=true&"+paging, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, paging)
+	}
+}
+
+type fakeIndexStats struct {
+	core.Searcher
+	stats core.IndexStats
+}
+
+func (f *fakeIndexStats) IndexStats(context.Context) (core.IndexStats, error) {
+	return f.stats, nil
+}
+
+func TestReadyHandler_FirstBuild(t *testing.T) {
+	searcher := &fakeIndexStats{}
+	handler := NewReadyHandler(noopLogger, searcher, time.Hour)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var reply ReadyReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, "starting", reply.Status)
+
+	searcher.stats = core.IndexStats{Comics: 0, BuiltAt: time.Now()}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, "degraded", reply.Status, "empty index")
+
+	searcher.stats = core.IndexStats{Comics: 3000, BuiltAt: time.Now()}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, "ok", reply.Status)
+	assert.Equal(t, 3000, reply.Comics)
+
+	searcher.stats.BuiltAt = time.Now().Add(-2 * time.Hour)
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "stale index")
+}
+
+func TestComicsReply_OmitsEmptyOptionalFields(t *testing.T) {
+	var minimal bytes.Buffer
+	require.NoError(t, encodeReply(&minimal, Comics{ID: 1}))
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(minimal.Bytes(), &fields))
+	assert.Equal(t, map[string]any{"id": 1.0, "url": "", "title": "", "alt": "", "score": 0.0}, fields)
+
+	normalized := 1.0
+	var full bytes.Buffer
+	require.NoError(t, encodeReply(&full, Comics{
+		ID: 1, URL: "u", ImageURL: "u", PageURL: "p", Title: "t", Alt: "a", Score: 2,
+		NormalizedScore: &normalized,
+	}))
+	fields = nil
+	require.NoError(t, json.Unmarshal(full.Bytes(), &fields))
+	assert.Len(t, fields, 8)
+
+	var empty bytes.Buffer
+	require.NoError(t, encodeReply(&empty, newComicsReply(nil, false)))
+	assert.Contains(t, empty.String(), `"comics": []`)
+}
+
+type fakeIntrospector map[string]core.TokenInfo
+
+func (f fakeIntrospector) Introspect(token string) core.TokenInfo {
+	return f[token]
+}
+
+func TestIntrospectHandler(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	handler := NewIntrospectHandler(noopLogger, fakeIntrospector{"good": {
+		Active: true, Name: "admin", Role: "superuser", Type: "access",
+		IssuedAt: issued, ExpiresAt: issued.Add(time.Hour),
+	}})
+	introspect := func(token string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/token/introspect",
+			strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	assert.JSONEq(t, `{"active": true, "name": "admin", "role": "superuser", "type": "access",
+		"iat": 1700000000, "exp": 1700003600}`, introspect("good"))
+	assert.JSONEq(t, `{"active": false}`, introspect("forged"))
+}
+
+type notFoundSearcher struct {
+	core.Searcher
+}
+
+func (notFoundSearcher) Search(context.Context, string, int, core.SearchOptions) (core.SearchResult, error) {
+	return core.SearchResult{}, core.ErrNotFound
+}
+
+func (notFoundSearcher) SearchIndex(context.Context, string, int, core.SearchOptions) (core.SearchResult, error) {
+	return core.SearchResult{}, core.ErrNotFound
+}
+
+func TestSearchHandlers_EmptyOK(t *testing.T) {
+	for name, handler := range map[string]func(*slog.Logger, core.Searcher, bool) http.HandlerFunc{
+		"search": NewSearchHandler, "isearch": NewSearchIndexHandler,
+	} {
+		rec := httptest.NewRecorder()
+		handler(noopLogger, notFoundSearcher{}, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=zzz", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, name)
+
+		rec = httptest.NewRecorder()
+		handler(noopLogger, notFoundSearcher{}, true)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=zzz", nil))
+		require.Equal(t, http.StatusOK, rec.Code, name)
+		assert.JSONEq(t, `{"comics": [], "total": 0}`, rec.Body.String(), name)
+
+		rec = httptest.NewRecorder()
+		handler(noopLogger, notFoundSearcher{}, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=zzz&empty_ok=true", nil))
+		assert.Equal(t, http.StatusOK, rec.Code, name)
+
+		rec = httptest.NewRecorder()
+		handler(noopLogger, notFoundSearcher{}, true)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=zzz&empty_ok=false", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, name)
+	}
+}
+
+type stopWordsSearcher struct {
+	core.Searcher
+}
+
+func (stopWordsSearcher) Search(context.Context, string, int, core.SearchOptions) (core.SearchResult, error) {
+	return core.SearchResult{}, core.ErrStopWords
+}
+
+func (stopWordsSearcher) SearchIndex(context.Context, string, int, core.SearchOptions) (core.SearchResult, error) {
+	return core.SearchResult{}, core.ErrStopWords
+}
+
+func TestSearchHandlers_StopWords(t *testing.T) {
+	for name, handler := range map[string]func(*slog.Logger, core.Searcher, bool) http.HandlerFunc{
+		"search": NewSearchHandler, "isearch": NewSearchIndexHandler,
+	} {
+		rec := httptest.NewRecorder()
+		handler(noopLogger, stopWordsSearcher{}, true)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=the+and+or", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+		assert.Equal(t, CodeStopWords, rec.Header().Get(middleware.ErrorCodeHeader), name)
+	}
+
+	rec := httptest.NewRecorder()
+	NewSearchQueryHandler(noopLogger, stopWordsSearcher{})(rec,
+		httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"phrase": "the and or"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeStopWords, rec.Header().Get(middleware.ErrorCodeHeader))
+	var reply ErrorReply
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &reply))
+	assert.Equal(t, CodeStopWords, reply.Code)
+}
+
+type fakeExplainer struct{}
+
+func (fakeExplainer) Explain(_ context.Context, id int) (core.ExplainXKCDInfo, error) {
+	return core.ExplainXKCDInfo{ID: id, HTML: "by id"}, nil
+}
+
+func (fakeExplainer) ExplainPage(_ context.Context, page string) (core.ExplainXKCDInfo, error) {
+	return core.ExplainXKCDInfo{Page: page, HTML: "by page"}, nil
+}
+
+func (fakeExplainer) Refresh(_ context.Context, id int) (core.ExplainXKCDInfo, error) {
+	return core.ExplainXKCDInfo{ID: id, HTML: "<p>fresh &amp; by id</p>"}, nil
+}
+
+func (fakeExplainer) RefreshPage(_ context.Context, page string) (core.ExplainXKCDInfo, error) {
+	return core.ExplainXKCDInfo{Page: page, HTML: "<p>fresh by page</p>"}, nil
+}
+
+func TestExplainHandler_IDAndPage(t *testing.T) {
+	handler := NewExplainHandler(noopLogger, fakeExplainer{}, ExplainHTML, fakeVerifier{})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/explain?id=353", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ID": 353, "HTML": "by id"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/explain?id=353&page="+url.QueryEscape("Main Page"), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ID": 0, "Page": "Main Page", "HTML": "by page"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/explain", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSearchHandlers_OffsetAndTotal(t *testing.T) {
+	for name, handler := range map[string]func(*slog.Logger, core.Searcher, bool) http.HandlerFunc{
+		"search": NewSearchHandler, "isearch": NewSearchIndexHandler,
+	} {
+		searcher := &fakeSearcher{comics: []core.Comics{{ID: 7, Score: 2}, {ID: 9, Score: 1}}, total: 25}
+
+		rec := httptest.NewRecorder()
+		handler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=linux&limit=2&offset=20", nil))
+		require.Equal(t, http.StatusOK, rec.Code, name)
+		assert.Equal(t, 20, searcher.opts.Offset, name)
+
+		var reply ComicsReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply), name)
+		assert.Len(t, reply.Comics, 2, name)
+		assert.Equal(t, 25, reply.Total, name)
+		assert.Equal(t, "25", rec.Header().Get("X-Total-Count"), name)
+
+		for _, offset := range []string{"-1", "x"} {
+			rec = httptest.NewRecorder()
+			handler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=linux&offset="+offset, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+		}
+	}
+}
+
+type fakeRevoker struct {
+	revoked []string
+}
+
+func (f *fakeRevoker) Revoke(token string) error {
+	f.revoked = append(f.revoked, token)
+	return nil
+}
+
+func TestLogoutHandler_RevokesTokens(t *testing.T) {
+	revoker := &fakeRevoker{}
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	req.Header.Set("Authorization", "Bearer access")
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "refresh"})
+
+	rec := httptest.NewRecorder()
+	NewLogoutHandler(noopLogger, revoker)(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"access", "refresh"}, revoker.revoked)
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "refresh_token=;")
+
+	revoker.revoked = nil
+	rec = httptest.NewRecorder()
+	NewLogoutHandler(noopLogger, revoker)(rec, httptest.NewRequest(http.MethodPost, "/api/logout", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, revoker.revoked)
+}
+
+type fakeBreaker struct {
+	status core.BreakerStatus
+}
+
+func (f *fakeBreaker) Status() core.BreakerStatus {
+	return f.status
+}
+
+func (f *fakeBreaker) Reset() {
+	f.status = core.BreakerStatus{State: "closed", Threshold: f.status.Threshold}
+}
+
+func TestBreakerHandlers(t *testing.T) {
+	openedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	breaker := &fakeBreaker{status: core.BreakerStatus{State: "open", Failures: 5, Threshold: 5, OpenedAt: openedAt}}
+
+	rec := httptest.NewRecorder()
+	NewBreakerHandler(noopLogger, breaker)(rec, httptest.NewRequest(http.MethodGet, "/api/explain/breaker", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"state": "open", "failures": 5, "threshold": 5, "opened_at": "2024-05-01T12:00:00Z"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	NewResetBreakerHandler(noopLogger, breaker)(rec, httptest.NewRequest(http.MethodPost, "/api/explain/breaker/reset", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"state": "closed", "failures": 0, "threshold": 5}`, rec.Body.String())
+}
+
+func TestSearchHandlers_IncludeKeywords(t *testing.T) {
+	for name, handler := range map[string]func(*slog.Logger, core.Searcher, bool) http.HandlerFunc{
+		"search": NewSearchHandler, "isearch": NewSearchIndexHandler,
+	} {
+		searcher := &fakeSearcher{comics: []core.Comics{{ID: 353, Score: 1, Keywords: []string{"python", "fli"}}}}
+
+		rec := httptest.NewRecorder()
+		handler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=python&include=keywords", nil))
+		require.Equal(t, http.StatusOK, rec.Code, name)
+		assert.True(t, searcher.opts.IncludeKeywords, name)
+		var reply ComicsReply
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply), name)
+		assert.Equal(t, []string{"python", "fli"}, reply.Comics[0].Keywords, name)
+
+		searcher.comics[0].Keywords = nil
+		rec = httptest.NewRecorder()
+		handler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=python", nil))
+		require.Equal(t, http.StatusOK, rec.Code, name)
+		assert.False(t, searcher.opts.IncludeKeywords, name)
+		assert.NotContains(t, rec.Body.String(), "keywords", name)
+
+		rec = httptest.NewRecorder()
+		handler(noopLogger, searcher, false)(rec, httptest.NewRequest(http.MethodGet, "/?phrase=python&include=transcript", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+	}
+}
+
+type fakeReindexer struct {
+	core.Searcher
+	from, to int
+	err      error
+}
+
+func (f *fakeReindexer) ReindexRange(_ context.Context, from, to int) (int, error) {
+	f.from, f.to = from, to
+	return to - from + 1, f.err
+}
+
+func TestReindexHandler_Range(t *testing.T) {
+	searcher := &fakeReindexer{}
+	handler := NewReindexComicHandler(noopLogger, searcher)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/search/reindex?from=100&to=199", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var reply ReindexReply
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&reply))
+	assert.Equal(t, 100, reply.Comics)
+	assert.Equal(t, []int{100, 199}, []int{searcher.from, searcher.to})
+
+	for _, query := range []string{"from=0&to=3", "from=5&to=4", "from=1", "to=3", "from=a&to=3"} {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/search/reindex?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	searcher.err = core.ErrAlreadyExists
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/search/reindex?from=1&to=3", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+type fakeComicUpdater struct {
+	core.Updater
+	updated []int
+}
+
+func (f *fakeComicUpdater) UpdateOne(_ context.Context, id int) error {
+	if id == 404 {
+		return core.ErrNotFound
+	}
+	f.updated = append(f.updated, id)
+	return nil
+}
+
+func TestUpdateComicHandler(t *testing.T) {
+	updater := &fakeComicUpdater{}
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/db/comic/{id}", NewUpdateComicHandler(noopLogger, updater))
+
+	for path, code := range map[string]int{
+		"/api/db/comic/353": http.StatusOK,
+		"/api/db/comic/404": http.StatusNotFound,
+		"/api/db/comic/0":   http.StatusBadRequest,
+		"/api/db/comic/abc": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, code, rec.Code, path)
+	}
+	assert.Equal(t, []int{353}, updater.updated)
+}
+
+// fakeVerifier accepts the "admin" token only.
+type fakeVerifier struct {
+	middleware.TokenVerifier
+}
+
+func (fakeVerifier) Verify(token string) error {
+	if token != "admin" {
+		return errors.New("bad token")
+	}
+	return nil
+}
+
+func TestExplainHandler_FormatAndNoCache(t *testing.T) {
+	handler := NewExplainHandler(noopLogger, fakeExplainer{}, ExplainText, fakeVerifier{})
+	admin := func(r *http.Request) *http.Request {
+		r.Header.Set("Authorization", "Token admin")
+		return r
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, admin(httptest.NewRequest(http.MethodGet, "/api/explain?id=353&nocache=true", nil)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ID": 353, "HTML": "", "Text": "fresh & by id"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/explain?id=353&nocache=true", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "anonymous clients cannot skip the cache")
+
+	rec = httptest.NewRecorder()
+	handler(rec, admin(httptest.NewRequest(http.MethodGet, "/api/explain?page=Main&nocache=1&format=html", nil)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ID": 0, "Page": "Main", "HTML": "<p>fresh by page</p>"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/explain?id=353", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ID": 353, "HTML": "", "Text": "by id"}`, rec.Body.String())
+
+	for _, query := range []string{"id=353&format=pdf", "id=353&nocache=maybe"} {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/api/explain?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+// cursorToken is the opaque cursor clients pass back to continue paging.
+type cursorToken struct {
+	Score int `json:"s"`
+	ID    int `json:"i"`
+}
+
+func encodeCursor(c core.Comics) string {
+	data, _ := json.Marshal(cursorToken{Score: c.Score, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseCursor(r *http.Request) (core.Cursor, error) {
+	value := r.URL.Query().Get("cursor")
+	if value == "" {
+		return core.Cursor{}, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return core.Cursor{}, err
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return core.Cursor{}, err
+	}
+	if token.ID <= 0 || token.Score < 0 {
+		return core.Cursor{}, errors.New("cursor out of range")
+	}
+	return core.Cursor{Score: token.Score, ID: token.ID}, nil
+}
+
+// nextCursor points after the last result, empty when there is none.
+func nextCursor(comics []core.Comics) string {
+	if len(comics) == 0 {
+		return ""
+	}
+	return encodeCursor(comics[len(comics)-1])
+}
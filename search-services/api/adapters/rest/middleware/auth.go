@@ -3,22 +3,42 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 )
 
 type TokenVerifier interface {
 	Verify(token string) error
-	RefreshAccessToken(refreshToken string) (string, error)
+	RefreshAccessToken(refreshToken string) (accessToken string, newRefreshToken string, err error)
+	// RefreshTTL is the lifetime of issued refresh tokens.
+	RefreshTTL() time.Duration
+}
+
+// SetRefreshCookie hands the refresh token to the client, the cookie
+// lives as long as the token.
+func SetRefreshCookie(w http.ResponseWriter, refreshToken string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// AccessToken returns the token of the Authorization header, empty if there is none.
+func AccessToken(r *http.Request) string {
+	parts := strings.Fields(r.Header.Get("Authorization"))
+	if len(parts) == 2 && (parts[0] == "Bearer" || parts[0] == "Token") {
+		return parts[1]
+	}
+	return ""
 }
 
 func Auth(next http.HandlerFunc, verifier TokenVerifier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		parts := strings.Fields(r.Header.Get("Authorization"))
-		
-		var accessToken string
-		if len(parts) == 2 && (parts[0] == "Bearer" || parts[0] == "Token") {
-			accessToken = parts[1]
-		}
-
+		accessToken := AccessToken(r)
 		if accessToken == "" || verifier.Verify(accessToken) != nil {
 			cookie, err := r.Cookie("refresh_token")
 			if err != nil {
@@ -26,11 +46,14 @@ func Auth(next http.HandlerFunc, verifier TokenVerifier) http.HandlerFunc {
 				return
 			}
 
-			newAccessToken, err := verifier.RefreshAccessToken(cookie.Value)
+			newAccessToken, newRefreshToken, err := verifier.RefreshAccessToken(cookie.Value)
 			if err != nil {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
+			if newRefreshToken != "" {
+				SetRefreshCookie(w, newRefreshToken, verifier.RefreshTTL())
+			}
 
 			r.Header.Set("Authorization", "Bearer "+newAccessToken)
 			accessToken = newAccessToken
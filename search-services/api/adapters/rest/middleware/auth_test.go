@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatingVerifier accepts the "fresh" token only and rotates refresh tokens.
+type rotatingVerifier struct {
+	ttl time.Duration
+}
+
+func (rotatingVerifier) Verify(token string) error {
+	if token != "fresh" {
+		return errors.New("bad token")
+	}
+	return nil
+}
+
+func (rotatingVerifier) RefreshAccessToken(string) (string, string, error) {
+	return "fresh", "rotated", nil
+}
+
+func (v rotatingVerifier) RefreshTTL() time.Duration {
+	return v.ttl
+}
+
+func TestAuth_RefreshCookieLivesAsToken(t *testing.T) {
+	handler := Auth(func(http.ResponseWriter, *http.Request) {}, rotatingVerifier{ttl: 2 * time.Hour})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/db/stats", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old"})
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "rotated", cookies[0].Value)
+	assert.Equal(t, int((2 * time.Hour).Seconds()), cookies[0].MaxAge)
+}
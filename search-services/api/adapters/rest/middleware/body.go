@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// BodyTimeout reads request bodies within timeout before serving them,
+// a client too slow to send one gets a 408 JSON reply. The server read
+// timeout would just drop the connection instead. Bodies are buffered up
+// to maxSize bytes, larger ones get a 413.
+func BodyTimeout(next http.HandlerFunc, timeout time.Duration, maxSize int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next(w, r)
+			return
+		}
+		rc := http.NewResponseController(w)
+		// recorders in tests cannot set deadlines, the body is still read up front
+		_ = rc.SetReadDeadline(time.Now().Add(timeout))
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Connection", "close")
+			w.WriteHeader(http.StatusRequestTimeout)
+			_, _ = io.WriteString(w, `{"error": "request body was not received in time"}`+"\n")
+			return
+		}
+		if err != nil {
+			http.Error(w, "cannot read request body", http.StatusBadRequest)
+			return
+		}
+		_ = rc.SetReadDeadline(time.Time{})
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyTimeout_SlowBody(t *testing.T) {
+	var served int
+	server := httptest.NewServer(BodyTimeout(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		_, _ = w.Write(body)
+	}, 50*time.Millisecond, 1024))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "POST /api/login HTTP/1.1\r\nHost: api\r\nContent-Length: 40\r\n\r\n"+`{"name": "adm`)
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var reply struct {
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&reply))
+	assert.NotEmpty(t, reply.Error)
+	assert.Zero(t, served)
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader(`{"phrase": "linux"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"phrase": "linux"}`, string(body))
+}
+
+func TestBodyTimeout_TooLarge(t *testing.T) {
+	var served int
+	handler := BodyTimeout(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}, time.Second, 16)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(`{"phrase": "linux linux"}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Zero(t, served)
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(`{"phrase": "a"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, served)
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+var encoders = map[string]func(io.Writer) encoder{
+	"br":   func(w io.Writer) encoder { return brotli.NewWriter(w) },
+	"gzip": func(w io.Writer) encoder { return gzip.NewWriter(w) },
+}
+
+func SupportedEncoding(name string) bool {
+	_, ok := encoders[name]
+	return ok
+}
+
+// Compress encodes responses of at least minSize bytes with the algorithm the
+// client prefers most among algorithms, which are listed in server preference order.
+func Compress(next http.HandlerFunc, minSize int, algorithms []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiate(r.Header.Get("Accept-Encoding"), algorithms)
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		defer func() {
+			_ = cw.close()
+		}()
+		next.ServeHTTP(cw, r)
+	}
+}
+
+func negotiate(acceptEncoding string, algorithms []string) string {
+	prefs := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		prefs[strings.ToLower(name)] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, name := range algorithms {
+		if !SupportedEncoding(name) {
+			continue
+		}
+		q, ok := prefs[name]
+		if !ok {
+			q = prefs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the response until it is known to reach minSize,
+// so small responses are sent as is with a Content-Length.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	decided  bool
+	encoder  encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered data right away, streamed responses are never compressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		_ = cw.start(false)
+	}
+	if cw.encoder != nil {
+		_ = cw.encoder.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" ||
+		slices.Contains([]int{http.StatusNoContent, http.StatusNotModified}, cw.status) {
+		compress = false
+	}
+	if compress {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		cw.encoder = encoders[cw.encoding](cw.ResponseWriter)
+	}
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buffered := cw.buf
+	cw.buf = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buffered)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buffered)
+	return err
+}
+
+func (cw *compressWriter) close() error {
+	if !cw.decided {
+		if len(cw.buf) > 0 {
+			cw.Header().Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		}
+		return cw.start(false)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, body, acceptEncoding string) *http.Response {
+	t.Helper()
+	handler := Compress(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, body)
+	}, 100, []string{"br", "gzip"})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec.Result()
+}
+
+func TestCompress_Gzip(t *testing.T) {
+	body := strings.Repeat("xkcd ", 100)
+	resp := serve(t, body, "gzip, br;q=0.5")
+
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+
+	reader, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+}
+
+func TestCompress_Brotli(t *testing.T) {
+	body := strings.Repeat("xkcd ", 100)
+	resp := serve(t, body, "gzip, br")
+
+	assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+	got, err := io.ReadAll(brotli.NewReader(resp.Body))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+}
+
+func TestCompress_BelowThreshold(t *testing.T) {
+	resp := serve(t, "small", "gzip, br")
+
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(t, "5", resp.Header.Get("Content-Length"))
+
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "small", string(got))
+}
+
+func TestNegotiate(t *testing.T) {
+	algorithms := []string{"br", "gzip"}
+	assert.Equal(t, "br", negotiate("gzip, br", algorithms))
+	assert.Equal(t, "gzip", negotiate("gzip;q=1.0, br;q=0.1", algorithms))
+	assert.Equal(t, "gzip", negotiate("br;q=0, *", algorithms))
+	assert.Equal(t, "", negotiate("identity", algorithms))
+	assert.Equal(t, "", negotiate("", algorithms))
+}
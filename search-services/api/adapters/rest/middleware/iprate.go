@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// IPLimiter keeps a token bucket per client IP for up to maxKeys IPs.
+// While it is full, a new IP is admitted through a global overflow limit
+// evicting the least recently seen IP, so spoofed addresses can neither
+// grow memory nor get fresh buckets for free.
+type IPLimiter struct {
+	rps     rate.Limit
+	burst   int
+	maxKeys int
+	global  *rate.Limiter
+
+	mu    sync.Mutex
+	order *list.List // of *ipBucket, most recently seen first
+	keys  map[string]*list.Element
+}
+
+type ipBucket struct {
+	ip      string
+	limiter *rate.Limiter
+}
+
+func NewIPLimiter(rps, burst, maxKeys, overflowRPS int) *IPLimiter {
+	return &IPLimiter{
+		rps:     rate.Limit(rps),
+		burst:   max(burst, 1),
+		maxKeys: max(maxKeys, 1),
+		global:  rate.NewLimiter(rate.Limit(overflowRPS), max(overflowRPS, 1)),
+		order:   list.New(),
+		keys:    make(map[string]*list.Element),
+	}
+}
+
+// Allow takes a token of the ip bucket.
+func (l *IPLimiter) Allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if element, ok := l.keys[ip]; ok {
+		l.order.MoveToFront(element)
+		return element.Value.(*ipBucket).limiter.Allow()
+	}
+	if l.order.Len() >= l.maxKeys {
+		// rejected IPs evict nobody, so a flood cannot push out known clients
+		if !l.global.Allow() {
+			return false
+		}
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.keys, oldest.Value.(*ipBucket).ip)
+	}
+	bucket := &ipBucket{ip: ip, limiter: rate.NewLimiter(l.rps, l.burst)}
+	l.keys[ip] = l.order.PushFront(bucket)
+	return bucket.limiter.Allow()
+}
+
+// Len is the number of tracked IPs.
+func (l *IPLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// RatePerIP rejects requests of clients over their rate with 429.
+func RatePerIP(next http.HandlerFunc, limiter *IPLimiter, ips ClientIPs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !limiter.Allow(ips.IP(r)) {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// ClientIPs tells client IPs of requests. X-Forwarded-For is trusted only
+// when a request comes from one of the trusted proxies, then the client is
+// the nearest address in it that is not a trusted proxy itself.
+type ClientIPs struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPs trusts proxies given as IPs or CIDRs, none trusts no header.
+func NewClientIPs(proxies []string) (ClientIPs, error) {
+	var ips ClientIPs
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return ClientIPs{}, fmt.Errorf("bad trusted proxy %q: %v", proxy, err)
+			}
+			ips.trusted = append(ips.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return ClientIPs{}, fmt.Errorf("bad trusted proxy %q: %v", proxy, err)
+		}
+		ips.trusted = append(ips.trusted, prefix.Masked())
+	}
+	return ips, nil
+}
+
+func (c ClientIPs) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IP of the client of r, without the port.
+func (c ClientIPs) IP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !c.isTrusted(ip) {
+		return ip
+	}
+	// proxies append to the header, so the right end is the most trusted
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// a garbled hop was not written by a trusted proxy
+			return ip
+		}
+		if !c.isTrusted(hop) {
+			return hop
+		}
+		ip = hop
+	}
+	return ip
+}
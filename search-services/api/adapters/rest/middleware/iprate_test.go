@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPLimiter_PerIP(t *testing.T) {
+	limiter := NewIPLimiter(1, 1, 10, 1)
+
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.False(t, limiter.Allow("10.0.0.1"), "bucket of the IP is empty")
+	assert.True(t, limiter.Allow("10.0.0.2"), "other IPs have own buckets")
+}
+
+func TestIPLimiter_KeyCapEvictsAndFallsBack(t *testing.T) {
+	limiter := NewIPLimiter(1, 1, 2, 1)
+
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.True(t, limiter.Allow("10.0.0.2"))
+	assert.True(t, limiter.Allow("10.0.0.3"), "evicts 10.0.0.1, takes the global token")
+	assert.Equal(t, 2, limiter.Len())
+
+	assert.False(t, limiter.Allow("10.0.0.4"), "global fallback is exhausted")
+	assert.False(t, limiter.Allow("10.0.0.1"), "evicted IP is new again")
+	assert.Equal(t, 2, limiter.Len())
+}
+
+func TestRatePerIP(t *testing.T) {
+	handler := RatePerIP(func(w http.ResponseWriter, _ *http.Request) {}, NewIPLimiter(1, 1, 10, 1), ClientIPs{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req.RemoteAddr = "10.0.0.1:5678"
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestRatePerIP_BehindProxy(t *testing.T) {
+	ips, err := NewClientIPs([]string{"192.168.0.1"})
+	require.NoError(t, err)
+	handler := RatePerIP(func(w http.ResponseWriter, _ *http.Request) {}, NewIPLimiter(1, 1, 10, 1), ips)
+
+	rec := httptest.NewRecorder()
+	handler(rec, forwarded("192.168.0.1:1234", "1.2.3.4"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler(rec, forwarded("192.168.0.1:1234", "5.6.7.8"))
+	assert.Equal(t, http.StatusOK, rec.Code, "clients behind the proxy have own buckets")
+
+	rec = httptest.NewRecorder()
+	handler(rec, forwarded("192.168.0.1:5678", "1.2.3.4"))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func forwarded(remote string, forwardedFor ...string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+	req.RemoteAddr = remote
+	for _, value := range forwardedFor {
+		req.Header.Add("X-Forwarded-For", value)
+	}
+	return req
+}
+
+func TestClientIPs_NoTrustedProxies(t *testing.T) {
+	ips, err := NewClientIPs(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.1", ips.IP(forwarded("10.0.0.1:1234")))
+	assert.Equal(t, "10.0.0.1", ips.IP(forwarded("10.0.0.1:1234", "1.2.3.4")), "header is not trusted")
+}
+
+func TestClientIPs_UntrustedForwardedFor(t *testing.T) {
+	ips, err := NewClientIPs([]string{"192.168.0.0/16"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.1", ips.IP(forwarded("10.0.0.1:1234", "1.2.3.4")),
+		"only trusted proxies may forward")
+}
+
+func TestClientIPs_TrustedForwardedFor(t *testing.T) {
+	ips, err := NewClientIPs([]string{"192.168.0.0/16", "10.0.0.7"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.2.3.4", ips.IP(forwarded("192.168.1.1:1234", "1.2.3.4")))
+	assert.Equal(t, "1.2.3.4", ips.IP(forwarded("192.168.1.1:1234", "6.6.6.6, 1.2.3.4, 10.0.0.7")),
+		"spoofed hops left of the client are ignored")
+	assert.Equal(t, "1.2.3.4", ips.IP(forwarded("192.168.1.1:1234", "6.6.6.6", "1.2.3.4")),
+		"several headers are one list")
+	assert.Equal(t, "192.168.1.1", ips.IP(forwarded("192.168.1.1:1234", "not an ip")))
+	assert.Equal(t, "192.168.1.1", ips.IP(forwarded("192.168.1.1:1234")))
+}
+
+func TestNewClientIPs_BadProxy(t *testing.T) {
+	_, err := NewClientIPs([]string{"proxy.local"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/metrics"
+)
+
+// unmatchedRoute labels requests no pattern of the mux matches, so that
+// unknown paths do not make a label each.
+const unmatchedRoute = "unmatched"
+
+// ErrorCodeHeader is set by handlers rejecting a request for a reason
+// counted apart from the status.
+const ErrorCodeHeader = "X-Error-Code"
+
+// statusWriter remembers the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController flush streamed responses.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// Metrics records requests served by mux, labelled by the path of the
+// matched pattern rather than the requested path.
+func Metrics(mux *http.ServeMux, recorder *metrics.HTTP) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		if _, pattern := mux.Handler(r); pattern != "" {
+			_, path, found := strings.Cut(pattern, " ")
+			if !found {
+				path = pattern
+			}
+			route = path
+		}
+
+		recorder.Started(route, r.Method)
+		sw := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+		defer func() {
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+			recorder.Finished(route, r.Method, sw.status, time.Since(start))
+			if code := sw.Header().Get(ErrorCodeHeader); code != "" {
+				recorder.Rejected(route, r.Method, code)
+			}
+		}()
+		mux.ServeHTTP(sw, r)
+	}
+}
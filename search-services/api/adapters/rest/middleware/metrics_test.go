@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/metrics"
+)
+
+// newRecorder records into a registry of its own served by the handler.
+func newRecorder() (*metrics.HTTP, http.Handler) {
+	registry := prometheus.NewRegistry()
+	return metrics.NewHTTP(registry), promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func TestMetrics_RoutePatternAndStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/comic/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "0" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	recorder, exposition := newRecorder()
+	handler := Metrics(mux, recorder)
+
+	for _, path := range []string{"/api/comic/1", "/api/comic/2", "/api/comic/0", "/unknown"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	exposition.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/comic/{id}",status="200"} 2`+"\n")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/comic/{id}",status="404"} 1`+"\n")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`+"\n")
+	assert.NotContains(t, body, "/api/comic/1")
+}
+
+func TestMetrics_RejectedByErrorCode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("phrase") == "the" {
+			w.Header().Set(ErrorCodeHeader, "stop_words_only")
+		}
+		http.Error(w, "bad", http.StatusBadRequest)
+	})
+	recorder, exposition := newRecorder()
+	handler := Metrics(mux, recorder)
+
+	for _, path := range []string{"/api/search?phrase=the", "/api/search?phrase=the", "/api/search"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	exposition.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/search",status="400"} 3`+"\n")
+	assert.Contains(t, body,
+		`http_requests_rejected_total{code="stop_words_only",method="GET",route="/api/search"} 2`+"\n")
+}
+
+func TestMetrics_Flushes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("event"))
+		assert.NoError(t, http.NewResponseController(w).Flush())
+	})
+
+	rec := httptest.NewRecorder()
+	recorder, _ := newRecorder()
+	Metrics(mux, recorder)(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	assert.True(t, rec.Flushed)
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	SlashRedirect = "redirect"
+	SlashRewrite  = "rewrite"
+	SlashOff      = "off"
+)
+
+func SupportedSlashMode(mode string) bool {
+	return mode == SlashRedirect || mode == SlashRewrite || mode == SlashOff
+}
+
+// TrailingSlash canonicalizes paths like /api/search/ to /api/search,
+// either redirecting clients or serving the canonical route in place.
+func TrailingSlash(next http.HandlerFunc, mode string) http.HandlerFunc {
+	if mode == SlashOff {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || !strings.HasSuffix(r.URL.Path, "/") {
+			next(w, r)
+			return
+		}
+		// Clean collapses leading slashes too, so //evil.example/ can't
+		// turn into a protocol-relative redirect off the site
+		trimmed := path.Clean(r.URL.Path)
+		if mode == SlashRedirect {
+			target := *r.URL
+			target.Path, target.RawPath = trimmed, ""
+			// 308 keeps the method and body of the original request
+			http.Redirect(w, r, target.RequestURI(), http.StatusPermanentRedirect)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path, r2.URL.RawPath = trimmed, ""
+		r2.RequestURI = r2.URL.RequestURI()
+		next(w, r2)
+	}
+}
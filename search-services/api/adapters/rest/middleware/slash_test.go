@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/search", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("search " + r.URL.Query().Get("phrase")))
+	})
+	return mux
+}
+
+func TestTrailingSlash_Rewrite(t *testing.T) {
+	handler := TrailingSlash(searchMux().ServeHTTP, SlashRewrite)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/search/?phrase=linux", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "search linux", rec.Body.String())
+}
+
+func TestTrailingSlash_Redirect(t *testing.T) {
+	handler := TrailingSlash(searchMux().ServeHTTP, SlashRedirect)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/search//?phrase=linux", nil))
+
+	assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+	assert.Equal(t, "/api/search?phrase=linux", rec.Header().Get("Location"))
+}
+
+func TestTrailingSlash_Off(t *testing.T) {
+	handler := TrailingSlash(searchMux().ServeHTTP, SlashOff)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/search/", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTrailingSlash_RedirectStaysOnSite(t *testing.T) {
+	handler := TrailingSlash(searchMux().ServeHTTP, SlashRedirect)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "//evil.example/", nil))
+
+	assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+	assert.Equal(t, "/evil.example", rec.Header().Get("Location"))
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+)
+
+// StreamsPerIP caps the requests served at once per client IP, it bounds
+// long-lived streams that request rates do not.
+func StreamsPerIP(next http.HandlerFunc, limit int, ips ClientIPs) http.HandlerFunc {
+	var mu sync.Mutex
+	open := make(map[string]int)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := ips.IP(r)
+		mu.Lock()
+		if open[ip] >= limit {
+			mu.Unlock()
+			http.Error(w, "too many streams", http.StatusTooManyRequests)
+			return
+		}
+		open[ip]++
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			if open[ip]--; open[ip] == 0 {
+				delete(open, ip)
+			}
+			mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	}
+}
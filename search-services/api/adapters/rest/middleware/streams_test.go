@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamsPerIP(t *testing.T) {
+	release := make(chan struct{})
+	opened := make(chan struct{})
+	handler := StreamsPerIP(func(w http.ResponseWriter, _ *http.Request) {
+		opened <- struct{}{}
+		<-release
+	}, 1, ClientIPs{})
+	stream := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/search/sse", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	done := make(chan int)
+	go func() { done <- stream("10.0.0.1:1234").Code }()
+	<-opened
+
+	assert.Equal(t, http.StatusTooManyRequests, stream("10.0.0.1:5678").Code)
+	go func() { done <- stream("10.0.0.2:1234").Code }()
+	<-opened
+
+	release <- struct{}{}
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	go func() { done <- stream("10.0.0.1:5678").Code }()
+	<-opened
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done, "closed streams free their slot")
+}
@@ -0,0 +1,219 @@
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+// SearchStreams passes phrases typed after a search event stream is open
+// to the stream, only the latest pending phrase of a stream is kept.
+type SearchStreams struct {
+	mu      sync.Mutex
+	streams map[string]chan string
+}
+
+func NewSearchStreams() *SearchStreams {
+	return &SearchStreams{streams: make(map[string]chan string)}
+}
+
+func (s *SearchStreams) open() (string, chan string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	stream, phrases := hex.EncodeToString(id), make(chan string, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[stream] = phrases
+	return stream, phrases, nil
+}
+
+func (s *SearchStreams) close(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, id)
+}
+
+// send replaces a phrase the stream has not searched yet.
+func (s *SearchStreams) send(id, phrase string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phrases, ok := s.streams[id]
+	if !ok {
+		return false
+	}
+	select {
+	case <-phrases:
+	default:
+	}
+	phrases <- phrase
+	return true
+}
+
+// SearchEvent are results of one phrase searched by a stream.
+type SearchEvent struct {
+	Phrase string   `json:"phrase"`
+	Comics []Comics `json:"comics"`
+	Total  int      `json:"total"`
+	Errors int      `json:"errors,omitempty"`
+}
+
+type StreamEvent struct {
+	ID string `json:"id"`
+}
+
+func writeEvent(w io.Writer, event string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("could not encode event: %v", err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+	return err
+}
+
+// NewSearchEventsHandler streams index search results as server-sent events.
+// The stream event tells the id to post further phrases with, every phrase
+// searched is answered by a results event or an error event. There is no
+// streaming search core behind it, each event is a unary SearchIndex call.
+func NewSearchEventsHandler(log *slog.Logger, searcher core.Searcher, streams *SearchStreams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var limit int
+		var err error
+		limitStr := r.URL.Query().Get("limit")
+		if limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit < 0 {
+				log.Error("wrong limit", "value", limitStr)
+				http.Error(w, "bad limit", http.StatusBadRequest)
+				return
+			}
+		}
+		rc := http.NewResponseController(w)
+
+		id, phrases, err := streams.open()
+		if err != nil {
+			log.Error("cannot open search stream", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer streams.close(id)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		if err := writeEvent(w, "stream", StreamEvent{ID: id}); err != nil {
+			log.Error("cannot write event", "error", err)
+			return
+		}
+		if phrase := r.URL.Query().Get("phrase"); phrase != "" {
+			streams.send(id, phrase)
+		}
+		opts := core.SearchOptions{Tag: r.URL.Query().Get("tag"), Language: searchLanguage(r)}
+		for {
+			if err := rc.Flush(); err != nil {
+				log.Error("cannot flush events", "error", err)
+				return
+			}
+			var phrase string
+			select {
+			case <-r.Context().Done():
+				return
+			case phrase = <-phrases:
+			}
+
+			found, err := searcher.SearchIndex(r.Context(), phrase, limit, opts)
+			switch {
+			case err == nil || errors.Is(err, core.ErrNotFound):
+				event := SearchEvent{
+					Phrase: phrase, Comics: make([]Comics, 0, len(found.Comics)),
+					Total: found.Total, Errors: found.Errors,
+				}
+				for _, c := range found.Comics {
+					event.Comics = append(event.Comics, newComics(c))
+				}
+				err = writeEvent(w, "results", event)
+			case r.Context().Err() != nil:
+				return
+			default:
+				log.Error("error while searching", "error", err)
+				err = writeEvent(w, "error", ErrorReply{Error: err.Error()})
+			}
+			if err != nil {
+				log.Error("cannot write event", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// NewSearchEventsQueryHandler searches a phrase on an open event stream.
+func NewSearchEventsQueryHandler(log *slog.Logger, streams *SearchStreams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("stream")
+		phrase := r.URL.Query().Get("phrase")
+		if id == "" || phrase == "" {
+			log.Error("no stream or phrase")
+			http.Error(w, "no stream or phrase", http.StatusBadRequest)
+			return
+		}
+		if !streams.send(id, phrase) {
+			http.Error(w, "unknown stream", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// UpdateProgressEvent tells how many of the Total missing comics the
+// running update has fetched.
+type UpdateProgressEvent struct {
+	Fetched   int `json:"fetched"`
+	Total     int `json:"total"`
+	CurrentID int `json:"current_id"`
+}
+
+// NewUpdateProgressHandler streams the progress of the running update as
+// server-sent progress events, a done event ends the stream once the update
+// finishes, at once when none runs.
+func NewUpdateProgressHandler(log *slog.Logger, updater core.Updater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		progress, err := updater.Progress(r.Context())
+		if err != nil {
+			log.Error("cannot watch update progress", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rc := http.NewResponseController(w)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		for p := range progress {
+			event := UpdateProgressEvent{Fetched: p.Fetched, Total: p.Total, CurrentID: p.CurrentID}
+			if err := writeEvent(w, "progress", event); err != nil {
+				log.Error("cannot write event", "error", err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				log.Error("cannot flush events", "error", err)
+				return
+			}
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+		if err := writeEvent(w, "done", struct{}{}); err != nil {
+			log.Error("cannot write event", "error", err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+func readEvent(t *testing.T, events *bufio.Reader, data any) string {
+	t.Helper()
+	var name string
+	for {
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return name
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), data))
+		}
+	}
+}
+
+func TestSearchEvents_Requery(t *testing.T) {
+	searcher := &fakeSearcher{comics: []core.Comics{{ID: 353, Title: "Python", Score: 2}}}
+	streams := NewSearchStreams()
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/search/sse", NewSearchEventsHandler(noopLogger, searcher, streams))
+	mux.Handle("POST /api/search/sse", NewSearchEventsQueryHandler(noopLogger, streams))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/search/sse?phrase=pyth&limit=3")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+	events := bufio.NewReader(resp.Body)
+
+	var stream StreamEvent
+	require.Equal(t, "stream", readEvent(t, events, &stream))
+	require.NotEmpty(t, stream.ID)
+
+	var results SearchEvent
+	require.Equal(t, "results", readEvent(t, events, &results))
+	assert.Equal(t, "pyth", results.Phrase)
+	assert.Equal(t, 1, results.Total)
+	require.Len(t, results.Comics, 1)
+	assert.Equal(t, 353, results.Comics[0].ID)
+
+	posted, err := http.Post(server.URL+"/api/search/sse?stream="+stream.ID+"&phrase=python", "", nil)
+	require.NoError(t, err)
+	posted.Body.Close()
+	require.Equal(t, http.StatusAccepted, posted.StatusCode)
+
+	require.Equal(t, "results", readEvent(t, events, &results))
+	assert.Equal(t, "python", results.Phrase)
+	assert.Equal(t, 3, searcher.limit)
+
+	posted, err = http.Post(server.URL+"/api/search/sse?stream=unknown&phrase=python", "", nil)
+	require.NoError(t, err)
+	posted.Body.Close()
+	assert.Equal(t, http.StatusNotFound, posted.StatusCode)
+}
+
+type fakeProgressUpdater struct {
+	core.Updater
+	progress []core.UpdateProgress
+}
+
+func (f fakeProgressUpdater) Progress(context.Context) (<-chan core.UpdateProgress, error) {
+	ch := make(chan core.UpdateProgress, len(f.progress))
+	for _, p := range f.progress {
+		ch <- p
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestUpdateProgress(t *testing.T) {
+	updater := fakeProgressUpdater{progress: []core.UpdateProgress{
+		{Total: 2}, {Fetched: 1, Total: 2, CurrentID: 403}, {Fetched: 2, Total: 2, CurrentID: 405},
+	}}
+	rec := httptest.NewRecorder()
+	NewUpdateProgressHandler(noopLogger, updater)(rec, httptest.NewRequest(http.MethodGet, "/api/db/progress", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	events := bufio.NewReader(rec.Body)
+	var progress []UpdateProgressEvent
+	for range updater.progress {
+		var event UpdateProgressEvent
+		require.Equal(t, "progress", readEvent(t, events, &event))
+		progress = append(progress, event)
+	}
+	assert.Equal(t, []UpdateProgressEvent{
+		{Total: 2}, {Fetched: 1, Total: 2, CurrentID: 403}, {Fetched: 2, Total: 2, CurrentID: 405},
+	}, progress)
+	var done struct{}
+	assert.Equal(t, "done", readEvent(t, events, &done))
+}
@@ -2,24 +2,34 @@ package search
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/liy0aay/xkcd-search/api/core"
 	searchpb "github.com/liy0aay/xkcd-search/proto/search"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Client struct {
 	log    *slog.Logger
 	client searchpb.SearchClient
 	conn   *grpc.ClientConn
+
+	inflight singleflight.Group
 }
 
-func NewClient(address string, log *slog.Logger) (*Client, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewClient(address string, log *slog.Logger, opts ...grpc.DialOption) (*Client, error) {
+	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(address, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,41 +44,245 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
-func (c *Client) Search(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
-	reply, err := c.client.Search(ctx, &searchpb.SearchRequest{
-		Phrase: phrase, Limit: int64(limit),
+func (c *Client) Search(
+	ctx context.Context, phrase string, limit int, opts core.SearchOptions,
+) (core.SearchResult, error) {
+	return c.coalesce(ctx, "search", phrase, limit, opts, c.client.Search)
+}
+
+func (c *Client) SearchIndex(
+	ctx context.Context, phrase string, limit int, opts core.SearchOptions,
+) (core.SearchResult, error) {
+	return c.coalesce(ctx, "index", phrase, limit, opts, c.client.SearchIndex)
+}
+
+// isStopWords tells whether the service rejected a query of stop words only.
+func isStopWords(err error) bool {
+	for _, detail := range status.Convert(err).Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if ok && info.Domain == searchpb.ErrorDomain && info.Reason == searchpb.ReasonStopWords {
+			return true
+		}
+	}
+	return false
+}
+
+type searchCall func(context.Context, *searchpb.SearchRequest, ...grpc.CallOption) (*searchpb.SearchReply, error)
+
+// sharedCallTimeout bounds a coalesced call, it is not bound to the context
+// of the caller starting it so others joining it survive that caller leaving.
+const sharedCallTimeout = 30 * time.Second
+
+// coalesce makes concurrent identical searches share a single backend call,
+// every caller still gives up when its own context is done.
+func (c *Client) coalesce(
+	ctx context.Context, method, phrase string, limit int, opts core.SearchOptions, call searchCall,
+) (core.SearchResult, error) {
+	key := fmt.Sprintf("%s|%s|%d|%d|%s|%t|%s|%d|%d|%v|%t|%t", method,
+		strings.ToLower(strings.Join(strings.Fields(phrase), " ")),
+		limit, opts.Offset, opts.Tag, opts.MatchAll, opts.Language, opts.After.Score, opts.After.ID, opts.Boosts,
+		opts.IncludeKeywords, opts.Phrase)
+	boosts := make(map[string]int64, len(opts.Boosts))
+	for field, boost := range opts.Boosts {
+		boosts[field] = int64(boost)
+	}
+
+	ch := c.inflight.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedCallTimeout)
+		defer cancel()
+		reply, err := call(ctx, &searchpb.SearchRequest{
+			Phrase: phrase, Limit: int64(limit),
+			Tag: opts.Tag, Offset: int64(opts.Offset), MatchAll: opts.MatchAll,
+			Language:   opts.Language,
+			AfterScore: int64(opts.After.Score), AfterId: int64(opts.After.ID),
+			Boosts: boosts, IncludeKeywords: opts.IncludeKeywords, PhraseMode: opts.Phrase,
+		})
+		if err != nil {
+			switch status.Code(err) {
+			case codes.NotFound:
+				return nil, core.ErrNotFound
+			case codes.InvalidArgument:
+				if isStopWords(err) {
+					return nil, core.ErrStopWords
+				}
+				return nil, fmt.Errorf("%w: %s", core.ErrBadArguments, status.Convert(err).Message())
+			}
+			return nil, err
+		}
+		comics := make([]core.Comics, 0, len(reply.Comics))
+		for _, c := range reply.Comics {
+			comics = append(comics, core.Comics{
+				ID: int(c.Id), URL: c.Url, Title: c.Title, Alt: c.Alt, Score: int(c.Score), Keywords: c.Keywords,
+			})
+		}
+		if reply.Errors > 0 {
+			c.log.Warn("search results are partial", "phrase", phrase, "failed", reply.Errors)
+		}
+		return core.SearchResult{Comics: comics, Total: int(reply.Total), Errors: int(reply.Errors)}, nil
 	})
+
+	select {
+	case <-ctx.Done():
+		return core.SearchResult{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return core.SearchResult{}, res.Err
+		}
+		if res.Shared {
+			c.log.Debug("search coalesced", "phrase", phrase)
+		}
+		result := res.Val.(core.SearchResult)
+		result.Comics = slices.Clone(result.Comics)
+		return result, nil
+	}
+}
+
+func (c *Client) Comic(ctx context.Context, id int, nav bool) (core.ComicsNav, error) {
+	reply, err := c.client.Comic(ctx, &searchpb.ComicRequest{Id: int64(id), Nav: nav})
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
+			return core.ComicsNav{}, core.ErrNotFound
+		}
+		return core.ComicsNav{}, err
+	}
+	comics := reply.GetComics()
+	return core.ComicsNav{
+		Comics: core.Comics{ID: int(comics.GetId()), URL: comics.GetUrl(), Title: comics.GetTitle(), Alt: comics.GetAlt()},
+		PrevID: int(reply.GetPrevId()),
+		NextID: int(reply.GetNextId()),
+	}, nil
+}
+
+func (c *Client) ByTitle(ctx context.Context, title string) ([]core.Comics, error) {
+	reply, err := c.client.ByTitle(ctx, &searchpb.TitleRequest{Title: title})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound:
 			return nil, core.ErrNotFound
+		case codes.InvalidArgument:
+			return nil, core.ErrBadArguments
 		}
 		return nil, err
 	}
-	comics := make([]core.Comics, 0, len(reply.Comics))
-	for _, c := range reply.Comics {
-		comics = append(comics, core.Comics{ID: int(c.Id), URL: c.Url, Title: c.Title, Alt: c.Alt, Score: int(c.Score)})
+	comics := make([]core.Comics, 0, len(reply.GetComics()))
+	for _, c := range reply.GetComics() {
+		comics = append(comics, core.Comics{ID: int(c.GetId()), URL: c.GetUrl(), Title: c.GetTitle(), Alt: c.GetAlt()})
 	}
 	return comics, nil
 }
 
-func (c *Client) SearchIndex(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
-	reply, err := c.client.SearchIndex(ctx, &searchpb.SearchRequest{
-		Phrase: phrase, Limit: int64(limit),
+func (c *Client) ReindexComic(ctx context.Context, id int) error {
+	_, err := c.client.ReindexComic(ctx, &searchpb.ComicRequest{Id: int64(id)})
+	if status.Code(err) == codes.NotFound {
+		return core.ErrNotFound
+	}
+	return err
+}
+
+func (c *Client) ReindexRange(ctx context.Context, from, to int) (int, error) {
+	reply, err := c.client.ReindexRange(ctx, &searchpb.ReindexRangeRequest{From: int64(from), To: int64(to)})
+	switch status.Code(err) {
+	case codes.OK:
+		return int(reply.GetComics()), nil
+	case codes.InvalidArgument:
+		return 0, core.ErrBadArguments
+	case codes.AlreadyExists:
+		return 0, core.ErrAlreadyExists
+	}
+	return 0, err
+}
+
+func (c *Client) Changes(
+	ctx context.Context, since time.Time, afterID, limit int, tombstones bool,
+) ([]core.Comics, error) {
+	reply, err := c.client.Changes(ctx, &searchpb.ChangesRequest{
+		Since: timestamppb.New(since), AfterId: int64(afterID), Limit: int64(limit), Tombstones: tombstones,
 	})
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return nil, core.ErrNotFound
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, core.ErrBadArguments
 		}
 		return nil, err
 	}
-	comics := make([]core.Comics, 0, len(reply.Comics))
-	for _, c := range reply.Comics {
-		comics = append(comics, core.Comics{ID: int(c.Id), URL: c.Url, Title: c.Title, Alt: c.Alt, Score: int(c.Score)})
+	comics := make([]core.Comics, 0, len(reply.GetComics()))
+	for _, c := range reply.GetComics() {
+		comics = append(comics, core.Comics{
+			ID: int(c.GetId()), URL: c.GetUrl(), Title: c.GetTitle(), Alt: c.GetAlt(),
+			UpdatedAt: c.GetUpdatedAt().AsTime(), Deleted: c.GetDeleted(),
+		})
 	}
 	return comics, nil
 }
 
+func (c *Client) DocumentFrequency(ctx context.Context, term string) (map[string]int, error) {
+	reply, err := c.client.DocumentFrequency(ctx, &searchpb.DocumentFrequencyRequest{Term: term})
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, core.ErrBadArguments
+		}
+		return nil, err
+	}
+	frequencies := make(map[string]int, len(reply.GetFrequencies()))
+	for keyword, count := range reply.GetFrequencies() {
+		frequencies[keyword] = int(count)
+	}
+	return frequencies, nil
+}
+
+func (c *Client) BuildHistory(ctx context.Context) ([]core.BuildRun, error) {
+	reply, err := c.client.BuildHistory(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]core.BuildRun, 0, len(reply.GetRuns()))
+	for _, run := range reply.GetRuns() {
+		runs = append(runs, core.BuildRun{
+			StartedAt: run.GetStartedAt().AsTime(),
+			Duration:  run.GetDuration().AsDuration(),
+			Comics:    int(run.GetComics()),
+			Error:     run.GetError(),
+		})
+	}
+	return runs, nil
+}
+
+func (c *Client) LastID(ctx context.Context) (int, error) {
+	reply, err := c.client.LastID(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(reply.GetId()), nil
+}
+
+func (c *Client) IndexStats(ctx context.Context) (core.IndexStats, error) {
+	reply, err := c.client.IndexStats(ctx, nil)
+	if err != nil {
+		return core.IndexStats{}, err
+	}
+	stats := core.IndexStats{
+		Comics:   int(reply.GetComics()),
+		Keywords: int(reply.GetKeywords()),
+		Disabled: reply.GetDisabled(),
+	}
+	if reply.GetBuiltAt() != nil {
+		stats.BuiltAt = reply.GetBuiltAt().AsTime()
+	}
+	return stats, nil
+}
+
+// Version reports the proto version the service is built with.
+func (c *Client) Version(ctx context.Context) (int, error) {
+	reply, err := c.client.Version(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(reply.GetVersion()), nil
+}
+
 func (c *Client) Ping(ctx context.Context) error {
 	_, err := c.client.Ping(ctx, nil)
+	if status.Code(err) == codes.FailedPrecondition {
+		return core.ErrDegraded
+	}
 	return err
 }
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/api/core"
+	searchpb "github.com/liy0aay/xkcd-search/proto/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSearchClient struct {
+	searchpb.SearchClient
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (f *fakeSearchClient) Search(
+	ctx context.Context, in *searchpb.SearchRequest, _ ...grpc.CallOption,
+) (*searchpb.SearchReply, error) {
+	f.calls.Add(1)
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &searchpb.SearchReply{Comics: []*searchpb.Comics{{Id: 1, Title: in.Phrase}}, Total: 2, Errors: 1}, nil
+}
+
+func TestClient_SearchCoalescesIdenticalQueries(t *testing.T) {
+	fake := &fakeSearchClient{release: make(chan struct{})}
+	client := &Client{log: slog.New(slog.NewTextHandler(io.Discard, nil)), client: fake}
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([]core.SearchResult, n)
+	errs := make([]error, n)
+	for i := range n {
+		phrase := "Linux cpu"
+		if i%2 == 1 {
+			phrase = "  linux   CPU "
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = client.Search(context.Background(), phrase, 5, core.SearchOptions{})
+		}()
+	}
+
+	// let every caller join the in-flight call before it completes
+	time.Sleep(100 * time.Millisecond)
+	close(fake.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, fake.calls.Load())
+	for i := range n {
+		require.NoError(t, errs[i])
+		require.Len(t, results[i].Comics, 1)
+		assert.Equal(t, 1, results[i].Comics[0].ID)
+		assert.Equal(t, 2, results[i].Total)
+		assert.Equal(t, 1, results[i].Errors, "failed comics reach every caller")
+	}
+}
+
+func TestClient_SearchFollowerOutlivesCancelledLeader(t *testing.T) {
+	fake := &fakeSearchClient{release: make(chan struct{})}
+	client := &Client{log: slog.New(slog.NewTextHandler(io.Discard, nil)), client: fake}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := client.Search(leaderCtx, "linux", 5, core.SearchOptions{})
+		leaderErr <- err
+	}()
+	require.Eventually(t, func() bool { return fake.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	type searched struct {
+		result core.SearchResult
+		err    error
+	}
+	follower := make(chan searched)
+	go func() {
+		result, err := client.Search(context.Background(), "linux", 5, core.SearchOptions{})
+		follower <- searched{result, err}
+	}()
+	// let the follower join the in-flight call before the leader leaves
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	close(fake.release)
+	got := <-follower
+	require.NoError(t, got.err)
+	require.Len(t, got.result.Comics, 1)
+	assert.EqualValues(t, 1, fake.calls.Load())
+}
+
+func TestClient_SearchDoesNotCoalesceDifferentPages(t *testing.T) {
+	fake := &fakeSearchClient{release: make(chan struct{})}
+	close(fake.release)
+	client := &Client{log: slog.New(slog.NewTextHandler(io.Discard, nil)), client: fake}
+
+	_, err := client.Search(context.Background(), "linux", 5, core.SearchOptions{})
+	require.NoError(t, err)
+	_, err = client.Search(context.Background(), "linux", 5, core.SearchOptions{Offset: 5})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, fake.calls.Load())
+}
+
+type rejectingSearchClient struct {
+	searchpb.SearchClient
+	err error
+}
+
+func (f rejectingSearchClient) Search(
+	context.Context, *searchpb.SearchRequest, ...grpc.CallOption,
+) (*searchpb.SearchReply, error) {
+	return nil, f.err
+}
+
+func TestClient_SearchStopWords(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "query has only stop words").WithDetails(&errdetails.ErrorInfo{
+		Reason: searchpb.ReasonStopWords, Domain: searchpb.ErrorDomain,
+	})
+	require.NoError(t, err)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	client := &Client{log: log, client: rejectingSearchClient{err: st.Err()}}
+	_, err = client.Search(context.Background(), "the and or", 5, core.SearchOptions{})
+	assert.ErrorIs(t, err, core.ErrStopWords)
+
+	client = &Client{log: log, client: rejectingSearchClient{err: status.Error(codes.InvalidArgument, "bad")}}
+	_, err = client.Search(context.Background(), "the and or", 5, core.SearchOptions{})
+	assert.ErrorIs(t, err, core.ErrBadArguments)
+	assert.NotErrorIs(t, err, core.ErrStopWords)
+}
@@ -0,0 +1,31 @@
+package timeouts
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Interceptor limits gRPC calls by the timeout configured for their
+// method, keyed either by the full name like "/search.Search/Search"
+// or by the bare method name like "Search". Unlisted methods keep
+// the deadline of their context.
+func Interceptor(timeouts map[string]time.Duration) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply any,
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		timeout, ok := timeouts[method]
+		if !ok {
+			timeout, ok = timeouts[path.Base(method)]
+		}
+		if ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
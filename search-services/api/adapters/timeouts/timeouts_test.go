@@ -0,0 +1,57 @@
+package timeouts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func deadlineOf(t *testing.T, interceptor grpc.UnaryClientInterceptor, ctx context.Context, method string) (time.Duration, bool) {
+	t.Helper()
+	var left time.Duration
+	var ok bool
+	err := interceptor(ctx, method, nil, nil, nil,
+		func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			var deadline time.Time
+			deadline, ok = ctx.Deadline()
+			left = time.Until(deadline)
+			return nil
+		})
+	require.NoError(t, err)
+	return left, ok
+}
+
+func TestInterceptor(t *testing.T) {
+	interceptor := Interceptor(map[string]time.Duration{
+		"Search":                2 * time.Second,
+		"/update.Update/Update": 10 * time.Minute,
+		"/search.Search/Comic":  0,
+	})
+
+	left, ok := deadlineOf(t, interceptor, context.Background(), "/search.Search/Search")
+	require.True(t, ok)
+	assert.InDelta(t, 2*time.Second, left, float64(100*time.Millisecond))
+
+	left, ok = deadlineOf(t, interceptor, context.Background(), "/update.Update/Update")
+	require.True(t, ok)
+	assert.InDelta(t, 10*time.Minute, left, float64(100*time.Millisecond))
+
+	_, ok = deadlineOf(t, interceptor, context.Background(), "/search.Search/Comic")
+	assert.False(t, ok)
+	_, ok = deadlineOf(t, interceptor, context.Background(), "/words.Words/Norm")
+	assert.False(t, ok)
+}
+
+func TestInterceptor_KeepsEarlierDeadline(t *testing.T) {
+	interceptor := Interceptor(map[string]time.Duration{"Update": time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	left, ok := deadlineOf(t, interceptor, ctx, "/update.Update/Update")
+	require.True(t, ok)
+	assert.LessOrEqual(t, left, time.Second)
+}
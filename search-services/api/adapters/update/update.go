@@ -3,6 +3,7 @@ package update
 import (
 	"context"
 	"errors"
+	"io"
 	"log/slog"
 
 	"github.com/liy0aay/xkcd-search/api/core"
@@ -19,8 +20,9 @@ type Client struct {
 	conn   *grpc.ClientConn
 }
 
-func NewClient(address string, log *slog.Logger) (*Client, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewClient(address string, log *slog.Logger, opts ...grpc.DialOption) (*Client, error) {
+	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(address, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +37,15 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
+// Version reports the proto version the service is built with.
+func (c *Client) Version(ctx context.Context) (int, error) {
+	reply, err := c.client.Version(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(reply.GetVersion()), nil
+}
+
 func (c *Client) Ping(ctx context.Context) error {
 	_, err := c.client.Ping(ctx, nil)
 	return err
@@ -50,6 +61,8 @@ func (c *Client) Status(ctx context.Context) (core.UpdateStatus, error) {
 		return core.StatusUpdateIdle, nil
 	case updatepb.Status_STATUS_RUNNING:
 		return core.StatusUpdateRunning, nil
+	case updatepb.Status_STATUS_REINDEXING:
+		return core.StatusUpdateReindexing, nil
 	}
 	return core.StatusUpdateUnknown, errors.New("unknown status")
 }
@@ -64,9 +77,36 @@ func (c *Client) Stats(ctx context.Context) (core.UpdateStats, error) {
 		WordsUnique:   int(reply.GetWordsUnique()),
 		ComicsFetched: int(reply.GetComicsFetched()),
 		ComicsTotal:   int(reply.GetComicsTotal()),
+		ComicsAdded:   int(reply.GetComicsAdded()),
+		ComicsSkipped: int(reply.GetComicsSkipped()),
+		ComicsFailed:  int(reply.GetComicsFailed()),
 	}, nil
 }
 
+func (c *Client) ResetStats(ctx context.Context) error {
+	_, err := c.client.ResetStats(ctx, nil)
+	return err
+}
+
+func (c *Client) SetTags(ctx context.Context, id int, tags []string) error {
+	_, err := c.client.SetTags(ctx, &updatepb.SetTagsRequest{Id: int64(id), Tags: tags})
+	if status.Code(err) == codes.NotFound {
+		return core.ErrNotFound
+	}
+	return err
+}
+
+func (c *Client) GetTags(ctx context.Context, id int) ([]string, error) {
+	reply, err := c.client.GetTags(ctx, &updatepb.TagsRequest{Id: int64(id)})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, core.ErrNotFound
+		}
+		return nil, err
+	}
+	return reply.GetTags(), nil
+}
+
 func (c *Client) Update(ctx context.Context) error {
 	_, err := c.client.Update(ctx, nil)
 	if status.Code(err) == codes.AlreadyExists {
@@ -75,7 +115,62 @@ func (c *Client) Update(ctx context.Context) error {
 	return err
 }
 
+func (c *Client) UpdateOne(ctx context.Context, id int) error {
+	_, err := c.client.UpdateOne(ctx, &updatepb.UpdateOneRequest{Id: int64(id)})
+	if status.Code(err) == codes.NotFound {
+		return core.ErrNotFound
+	}
+	return err
+}
+
 func (c *Client) Drop(ctx context.Context) error {
 	_, err := c.client.Drop(ctx, nil)
 	return err
 }
+
+func (c *Client) BrokenLinks(ctx context.Context) ([]core.BrokenLink, error) {
+	reply, err := c.client.BrokenLinks(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]core.BrokenLink, 0, len(reply.GetLinks()))
+	for _, l := range reply.GetLinks() {
+		links = append(links, core.BrokenLink{
+			ID:        int(l.GetId()),
+			URL:       l.GetUrl(),
+			Reason:    l.GetReason(),
+			CheckedAt: l.GetCheckedAt().AsTime(),
+		})
+	}
+	return links, nil
+}
+
+// Progress streams the progress of the running update until it finishes or
+// ctx is done.
+func (c *Client) Progress(ctx context.Context) (<-chan core.UpdateProgress, error) {
+	stream, err := c.client.UpdateProgress(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	progress := make(chan core.UpdateProgress)
+	go func() {
+		defer close(progress)
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					c.log.Error("update progress stream failed", "error", err)
+				}
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case progress <- core.UpdateProgress{
+				Fetched: int(reply.Fetched), Total: int(reply.Total), CurrentID: int(reply.CurrentId),
+			}:
+			}
+		}
+	}()
+	return progress, nil
+}
@@ -18,8 +18,9 @@ type Client struct {
 	conn   *grpc.ClientConn
 }
 
-func NewClient(address string, log *slog.Logger) (*Client, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewClient(address string, log *slog.Logger, opts ...grpc.DialOption) (*Client, error) {
+	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(address, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +46,15 @@ func (c *Client) Norm(ctx context.Context, phrase string) ([]string, error) {
 	return reply.GetWords(), nil
 }
 
+// Version reports the proto version the service is built with.
+func (c *Client) Version(ctx context.Context) (int, error) {
+	reply, err := c.client.Version(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(reply.GetVersion()), nil
+}
+
 func (c *Client) Ping(ctx context.Context) error {
 	_, err := c.client.Ping(ctx, nil)
 	return err
@@ -16,6 +16,7 @@ import (
 )
 
 type fakeWordsClient struct {
+	wordspb.WordsClient
 	normFunc func(ctx context.Context, req *wordspb.WordsRequest) (*wordspb.WordsReply, error)
 	pingFunc func(ctx context.Context, req *emptypb.Empty) (*emptypb.Empty, error)
 }
@@ -8,20 +8,146 @@ import (
 )
 
 type HTTPConfig struct {
-	Address string        `yaml:"address" env:"API_ADDRESS" env-default:"localhost:80"`
-	Timeout time.Duration `yaml:"timeout" env:"API_TIMEOUT" env-default:"5s"`
+	Address     string        `yaml:"address" env:"API_ADDRESS" env-default:"localhost:80"`
+	Timeout     time.Duration `yaml:"timeout" env:"API_TIMEOUT" env-default:"5s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"API_IDLE_TIMEOUT" env-default:"60s"`
+	HTTP2       bool          `yaml:"http2" env:"API_HTTP2" env-default:"false"`
+	// BodyTimeout replies 408 to clients sending bodies slower, zero leaves it to Timeout.
+	BodyTimeout time.Duration `yaml:"body_timeout" env:"API_BODY_TIMEOUT" env-default:"5s"`
+	// MaxBodySize limits bodies read within BodyTimeout.
+	MaxBodySize int64 `yaml:"max_body_size" env:"API_MAX_BODY_SIZE" env-default:"1048576"`
+}
+
+type CompressionConfig struct {
+	MinSize    int      `yaml:"min_size" env:"COMPRESSION_MIN_SIZE" env-default:"1024"`
+	Algorithms []string `yaml:"algorithms" env:"COMPRESSION_ALGORITHMS" env-separator:"," env-default:"br,gzip"`
+}
+
+// ImagesConfig enables the on-disk image cache when CacheDir is set.
+type ImagesConfig struct {
+	Timeout      time.Duration `yaml:"timeout" env:"IMAGES_TIMEOUT" env-default:"10s"`
+	CacheDir     string        `yaml:"cache_dir" env:"IMAGES_CACHE_DIR"`
+	CacheMaxSize int64         `yaml:"cache_max_size" env:"IMAGES_CACHE_MAX_SIZE" env-default:"104857600"`
+	CacheTTL     time.Duration `yaml:"cache_ttl" env:"IMAGES_CACHE_TTL" env-default:"168h"`
+}
+
+// ExplainConfig guards explainxkcd with a cache and a circuit breaker,
+// the newest WarmCount explanations are fetched in the background on start.
+// CacheTTLs override CacheTTL per comics id within the min and max bounds,
+// past CacheMaxEntries the least recently used explanations are evicted.
+type ExplainConfig struct {
+	CacheTTL         time.Duration         `yaml:"cache_ttl" env:"EXPLAIN_CACHE_TTL" env-default:"24h"`
+	CacheTTLs        map[int]time.Duration `yaml:"cache_ttls" env:"EXPLAIN_CACHE_TTLS" env-separator:","`
+	MinCacheTTL      time.Duration         `yaml:"min_cache_ttl" env:"EXPLAIN_MIN_CACHE_TTL" env-default:"0s"`
+	MaxCacheTTL      time.Duration         `yaml:"max_cache_ttl" env:"EXPLAIN_MAX_CACHE_TTL" env-default:"0s"`
+	CacheMaxEntries  int                   `yaml:"cache_max_entries" env:"EXPLAIN_CACHE_MAX_ENTRIES" env-default:"5000"`
+	BreakerThreshold int                   `yaml:"breaker_threshold" env:"EXPLAIN_BREAKER_THRESHOLD" env-default:"5"`
+	BreakerCooldown  time.Duration         `yaml:"breaker_cooldown" env:"EXPLAIN_BREAKER_COOLDOWN" env-default:"30s"`
+	WarmCount        int                   `yaml:"warm_count" env:"EXPLAIN_WARM_COUNT" env-default:"0"`
+	WarmConcurrency  int                   `yaml:"warm_concurrency" env:"EXPLAIN_WARM_CONCURRENCY" env-default:"2"`
+	// Format is the reply format unless asked otherwise, html or text.
+	Format string `yaml:"format" env:"EXPLAIN_FORMAT" env-default:"html"`
+}
+
+// HTTPTransport tunes connection reuse of outbound clients, zero keeps net/http defaults.
+type HTTPTransport struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns" env:"HTTP_MAX_IDLE_CONNS" env-default:"100"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host" env:"HTTP_MAX_IDLE_CONNS_PER_HOST" env-default:"16"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout" env:"HTTP_IDLE_CONN_TIMEOUT" env-default:"90s"`
+	KeepAlive           time.Duration `yaml:"keep_alive" env:"HTTP_KEEP_ALIVE" env-default:"30s"`
+}
+
+// IPRateConfig limits searches per client IP when RPS is positive, up to
+// MaxKeys IPs are tracked and new ones over it share OverflowRPS.
+type IPRateConfig struct {
+	RPS         int `yaml:"rps" env:"IP_RATE_RPS" env-default:"0"`
+	Burst       int `yaml:"burst" env:"IP_RATE_BURST" env-default:"5"`
+	MaxKeys     int `yaml:"max_keys" env:"IP_RATE_MAX_KEYS" env-default:"10000"`
+	OverflowRPS int `yaml:"overflow_rps" env:"IP_RATE_OVERFLOW_RPS" env-default:"10"`
+}
+
+// QueryLogConfig keeps the latest Size searches, zero Retention keeps
+// them until pushed out. With File set they survive restarts.
+type QueryLogConfig struct {
+	Size          int           `yaml:"size" env:"QUERY_LOG_SIZE" env-default:"1000"`
+	Retention     time.Duration `yaml:"retention" env:"QUERY_LOG_RETENTION" env-default:"0s"`
+	File          string        `yaml:"file" env:"QUERY_LOG_FILE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"QUERY_LOG_FLUSH_INTERVAL" env-default:"1m"`
+}
+
+// WarmupConfig searches Phrases and the TopQueries most logged phrases
+// in the background on start, nothing is warmed when both are empty.
+type WarmupConfig struct {
+	Phrases    []string `yaml:"phrases" env:"WARMUP_PHRASES" env-separator:","`
+	TopQueries int      `yaml:"top_queries" env:"WARMUP_TOP_QUERIES" env-default:"0"`
+}
+
+// RecentLogsConfig keeps the latest Size log records of Level or above
+// for the admin, zero Size disables it.
+type RecentLogsConfig struct {
+	Size  int    `yaml:"size" env:"RECENT_LOGS_SIZE" env-default:"200"`
+	Level string `yaml:"level" env:"RECENT_LOGS_LEVEL" env-default:"WARN"`
+}
+
+// GRPCGraceConfig retries gRPC calls failing while a backend starts, for
+// up to Window after the api starts, zero Window disables it.
+type GRPCGraceConfig struct {
+	Window  time.Duration `yaml:"window" env:"GRPC_GRACE_WINDOW" env-default:"0s"`
+	Backoff time.Duration `yaml:"backoff" env:"GRPC_GRACE_BACKOFF" env-default:"100ms"`
+}
+
+// PopularityConfig counts a click of a client on a comics once per
+// DedupeWindow, remembering up to MaxClicks recent clicks. Counts are
+// kept in memory and start over on restart.
+type PopularityConfig struct {
+	DedupeWindow time.Duration `yaml:"dedupe_window" env:"POPULARITY_DEDUPE_WINDOW" env-default:"24h"`
+	MaxClicks    int           `yaml:"max_clicks" env:"POPULARITY_MAX_CLICKS" env-default:"100000"`
 }
 
 type Config struct {
-	LogLevel          string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
-	SearchConcurrency int           `yaml:"search_concurrency" env:"SEARCH_CONCURRENCY" env-default:"1"`
-	SearchRate        int           `yaml:"search_rate" env:"SEARCH_RATE" env-default:"1"`
-	HTTPConfig        HTTPConfig    `yaml:"api_server"`
-	WordsAddress      string        `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"words:81"`
-	UpdateAddress     string        `yaml:"update_address" env:"UPDATE_ADDRESS" env-default:"update:82"`
-	SearchAddress     string        `yaml:"search_address" env:"SEARCH_ADDRESS" env-default:"search:83"`
-	TokenTTL          time.Duration `yaml:"token_ttl" env:"TOKEN_TTL" env-default:"24h"`
-	ExplainXKCDURL    string        `yaml:"explain_xkcd_url" env:"EXPLAIN_XKCD_URL" env-default:"https://www.explainxkcd.com"`
+	LogLevel          string            `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
+	SearchConcurrency int               `yaml:"search_concurrency" env:"SEARCH_CONCURRENCY" env-default:"1"`
+	SearchRate        int               `yaml:"search_rate" env:"SEARCH_RATE" env-default:"1"`
+	SearchIPRate      IPRateConfig      `yaml:"search_ip_rate"`
+	HTTPConfig        HTTPConfig        `yaml:"api_server"`
+	Compression       CompressionConfig `yaml:"compression"`
+	WordsAddress      string            `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"words:81"`
+	UpdateAddress     string            `yaml:"update_address" env:"UPDATE_ADDRESS" env-default:"update:82"`
+	SearchAddress     string            `yaml:"search_address" env:"SEARCH_ADDRESS" env-default:"search:83"`
+	TokenTTL          time.Duration     `yaml:"token_ttl" env:"TOKEN_TTL" env-default:"24h"`
+	RefreshTTL        time.Duration     `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" env-default:"720h"`
+	SlidingRefresh    bool              `yaml:"sliding_refresh" env:"SLIDING_REFRESH" env-default:"false"`
+	MaxSessions       int               `yaml:"max_sessions" env:"MAX_SESSIONS" env-default:"0"`
+	RevocationsFile   string            `yaml:"revocations_file" env:"REVOCATIONS_FILE"`
+	TokenIssuer       string            `yaml:"token_issuer" env:"TOKEN_ISSUER"`
+	TokenAudience     string            `yaml:"token_audience" env:"TOKEN_AUDIENCE"`
+	AuthRoutes        []string          `yaml:"auth_routes" env:"AUTH_ROUTES" env-separator:","`
+	ExplainXKCDURL    string            `yaml:"explain_xkcd_url" env:"EXPLAIN_XKCD_URL" env-default:"https://www.explainxkcd.com"`
+	ExplainXKCDProxy  string            `yaml:"explain_xkcd_proxy" env:"EXPLAIN_XKCD_PROXY"`
+	Explain           ExplainConfig     `yaml:"explain"`
+	HTTPTransport     HTTPTransport     `yaml:"http_transport"`
+	QueryLog          QueryLogConfig    `yaml:"query_log"`
+	RecentLogs        RecentLogsConfig  `yaml:"recent_logs"`
+	Warmup            WarmupConfig      `yaml:"warmup"`
+	Images            ImagesConfig      `yaml:"images"`
+	GRPCGrace         GRPCGraceConfig   `yaml:"grpc_grace"`
+	TrailingSlash     string            `yaml:"trailing_slash" env:"TRAILING_SLASH" env-default:"rewrite"`
+	SelfTestPhrase    string            `yaml:"self_test_phrase" env:"SELF_TEST_PHRASE" env-default:"linux"`
+	// SearchEmptyOK replies 200 with no comics instead of 404 when nothing is found.
+	SearchEmptyOK bool `yaml:"search_empty_ok" env:"SEARCH_EMPTY_OK" env-default:"false"`
+	// ReadyMaxIndexAge makes readiness fail once the search index is not rebuilt for longer, zero disables.
+	ReadyMaxIndexAge time.Duration `yaml:"ready_max_index_age" env:"READY_MAX_INDEX_AGE" env-default:"0s"`
+	// GRPCTimeouts maps gRPC methods, e.g. Search or /update.Update/Update, to call timeouts.
+	GRPCTimeouts map[string]time.Duration `yaml:"grpc_timeouts" env:"GRPC_TIMEOUTS" env-separator:","`
+	Popularity   PopularityConfig         `yaml:"popularity"`
+	// TrustedProxies are IPs or CIDRs whose X-Forwarded-For tells client IPs.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+	// SearchStreamsPerIP caps search event streams open at once per client IP, zero disables.
+	SearchStreamsPerIP int `yaml:"search_streams_per_ip" env:"SEARCH_STREAMS_PER_IP" env-default:"2"`
+	// StartupTimeout bounds waiting for backends to answer at start.
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"STARTUP_TIMEOUT" env-default:"30s"`
+	// SessionsFile keeps sessions limited by MaxSessions across restarts, empty keeps them in memory.
+	SessionsFile string `yaml:"sessions_file" env:"SESSIONS_FILE"`
 }
 
 func MustLoad(configPath string) Config {
@@ -1,7 +1,15 @@
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrBadArguments = errors.New("arguments are not acceptable")
 var ErrAlreadyExists = errors.New("resource or task already exists")
 var ErrNotFound = errors.New("resource is not found")
+var ErrDegraded = errors.New("service is degraded")
+var ErrUnavailable = errors.New("service is unavailable")
+
+// ErrStopWords rejects a query with no keywords left after normalization.
+var ErrStopWords = fmt.Errorf("%w: query has only stop words", ErrBadArguments)
@@ -1,29 +1,158 @@
 package core
 
+import "time"
+
 type UpdateStatus string
 
 const (
-	StatusUpdateUnknown UpdateStatus = "unknown"
-	StatusUpdateIdle    UpdateStatus = "idle"
-	StatusUpdateRunning UpdateStatus = "running"
+	StatusUpdateUnknown    UpdateStatus = "unknown"
+	StatusUpdateIdle       UpdateStatus = "idle"
+	StatusUpdateRunning    UpdateStatus = "running"
+	StatusUpdateReindexing UpdateStatus = "reindexing"
 )
 
+// UpdateProgress of a running update, Fetched of the Total missing comics
+// are done with so far, skipped and failed ones included, and CurrentID is
+// the latest of them.
+type UpdateProgress struct {
+	Fetched   int
+	Total     int
+	CurrentID int
+}
+
+// UpdateStats are derived from the stored comics, apart from the comics
+// added, skipped and failed by updates since the update service started or
+// its stats were reset.
 type UpdateStats struct {
 	WordsTotal    int
 	WordsUnique   int
 	ComicsFetched int
 	ComicsTotal   int
+	ComicsAdded   int
+	ComicsSkipped int
+	ComicsFailed  int
 }
 
 type Comics struct {
-	ID    int
-	URL   string
-	Title string
-	Alt   string
+	ID        int
+	URL       string
+	Title     string
+	Alt       string
+	Score     int
+	UpdatedAt time.Time
+	// Deleted marks a tombstone in the changes feed.
+	Deleted bool
+	// Keywords are only set by searches asking for them.
+	Keywords []string
+}
+
+// SearchResult is a page of found comics and the Total of matching ones
+// regardless of paging. Errors counts matching comics the search service
+// failed to fetch, they are missing from the page.
+type SearchResult struct {
+	Comics []Comics
+	Total  int
+	Errors int
+}
+
+type SearchOptions struct {
+	Tag      string
+	Offset   int
+	MatchAll bool
+	Language string
+	// After continues results strictly after the cursor, when set.
+	After Cursor
+	// Boosts weight keywords found in a field, e.g. title or alt.
+	Boosts map[string]int
+	// BoostPopular adds clicks on comics to their scores.
+	BoostPopular bool
+	// IncludeKeywords returns stored keywords of found comics.
+	IncludeKeywords bool
+	// Phrase keeps only comics with the phrase words next to each other, in order.
+	Phrase bool
+}
+
+// Cursor is the score and ID of the last seen search result.
+type Cursor struct {
 	Score int
+	ID    int
 }
 
+type ComicsNav struct {
+	Comics Comics
+	PrevID int
+	NextID int
+}
+
+// ExplainXKCDInfo is an explanation of comics ID, or of a wiki Page asked by
+// title with a zero ID. Text is set and HTML left empty when plain text is asked for.
 type ExplainXKCDInfo struct {
 	ID   int
+	Page string `json:",omitempty"`
 	HTML string
+	Text string `json:",omitempty"`
+}
+
+// Image is a comics picture, Cached tells it was served without an upstream fetch.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Cached      bool
+}
+
+// Query is a search recorded in the query log.
+type Query struct {
+	Phrase  string
+	Results int
+	At      time.Time
+}
+
+// LogRecord is a log message kept for debugging, attrs keys are dotted by groups.
+type LogRecord struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Attrs   map[string]any
+}
+
+// BreakerStatus tells whether calls to an upstream are let through,
+// OpenedAt is when the breaker last tripped, zero if it never did.
+type BreakerStatus struct {
+	State     string
+	Failures  int
+	Threshold int
+	OpenedAt  time.Time
+}
+
+type BuildRun struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Comics    int
+	Error     string
+}
+
+// TokenInfo is what a token claims, only filled in for an active one.
+type TokenInfo struct {
+	Active    bool
+	Name      string
+	Role      string
+	Type      string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+// IndexStats is the search index size and its last successful build,
+// BuiltAt is zero before the first one.
+type IndexStats struct {
+	Comics   int
+	Keywords int
+	BuiltAt  time.Time
+	Disabled bool
+}
+
+type BrokenLink struct {
+	ID        int
+	URL       string
+	Reason    string
+	CheckedAt time.Time
 }
@@ -1,6 +1,9 @@
 package core
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Normalizer interface {
 	Norm(context.Context, string) ([]string, error)
@@ -12,22 +15,64 @@ type Pinger interface {
 
 type Updater interface {
 	Update(context.Context) error
+	UpdateOne(ctx context.Context, id int) error
 	Stats(context.Context) (UpdateStats, error)
+	ResetStats(context.Context) error
 	Status(context.Context) (UpdateStatus, error)
 	Drop(context.Context) error
+	BrokenLinks(context.Context) ([]BrokenLink, error)
+	SetTags(ctx context.Context, id int, tags []string) error
+	GetTags(ctx context.Context, id int) ([]string, error)
+	// Progress streams the progress of the running update, the channel is
+	// closed when it finishes.
+	Progress(context.Context) (<-chan UpdateProgress, error)
 }
 
 type Searcher interface {
-	Search(context.Context, string, int) ([]Comics, error)
-	SearchIndex(context.Context, string, int) ([]Comics, error)
+	Search(context.Context, string, int, SearchOptions) (SearchResult, error)
+	SearchIndex(context.Context, string, int, SearchOptions) (SearchResult, error)
+	Comic(ctx context.Context, id int, nav bool) (ComicsNav, error)
+	DocumentFrequency(ctx context.Context, term string) (map[string]int, error)
+	BuildHistory(ctx context.Context) ([]BuildRun, error)
+	Changes(ctx context.Context, since time.Time, afterID, limit int, tombstones bool) ([]Comics, error)
+	ReindexComic(ctx context.Context, id int) error
+	// ReindexRange returns how many comics of the range are indexed.
+	ReindexRange(ctx context.Context, from, to int) (int, error)
+	ByTitle(ctx context.Context, title string) ([]Comics, error)
+	LastID(ctx context.Context) (int, error)
+	IndexStats(ctx context.Context) (IndexStats, error)
 }
 
 type Authenticator interface {
 	Login(user, password string) (accessToken string, refreshToken string, err error)
 	Verify(token string) error
-	RefreshAccessToken(refreshToken string) (string, error)
+	RefreshAccessToken(refreshToken string) (accessToken string, newRefreshToken string, err error)
+}
+
+type Images interface {
+	Image(ctx context.Context, url string) (Image, error)
+}
+
+type QueryLog interface {
+	Recent() []Query
+}
+
+type LogBuffer interface {
+	Recent() []LogRecord
+}
+
+type Breaker interface {
+	Status() BreakerStatus
+	// Reset closes the breaker at once, forgetting failures.
+	Reset()
+}
+
+type Popularity interface {
+	// Click counts a click of client on comics id, false when ignored.
+	Click(client string, id int) bool
 }
 
 type Explainer interface {
 	Explain(ctx context.Context, id int) (ExplainXKCDInfo, error)
+	ExplainPage(ctx context.Context, page string) (ExplainXKCDInfo, error)
 }
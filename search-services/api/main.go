@@ -10,18 +10,36 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/liy0aay/xkcd-search/api/adapters/aaa"
 	"github.com/liy0aay/xkcd-search/api/adapters/explainxkcd"
+	"github.com/liy0aay/xkcd-search/api/adapters/grace"
+	"github.com/liy0aay/xkcd-search/api/adapters/images"
+	"github.com/liy0aay/xkcd-search/api/adapters/logbuffer"
+	"github.com/liy0aay/xkcd-search/api/adapters/popularity"
+	"github.com/liy0aay/xkcd-search/api/adapters/querylog"
 	"github.com/liy0aay/xkcd-search/api/adapters/rest"
 	"github.com/liy0aay/xkcd-search/api/adapters/rest/middleware"
 	"github.com/liy0aay/xkcd-search/api/adapters/search"
+	"github.com/liy0aay/xkcd-search/api/adapters/timeouts"
 	"github.com/liy0aay/xkcd-search/api/adapters/update"
 	"github.com/liy0aay/xkcd-search/api/adapters/words"
 	"github.com/liy0aay/xkcd-search/api/config"
 	"github.com/liy0aay/xkcd-search/api/core"
 	"github.com/liy0aay/xkcd-search/closers"
+	"github.com/liy0aay/xkcd-search/metrics"
+	searchpb "github.com/liy0aay/xkcd-search/proto/search"
+	updatepb "github.com/liy0aay/xkcd-search/proto/update"
+	wordspb "github.com/liy0aay/xkcd-search/proto/words"
+	"github.com/liy0aay/xkcd-search/transport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func main() {
@@ -39,44 +57,160 @@ func main() {
 	}
 }
 
+// mutatingCalls change backend state, so a call failing as Unavailable
+// is not retried as it may have been served.
+var mutatingCalls = []string{
+	"/update.Update/Update",
+	"/update.Update/UpdateOne",
+	"/update.Update/ResetStats",
+	"/update.Update/Drop",
+	"/update.Update/SetTags",
+	"/search.Search/ReindexComic",
+	"/search.Search/ReindexRange",
+}
+
 func run(cfg config.Config, log *slog.Logger) error {
+	var recentLogs *logbuffer.Buffer
+	if cfg.RecentLogs.Size > 0 {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(cfg.RecentLogs.Level)); err != nil {
+			return fmt.Errorf("wrong recent logs level: %v", err)
+		}
+		var err error
+		recentLogs, err = logbuffer.New(cfg.RecentLogs.Size, level)
+		if err != nil {
+			return fmt.Errorf("cannot init recent logs: %v", err)
+		}
+		log = slog.New(recentLogs.Handler(log.Handler()))
+	}
+
 	log.Info("starting server")
 	log.Debug("debug messages are enabled")
 
-	wordsClient, err := words.NewClient(cfg.WordsAddress, log)
+	// each retry of a starting backend gets a call timeout of its own
+	callInterceptors := grpc.WithChainUnaryInterceptor(
+		grace.Interceptor(cfg.GRPCGrace.Window, cfg.GRPCGrace.Backoff, log, mutatingCalls...),
+		timeouts.Interceptor(cfg.GRPCTimeouts),
+	)
+
+	wordsClient, err := words.NewClient(cfg.WordsAddress, log, callInterceptors)
 	if err != nil {
 		return fmt.Errorf("cannot init words adapter: %v", err)
 	}
 	defer closers.CloseOrLog(wordsClient, log)
 
-	updateClient, err := update.NewClient(cfg.UpdateAddress, log)
+	updateClient, err := update.NewClient(cfg.UpdateAddress, log, callInterceptors)
 	if err != nil {
 		return fmt.Errorf("cannot init update adapter: %v", err)
 	}
 	defer closers.CloseOrLog(updateClient, log)
 
-	searchClient, err := search.NewClient(cfg.SearchAddress, log)
+	searchClient, err := search.NewClient(cfg.SearchAddress, log, callInterceptors)
 	if err != nil {
 		return fmt.Errorf("cannot init search adapter: %v", err)
 	}
 	defer closers.CloseOrLog(searchClient, log)
 
-	explainClient, err := explainxkcd.NewClient(cfg.ExplainXKCDURL, 5*time.Second, log)
+	backends := map[string]core.Pinger{
+		"words":  wordsClient,
+		"update": updateClient,
+		"search": searchClient,
+	}
+	// backends have to answer within startup timeout
+	startCtx, cancel := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	defer cancel()
+	if err := waitBackends(startCtx, backends); err != nil {
+		return err
+	}
+
+	if cfg.Explain.Format != rest.ExplainHTML && cfg.Explain.Format != rest.ExplainText {
+		return fmt.Errorf("unknown explain format %q", cfg.Explain.Format)
+	}
+	explainBreaker, err := explainxkcd.NewBreaker(cfg.Explain.BreakerThreshold, cfg.Explain.BreakerCooldown)
+	if err != nil {
+		return fmt.Errorf("cannot init ExplainXKCD breaker: %v", err)
+	}
+	explainClient, err := explainxkcd.NewClient(cfg.ExplainXKCDURL, cfg.ExplainXKCDProxy, 5*time.Second, log,
+		explainxkcd.WithBreaker(explainBreaker),
+		explainxkcd.WithTransport(transport.New(transport.Config(cfg.HTTPTransport))),
+	)
 	if err != nil {
 		return fmt.Errorf("cannot init ExplainXKCD client: %v", err)
 	}
 	defer closers.CloseOrLog(explainClient, log)
+	explainCache := explainxkcd.NewCache(explainClient, cfg.Explain.CacheTTL,
+		explainxkcd.WithTTLs(cfg.Explain.CacheTTLs, cfg.Explain.MinCacheTTL, cfg.Explain.MaxCacheTTL),
+		explainxkcd.WithMaxEntries(cfg.Explain.CacheMaxEntries),
+	)
+	explainWarmer, err := explainxkcd.NewWarmer(log, explainCache, explainBreaker, cfg.Explain.WarmConcurrency)
+	if err != nil {
+		return fmt.Errorf("cannot init ExplainXKCD warmer: %v", err)
+	}
 
-	authSrv, err := aaa.New(cfg.TokenTTL, log)
+	var imagesOpts []images.Option
+	if cfg.Images.CacheDir != "" {
+		imagesOpts = append(imagesOpts,
+			images.WithDiskCache(cfg.Images.CacheDir, cfg.Images.CacheMaxSize, cfg.Images.CacheTTL))
+	}
+	imagesClient, err := images.NewClient(cfg.Images.Timeout, log, imagesOpts...)
+	if err != nil {
+		return fmt.Errorf("cannot init images client: %v", err)
+	}
+
+	authOpts := []aaa.Option{
+		aaa.WithIssuer(cfg.TokenIssuer),
+		aaa.WithAudience(cfg.TokenAudience),
+		aaa.WithRefreshTTL(cfg.RefreshTTL),
+		aaa.WithMaxSessions(cfg.MaxSessions),
+	}
+	if cfg.SlidingRefresh {
+		authOpts = append(authOpts, aaa.WithSlidingRefresh())
+	}
+	if cfg.RevocationsFile != "" {
+		authOpts = append(authOpts, aaa.WithRevocationStore(aaa.NewFileRevocations(cfg.RevocationsFile)))
+	}
+	if cfg.SessionsFile != "" {
+		authOpts = append(authOpts, aaa.WithSessionStore(aaa.NewFileSessions(cfg.SessionsFile)))
+	}
+	authSrv, err := aaa.New(cfg.TokenTTL, log, authOpts...)
 	if err != nil {
 		return fmt.Errorf("cannot init authenticator: %v", err)
 	}
 
+	queries, err := querylog.New(log, cfg.QueryLog.Size,
+		querylog.WithRetention(cfg.QueryLog.Retention),
+		querylog.WithFile(cfg.QueryLog.File),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot init query log: %v", err)
+	}
+	if err := queries.Load(); err != nil {
+		log.Warn("cannot load query log", "error", err)
+	}
+	defer func() {
+		if err := queries.Flush(); err != nil {
+			log.Error("failed to flush query log", "error", err)
+		}
+	}()
+	clicks := popularity.New(cfg.Popularity.DedupeWindow, cfg.Popularity.MaxClicks)
+	loggedSearcher := querylog.Searcher{
+		Searcher: popularity.Searcher{Searcher: searchClient, Popularity: clicks},
+		Queries:  queries,
+	}
+
 	mux := http.NewServeMux()
 
 	mux.Handle("POST /api/login", rest.NewLoginHandler(log, authSrv))
 	mux.Handle("POST /api/refresh", rest.NewRefreshTokenHandler(log, authSrv))
-	mux.Handle("POST /api/logout", rest.NewLogoutHandler(log))
+	mux.Handle("POST /api/logout", rest.NewLogoutHandler(log, authSrv))
+	mux.Handle("POST /api/token/introspect",
+		middleware.Auth(
+			rest.NewIntrospectHandler(log, authSrv), authSrv,
+		),
+	)
+
+	// public unless listed in auth routes
+	public := publicRoutes{mux: mux, verifier: authSrv, authRoutes: cfg.AuthRoutes}
 
 	mux.Handle("GET /api/db/stats",
 		middleware.Auth(
@@ -88,7 +222,39 @@ func run(cfg config.Config, log *slog.Logger) error {
 			rest.NewUpdateStatusHandler(log, updateClient), authSrv,
 		),
 	)
-	mux.Handle("GET /api/explain", rest.NewExplainHandler(log, explainClient))
+	mux.Handle("GET /api/db/progress",
+		middleware.Auth(
+			rest.NewUpdateProgressHandler(log, updateClient), authSrv,
+		),
+	)
+	mux.Handle("GET /api/db/broken-links",
+		middleware.Auth(
+			rest.NewBrokenLinksHandler(log, updateClient), authSrv,
+		),
+	)
+	mux.Handle("GET /api/search/build-history",
+		middleware.Auth(
+			rest.NewBuildHistoryHandler(log, searchClient), authSrv,
+		),
+	)
+	mux.Handle("GET /api/search/queries",
+		middleware.Auth(
+			rest.NewQueryLogHandler(log, queries), authSrv,
+		),
+	)
+	if recentLogs != nil {
+		mux.Handle("GET /api/logs/recent",
+			middleware.Auth(
+				rest.NewRecentLogsHandler(log, recentLogs), authSrv,
+			),
+		)
+	}
+	mux.Handle("GET /api/explain/breaker",
+		middleware.Auth(
+			rest.NewBreakerHandler(log, explainBreaker), authSrv,
+		),
+	)
+	public.Handle("GET /api/explain", rest.NewExplainHandler(log, explainCache, cfg.Explain.Format, authSrv))
 
 	// authorize update/delete
 	mux.Handle("POST /api/db/update",
@@ -96,6 +262,31 @@ func run(cfg config.Config, log *slog.Logger) error {
 			rest.NewUpdateHandler(log, updateClient), authSrv,
 		),
 	)
+	mux.Handle("POST /api/db/comic/{id}",
+		middleware.Auth(
+			rest.NewUpdateComicHandler(log, updateClient), authSrv,
+		),
+	)
+	mux.Handle("POST /api/db/stats/reset",
+		middleware.Auth(
+			rest.NewResetStatsHandler(log, updateClient), authSrv,
+		),
+	)
+	mux.Handle("PUT /api/comic/tags",
+		middleware.Auth(
+			rest.NewSetTagsHandler(log, updateClient), authSrv,
+		),
+	)
+	mux.Handle("POST /api/search/reindex",
+		middleware.Auth(
+			rest.NewReindexComicHandler(log, searchClient), authSrv,
+		),
+	)
+	mux.Handle("POST /api/explain/breaker/reset",
+		middleware.Auth(
+			rest.NewResetBreakerHandler(log, explainBreaker), authSrv,
+		),
+	)
 	mux.Handle("DELETE /api/db",
 		middleware.Auth(
 			rest.NewDropHandler(log, updateClient), authSrv,
@@ -103,35 +294,108 @@ func run(cfg config.Config, log *slog.Logger) error {
 	)
 
 	// restrict
-	mux.Handle("GET /api/search",
+	clientIPs, err := middleware.NewClientIPs(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	limitIP := func(next http.HandlerFunc) http.HandlerFunc { return next }
+	if ipRate := cfg.SearchIPRate; ipRate.RPS > 0 {
+		ipLimiter := middleware.NewIPLimiter(ipRate.RPS, ipRate.Burst, ipRate.MaxKeys, ipRate.OverflowRPS)
+		limitIP = func(next http.HandlerFunc) http.HandlerFunc {
+			return middleware.RatePerIP(next, ipLimiter, clientIPs)
+		}
+	}
+	public.Handle("GET /api/search", limitIP(
 		middleware.Concurrency(
-			rest.NewSearchHandler(log, searchClient), cfg.SearchConcurrency,
+			rest.NewSearchHandler(log, loggedSearcher, cfg.SearchEmptyOK), cfg.SearchConcurrency,
 		),
-	)
-	mux.Handle("GET /api/isearch",
+	))
+	public.Handle("POST /api/search", limitIP(
+		middleware.Concurrency(
+			rest.NewSearchQueryHandler(log, loggedSearcher), cfg.SearchConcurrency,
+		),
+	))
+	public.Handle("GET /api/isearch", limitIP(
 		middleware.Rate(
-			rest.NewSearchIndexHandler(log, searchClient), cfg.SearchRate,
+			rest.NewSearchIndexHandler(log, loggedSearcher, cfg.SearchEmptyOK), cfg.SearchRate,
 		),
-	)
+	))
+	limitStreams := func(next http.HandlerFunc) http.HandlerFunc { return next }
+	if cfg.SearchStreamsPerIP > 0 {
+		limitStreams = func(next http.HandlerFunc) http.HandlerFunc {
+			return middleware.StreamsPerIP(next, cfg.SearchStreamsPerIP, clientIPs)
+		}
+	}
+	// an open stream holds a search concurrency slot until it is closed
+	searchStreams := rest.NewSearchStreams()
+	public.Handle("GET /api/search/sse", limitIP(limitStreams(
+		middleware.Concurrency(
+			middleware.Rate(
+				rest.NewSearchEventsHandler(log, loggedSearcher, searchStreams), cfg.SearchRate,
+			), cfg.SearchConcurrency,
+		),
+	)))
+	public.Handle("POST /api/search/sse", limitIP(
+		middleware.Concurrency(
+			middleware.Rate(
+				rest.NewSearchEventsQueryHandler(log, searchStreams), cfg.SearchRate,
+			), cfg.SearchConcurrency,
+		),
+	))
 
-	mux.Handle("GET /api/ping", rest.NewPingHandler(
-		log,
-		map[string]core.Pinger{
-			"words":  wordsClient,
-			"update": updateClient,
-			"search": searchClient,
-		}),
+	public.Handle("GET /api/comic", rest.NewComicHandler(log, searchClient))
+	public.Handle("POST /api/comic/click", limitIP(rest.NewClickHandler(log, searchClient, clicks, clientIPs)))
+	public.Handle("GET /api/comic/by-title", rest.NewComicByTitleHandler(log, searchClient))
+	public.Handle("GET /api/comic/image", rest.NewComicImageHandler(log, searchClient, imagesClient))
+	public.Handle("GET /api/comics/changes", rest.NewChangesHandler(log, searchClient))
+	public.Handle("GET /api/comics/latest-id", rest.NewLatestIDHandler(log, searchClient))
+	public.Handle("GET /api/comic/tags", rest.NewTagsHandler(log, updateClient))
+	public.Handle("GET /api/words/df", rest.NewDocumentFrequencyHandler(log, searchClient))
+
+	public.Handle("GET /api/selftest",
+		rest.NewSelfTestHandler(log, wordsClient, searchClient, cfg.SelfTestPhrase),
 	)
+	public.Handle("GET /api/readyz", rest.NewReadyHandler(log, searchClient, cfg.ReadyMaxIndexAge))
+	public.Handle("GET /api/ping", rest.NewPingHandler(log, backends))
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	server := http.Server{
-		Addr:        cfg.HTTPConfig.Address,
-		ReadTimeout: cfg.HTTPConfig.Timeout,
-		Handler:     mux,
-		BaseContext: func(_ net.Listener) context.Context { return ctx },
+	for _, algorithm := range cfg.Compression.Algorithms {
+		if !middleware.SupportedEncoding(algorithm) {
+			return fmt.Errorf("unsupported compression algorithm %q", algorithm)
+		}
+	}
+	if !middleware.SupportedSlashMode(cfg.TrailingSlash) {
+		return fmt.Errorf("unsupported trailing slash mode %q", cfg.TrailingSlash)
 	}
+	httpMetrics := metrics.NewHTTP(prometheus.DefaultRegisterer)
+	mux.Handle("GET /metrics", promhttp.Handler())
+	routes := middleware.TrailingSlash(middleware.Metrics(mux, httpMetrics), cfg.TrailingSlash)
+	if cfg.HTTPConfig.BodyTimeout > 0 {
+		routes = middleware.BodyTimeout(routes, cfg.HTTPConfig.BodyTimeout, cfg.HTTPConfig.MaxBodySize)
+	}
+	handler := middleware.Compress(routes, cfg.Compression.MinSize, cfg.Compression.Algorithms)
+
+	server := newServer(ctx, cfg.HTTPConfig, handler)
+
+	if cfg.QueryLog.File != "" {
+		go queries.Run(ctx, cfg.QueryLog.FlushInterval)
+	}
+
+	if cfg.Explain.WarmCount > 0 {
+		go warmExplanations(ctx, log, explainWarmer, updateClient, cfg.Explain.WarmCount)
+	}
+
+	if warmup := warmupPhrases(cfg.Warmup, queries); len(warmup) > 0 {
+		go warmSearches(ctx, log, searchClient, warmup)
+	}
+
+	go checkVersions(ctx, log, []protoVersion{
+		{service: "words", client: wordsClient, want: wordspb.Version},
+		{service: "update", client: updateClient, want: updatepb.Version},
+		{service: "search", client: searchClient, want: searchpb.Version},
+	})
 
 	go func() {
 		<-ctx.Done()
@@ -150,6 +414,150 @@ func run(cfg config.Config, log *slog.Logger) error {
 	return nil
 }
 
+// backendPollInterval paces pings of backends not answering at startup.
+const backendPollInterval = 200 * time.Millisecond
+
+// waitBackends pings backends until each answers, a degraded one counts.
+func waitBackends(ctx context.Context, backends map[string]core.Pinger) error {
+	for name, backend := range backends {
+		for {
+			err := backend.Ping(ctx)
+			if err == nil || errors.Is(err, core.ErrDegraded) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s is not ready: %v", name, err)
+			case <-time.After(backendPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// warmExplanations caches explanations of the newest count comics.
+func warmExplanations(ctx context.Context, log *slog.Logger, warmer *explainxkcd.Warmer, updater core.Updater, count int) {
+	stats, err := updater.Stats(ctx)
+	if err != nil {
+		log.Warn("cannot warm explanations", "error", err)
+		return
+	}
+	ids := make([]int, 0, count)
+	for id := stats.ComicsTotal; id > 0 && len(ids) < count; id-- {
+		ids = append(ids, id)
+	}
+	log.Info("explanations warmed", "count", warmer.Warm(ctx, ids), "requested", len(ids))
+}
+
+// warmupPhrases joins configured phrases with the most logged ones, without repeats.
+func warmupPhrases(cfg config.WarmupConfig, queries *querylog.Log) []string {
+	var phrases []string
+	for _, phrase := range append(slices.Clone(cfg.Phrases), queries.Top(cfg.TopQueries)...) {
+		if phrase != "" && !slices.Contains(phrases, phrase) {
+			phrases = append(phrases, phrase)
+		}
+	}
+	return phrases
+}
+
+type indexSearcher interface {
+	SearchIndex(context.Context, string, int, core.SearchOptions) (core.SearchResult, error)
+}
+
+const warmupLimit = 10
+
+// warmSearches runs phrases through normalization and the index, so that
+// first searches after a restart do not pay for cold connections and caches.
+// Searches are not recorded in the query log.
+func warmSearches(ctx context.Context, log *slog.Logger, searcher indexSearcher, phrases []string) int {
+	var warmed int
+	for _, phrase := range phrases {
+		if ctx.Err() != nil {
+			break
+		}
+		_, err := searcher.SearchIndex(ctx, phrase, warmupLimit, core.SearchOptions{})
+		if err != nil && !errors.Is(err, core.ErrNotFound) {
+			log.Warn("warmup search failed", "phrase", phrase, "error", err)
+			continue
+		}
+		warmed++
+	}
+	log.Info("searches warmed", "count", warmed, "requested", len(phrases))
+	return warmed
+}
+
+type versioned interface {
+	Version(ctx context.Context) (int, error)
+}
+
+// protoVersion is the proto version of a service the API is built with.
+type protoVersion struct {
+	service string
+	client  versioned
+	want    int
+}
+
+const versionCheckTimeout = 5 * time.Second
+
+// checkVersions logs services built with another proto version than
+// the API, as calls to them fail with opaque errors, and returns their count.
+func checkVersions(ctx context.Context, log *slog.Logger, versions []protoVersion) int {
+	var mismatched int
+	for _, v := range versions {
+		callCtx, cancel := context.WithTimeout(ctx, versionCheckTimeout)
+		got, err := v.client.Version(callCtx)
+		cancel()
+		switch {
+		case status.Code(err) == codes.Unimplemented:
+			log.Error("proto version mismatch, service predates versioning",
+				"service", v.service, "want", v.want)
+			mismatched++
+		case err != nil:
+			log.Warn("cannot check proto version", "service", v.service, "error", err)
+		case got != v.want:
+			log.Error("proto version mismatch, redeploy API and service together",
+				"service", v.service, "want", v.want, "got", got)
+			mismatched++
+		default:
+			log.Debug("proto version matches", "service", v.service, "version", got)
+		}
+	}
+	return mismatched
+}
+
+// publicRoutes registers handlers that only require authentication
+// when their pattern or path is listed in authRoutes.
+type publicRoutes struct {
+	mux        *http.ServeMux
+	verifier   middleware.TokenVerifier
+	authRoutes []string
+}
+
+func (p publicRoutes) Handle(pattern string, handler http.HandlerFunc) {
+	_, path, _ := strings.Cut(pattern, " ")
+	if slices.Contains(p.authRoutes, pattern) || slices.Contains(p.authRoutes, path) {
+		handler = middleware.Auth(handler, p.verifier)
+	}
+	p.mux.Handle(pattern, handler)
+}
+
+func newServer(ctx context.Context, cfg config.HTTPConfig, handler http.Handler) *http.Server {
+	// h2c is the only HTTP/2 flavour available without TLS
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2)
+	protocols.SetUnencryptedHTTP2(cfg.HTTP2)
+
+	return &http.Server{
+		Addr:        cfg.Address,
+		ReadTimeout: cfg.Timeout,
+		IdleTimeout: cfg.IdleTimeout,
+		Protocols:   &protocols,
+		Handler:     handler,
+		BaseContext: func(_ net.Listener) context.Context { return ctx },
+	}
+}
+
 func mustMakeLogger(logLevel string) *slog.Logger {
 	var level slog.Level
 	switch logLevel {
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/liy0aay/xkcd-search/api/adapters/querylog"
+	"github.com/liy0aay/xkcd-search/api/config"
+	"github.com/liy0aay/xkcd-search/api/core"
+)
+
+func TestNewServer_Timeouts(t *testing.T) {
+	server := newServer(context.Background(), config.HTTPConfig{
+		Address:     ":8080",
+		Timeout:     5 * time.Second,
+		IdleTimeout: 42 * time.Second,
+	}, http.NewServeMux())
+
+	assert.Equal(t, ":8080", server.Addr)
+	assert.Equal(t, 5*time.Second, server.ReadTimeout)
+	assert.Equal(t, 42*time.Second, server.IdleTimeout)
+}
+
+func TestNewServer_HTTP2(t *testing.T) {
+	disabled := newServer(context.Background(), config.HTTPConfig{}, http.NewServeMux())
+	assert.True(t, disabled.Protocols.HTTP1())
+	assert.False(t, disabled.Protocols.UnencryptedHTTP2())
+
+	enabled := newServer(context.Background(), config.HTTPConfig{HTTP2: true}, http.NewServeMux())
+	assert.True(t, enabled.Protocols.HTTP1())
+	assert.True(t, enabled.Protocols.UnencryptedHTTP2())
+}
+
+type rejectingVerifier struct{}
+
+func (rejectingVerifier) Verify(string) error {
+	return errors.New("invalid token")
+}
+
+func (rejectingVerifier) RefreshAccessToken(string) (string, string, error) {
+	return "", "", errors.New("invalid token")
+}
+
+func (rejectingVerifier) RefreshTTL() time.Duration {
+	return time.Hour
+}
+
+func TestPublicRoutes_AuthRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	public := publicRoutes{mux: mux, verifier: rejectingVerifier{}, authRoutes: []string{"/api/search"}}
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+	public.Handle("GET /api/search", ok)
+	public.Handle("GET /api/comic", ok)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/search?phrase=linux", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/comic?id=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+type fakeVersioned struct {
+	version int
+	err     error
+}
+
+func (f fakeVersioned) Version(context.Context) (int, error) {
+	return f.version, f.err
+}
+
+func TestCheckVersions_Mismatch(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+
+	mismatched := checkVersions(context.Background(), log, []protoVersion{
+		{service: "words", client: fakeVersioned{version: 1}, want: 1},
+		{service: "search", client: fakeVersioned{version: 2}, want: 1},
+		{service: "update", client: fakeVersioned{err: status.Error(codes.Unimplemented, "unknown method")}, want: 1},
+	})
+
+	assert.Equal(t, 2, mismatched)
+	assert.Contains(t, logs.String(), `level=ERROR msg="proto version mismatch, redeploy API and service together" service=search want=1 got=2`)
+	assert.Contains(t, logs.String(), `service=update`)
+	assert.NotContains(t, logs.String(), `service=words`)
+}
+
+type recordingSearcher struct {
+	phrases []string
+}
+
+func (r *recordingSearcher) SearchIndex(
+	_ context.Context, phrase string, _ int, _ core.SearchOptions,
+) (core.SearchResult, error) {
+	r.phrases = append(r.phrases, phrase)
+	switch phrase {
+	case "nothing":
+		return core.SearchResult{}, core.ErrNotFound
+	case "broken":
+		return core.SearchResult{}, errors.New("unavailable")
+	}
+	return core.SearchResult{Comics: []core.Comics{{ID: 1}}, Total: 1}, nil
+}
+
+func TestWarmSearches_ConfiguredAndTopPhrases(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	queries, err := querylog.New(log, 10)
+	require.NoError(t, err)
+	for _, phrase := range []string{"linux", "python", "python", "nothing"} {
+		queries.Record(core.Query{Phrase: phrase, At: time.Now()})
+	}
+
+	phrases := warmupPhrases(config.WarmupConfig{Phrases: []string{"linux", "cat"}, TopQueries: 2}, queries)
+	assert.Equal(t, []string{"linux", "cat", "python"}, phrases)
+
+	searcher := &recordingSearcher{}
+	assert.Equal(t, 3, warmSearches(context.Background(), log, searcher, phrases))
+	assert.Equal(t, []string{"linux", "cat", "python"}, searcher.phrases)
+	assert.Equal(t, 1, warmSearches(context.Background(), log, searcher, []string{"nothing", "broken"}))
+}
+
+// startingBackend fails pings until it answered the given times.
+type startingBackend struct {
+	failures int
+	err      error
+}
+
+func (b *startingBackend) Ping(context.Context) error {
+	if b.failures > 0 {
+		b.failures--
+		return status.Error(codes.Unavailable, "starting")
+	}
+	return b.err
+}
+
+func TestWaitBackends(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := waitBackends(ctx, map[string]core.Pinger{
+		"words":  &startingBackend{failures: 2},
+		"search": &startingBackend{err: core.ErrDegraded},
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = waitBackends(ctx, map[string]core.Pinger{"update": &startingBackend{failures: 1000}})
+	assert.ErrorContains(t, err, "update is not ready")
+}
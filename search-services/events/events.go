@@ -4,3 +4,9 @@ const (
 	TopicDBUpdated = "xkcd.db.updated"
 	TopicDBDropped = "xkcd.db.dropped"
 )
+
+// DBUpdated is the payload of TopicDBUpdated, IDs lists comics added
+// by an update checkpoint and is empty once the whole update is done.
+type DBUpdated struct {
+	IDs []int `json:"ids,omitempty"`
+}
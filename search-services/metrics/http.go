@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTP tracks requests of a server per route pattern and method.
+type HTTP struct {
+	requests  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+	inFlight  *prometheus.GaugeVec
+	rejected  *prometheus.CounterVec
+}
+
+// NewHTTP registers the request metrics in registerer.
+func NewHTTP(registerer prometheus.Registerer) *HTTP {
+	m := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Requests served by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Request latencies by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Requests being served by route and method.",
+		}, []string{"route", "method"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_rejected_total",
+			Help: "Requests rejected by route, method and error code.",
+		}, []string{"route", "method", "code"}),
+	}
+	registerer.MustRegister(m.requests, m.durations, m.inFlight, m.rejected)
+	return m
+}
+
+// Started counts a request in flight until it is Finished.
+func (m *HTTP) Started(pattern, method string) {
+	m.inFlight.WithLabelValues(pattern, method).Inc()
+}
+
+func (m *HTTP) Finished(pattern, method string, status int, duration time.Duration) {
+	m.inFlight.WithLabelValues(pattern, method).Dec()
+	m.requests.WithLabelValues(pattern, method, strconv.Itoa(status)).Inc()
+	m.durations.WithLabelValues(pattern, method).Observe(duration.Seconds())
+}
+
+// Rejected counts a request refused for the reason of code.
+func (m *HTTP) Rejected(pattern, method, code string) {
+	m.rejected.WithLabelValues(pattern, method, code).Inc()
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_Exposition(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewHTTP(registry)
+	m.Started("/api/search", "GET")
+	m.Finished("/api/search", "GET", 200, 30*time.Millisecond)
+	m.Started("/api/search", "GET")
+	m.Finished("/api/search", "GET", 404, 2*time.Second)
+	m.Started("/api/search/sse", "GET")
+	m.Rejected("/api/search", "GET", "stop_words_only")
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/search",status="200"} 1`+"\n")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/search",status="404"} 1`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/api/search",le="0.025"} 0`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/api/search",le="0.05"} 1`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/api/search",le="2.5"} 2`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/api/search",le="+Inf"} 2`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_sum{method="GET",route="/api/search"} 2.03`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/api/search"} 2`+"\n")
+	assert.Contains(t, body, `http_requests_in_flight{method="GET",route="/api/search"} 0`+"\n")
+	assert.Contains(t, body, `http_requests_in_flight{method="GET",route="/api/search/sse"} 1`+"\n")
+	assert.Contains(t, body,
+		`http_requests_rejected_total{code="stop_words_only",method="GET",route="/api/search"} 1`+"\n")
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NATS tracks the broker connection of a service, it is updated from the
+// connection handlers and collected by Prometheus registries.
+type NATS struct {
+	connected      atomic.Bool
+	reconnects     atomic.Int64
+	disconnects    atomic.Int64
+	lastDisconnect atomic.Int64
+}
+
+func (m *NATS) Connected() {
+	m.connected.Store(true)
+}
+
+func (m *NATS) Disconnected(at time.Time) {
+	m.connected.Store(false)
+	m.disconnects.Add(1)
+	m.lastDisconnect.Store(at.Unix())
+}
+
+func (m *NATS) Reconnected() {
+	m.connected.Store(true)
+	m.reconnects.Add(1)
+}
+
+func (m *NATS) Closed() {
+	m.connected.Store(false)
+}
+
+func (m *NATS) IsConnected() bool {
+	return m.connected.Load()
+}
+
+func (m *NATS) Reconnects() int64 {
+	return m.reconnects.Load()
+}
+
+func (m *NATS) Disconnects() int64 {
+	return m.disconnects.Load()
+}
+
+// LastDisconnect is zero until the first disconnect.
+func (m *NATS) LastDisconnect() time.Time {
+	if at := m.lastDisconnect.Load(); at != 0 {
+		return time.Unix(at, 0)
+	}
+	return time.Time{}
+}
+
+var (
+	natsConnectedDesc = prometheus.NewDesc("nats_connected",
+		"Whether the broker connection is up.", nil, nil)
+	natsReconnectsDesc = prometheus.NewDesc("nats_reconnects_total",
+		"Broker reconnects since start.", nil, nil)
+	natsDisconnectsDesc = prometheus.NewDesc("nats_disconnects_total",
+		"Broker disconnects since start.", nil, nil)
+	natsLastDisconnectDesc = prometheus.NewDesc("nats_last_disconnect_timestamp_seconds",
+		"Time of the last broker disconnect.", nil, nil)
+)
+
+func (m *NATS) Describe(ch chan<- *prometheus.Desc) {
+	ch <- natsConnectedDesc
+	ch <- natsReconnectsDesc
+	ch <- natsDisconnectsDesc
+	ch <- natsLastDisconnectDesc
+}
+
+// Collect reads the connection state as it is at scrape time.
+func (m *NATS) Collect(ch chan<- prometheus.Metric) {
+	var connected float64
+	if m.IsConnected() {
+		connected = 1
+	}
+	ch <- prometheus.MustNewConstMetric(natsConnectedDesc, prometheus.GaugeValue, connected)
+	ch <- prometheus.MustNewConstMetric(natsReconnectsDesc, prometheus.CounterValue, float64(m.Reconnects()))
+	ch <- prometheus.MustNewConstMetric(natsDisconnectsDesc, prometheus.CounterValue, float64(m.Disconnects()))
+	ch <- prometheus.MustNewConstMetric(natsLastDisconnectDesc, prometheus.GaugeValue,
+		float64(m.lastDisconnect.Load()))
+}
+
+// Serve exposes handler on /metrics at address until ctx is done.
+func Serve(ctx context.Context, address string, handler http.Handler, log *slog.Logger) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", handler)
+	server := http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		if err := server.Close(); err != nil {
+			log.Error("failed to stop metrics server", "error", err)
+		}
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("metrics server failed", "error", err)
+		}
+	}()
+	return nil
+}
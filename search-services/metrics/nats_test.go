@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATS_Collect(t *testing.T) {
+	var m NATS
+	m.Connected()
+	m.Disconnected(time.Unix(1700000000, 0))
+	m.Reconnected()
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(&m))
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "nats_connected 1\n")
+	assert.Contains(t, body, "nats_reconnects_total 1\n")
+	assert.Contains(t, body, "nats_disconnects_total 1\n")
+	assert.Contains(t, body, "nats_last_disconnect_timestamp_seconds 1.7e+09\n")
+}
+
+func TestNATS_Closed(t *testing.T) {
+	var m NATS
+	m.Connected()
+	m.Closed()
+
+	assert.False(t, m.IsConnected())
+	assert.True(t, m.LastDisconnect().IsZero())
+}
@@ -0,0 +1,25 @@
+package ngrams
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokens splits text into words the way the words service does before stemming.
+func Tokens(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsDigit(r) && !unicode.IsLetter(r)
+	})
+}
+
+// Join joins every 2 up to n adjacent stems with spaces into keywords,
+// the update service indexes them and the search service looks them up.
+func Join(stems []string, n int) []string {
+	var grams []string
+	for size := 2; size <= n; size++ {
+		for i := 0; i+size <= len(stems); i++ {
+			grams = append(grams, strings.Join(stems[i:i+size], " "))
+		}
+	}
+	return grams
+}
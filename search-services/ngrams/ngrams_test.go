@@ -0,0 +1,16 @@
+package ngrams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoin(t *testing.T) {
+	assert.Nil(t, Join([]string{"self"}, 2))
+	assert.Equal(t, []string{"a b", "b c", "a b c"}, Join([]string{"a", "b", "c"}, 3))
+}
+
+func TestTokens(t *testing.T) {
+	assert.Equal(t, []string{"Self", "driving", "car", "x11"}, Tokens("Self-driving car; x11"))
+}
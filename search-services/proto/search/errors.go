@@ -0,0 +1,9 @@
+package search
+
+// Reasons of errdetails.ErrorInfo attached to statuses of the search
+// service, they tell apart errors sharing a status code.
+const (
+	ErrorDomain = "search"
+	// ReasonStopWords rejects a phrase normalized to no keywords.
+	ReasonStopWords = "STOP_WORDS_ONLY"
+)
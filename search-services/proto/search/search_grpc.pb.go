@@ -20,8 +20,18 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type SearchClient interface {
 	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionReply, error)
 	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchReply, error)
 	SearchIndex(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchReply, error)
+	Comic(ctx context.Context, in *ComicRequest, opts ...grpc.CallOption) (*ComicReply, error)
+	DocumentFrequency(ctx context.Context, in *DocumentFrequencyRequest, opts ...grpc.CallOption) (*DocumentFrequencyReply, error)
+	BuildHistory(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BuildHistoryReply, error)
+	Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (*ChangesReply, error)
+	ReindexComic(ctx context.Context, in *ComicRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ReindexRange(ctx context.Context, in *ReindexRangeRequest, opts ...grpc.CallOption) (*ReindexRangeReply, error)
+	ByTitle(ctx context.Context, in *TitleRequest, opts ...grpc.CallOption) (*SearchReply, error)
+	LastID(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*LastIDReply, error)
+	IndexStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*IndexStatsReply, error)
 }
 
 type searchClient struct {
@@ -41,6 +51,15 @@ func (c *searchClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc
 	return out, nil
 }
 
+func (c *searchClient) Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionReply, error) {
+	out := new(VersionReply)
+	err := c.cc.Invoke(ctx, "/search.Search/Version", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *searchClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchReply, error) {
 	out := new(SearchReply)
 	err := c.cc.Invoke(ctx, "/search.Search/Search", in, out, opts...)
@@ -59,13 +78,104 @@ func (c *searchClient) SearchIndex(ctx context.Context, in *SearchRequest, opts
 	return out, nil
 }
 
+func (c *searchClient) Comic(ctx context.Context, in *ComicRequest, opts ...grpc.CallOption) (*ComicReply, error) {
+	out := new(ComicReply)
+	err := c.cc.Invoke(ctx, "/search.Search/Comic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) DocumentFrequency(ctx context.Context, in *DocumentFrequencyRequest, opts ...grpc.CallOption) (*DocumentFrequencyReply, error) {
+	out := new(DocumentFrequencyReply)
+	err := c.cc.Invoke(ctx, "/search.Search/DocumentFrequency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) BuildHistory(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BuildHistoryReply, error) {
+	out := new(BuildHistoryReply)
+	err := c.cc.Invoke(ctx, "/search.Search/BuildHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (*ChangesReply, error) {
+	out := new(ChangesReply)
+	err := c.cc.Invoke(ctx, "/search.Search/Changes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) ReindexComic(ctx context.Context, in *ComicRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/search.Search/ReindexComic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) ReindexRange(ctx context.Context, in *ReindexRangeRequest, opts ...grpc.CallOption) (*ReindexRangeReply, error) {
+	out := new(ReindexRangeReply)
+	err := c.cc.Invoke(ctx, "/search.Search/ReindexRange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) ByTitle(ctx context.Context, in *TitleRequest, opts ...grpc.CallOption) (*SearchReply, error) {
+	out := new(SearchReply)
+	err := c.cc.Invoke(ctx, "/search.Search/ByTitle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) LastID(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*LastIDReply, error) {
+	out := new(LastIDReply)
+	err := c.cc.Invoke(ctx, "/search.Search/LastID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) IndexStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*IndexStatsReply, error) {
+	out := new(IndexStatsReply)
+	err := c.cc.Invoke(ctx, "/search.Search/IndexStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SearchServer is the server API for Search service.
 // All implementations must embed UnimplementedSearchServer
 // for forward compatibility
 type SearchServer interface {
 	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	Version(context.Context, *emptypb.Empty) (*VersionReply, error)
 	Search(context.Context, *SearchRequest) (*SearchReply, error)
 	SearchIndex(context.Context, *SearchRequest) (*SearchReply, error)
+	Comic(context.Context, *ComicRequest) (*ComicReply, error)
+	DocumentFrequency(context.Context, *DocumentFrequencyRequest) (*DocumentFrequencyReply, error)
+	BuildHistory(context.Context, *emptypb.Empty) (*BuildHistoryReply, error)
+	Changes(context.Context, *ChangesRequest) (*ChangesReply, error)
+	ReindexComic(context.Context, *ComicRequest) (*emptypb.Empty, error)
+	ReindexRange(context.Context, *ReindexRangeRequest) (*ReindexRangeReply, error)
+	ByTitle(context.Context, *TitleRequest) (*SearchReply, error)
+	LastID(context.Context, *emptypb.Empty) (*LastIDReply, error)
+	IndexStats(context.Context, *emptypb.Empty) (*IndexStatsReply, error)
 	mustEmbedUnimplementedSearchServer()
 }
 
@@ -76,12 +186,42 @@ type UnimplementedSearchServer struct {
 func (UnimplementedSearchServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedSearchServer) Version(context.Context, *emptypb.Empty) (*VersionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
 func (UnimplementedSearchServer) Search(context.Context, *SearchRequest) (*SearchReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
 }
 func (UnimplementedSearchServer) SearchIndex(context.Context, *SearchRequest) (*SearchReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchIndex not implemented")
 }
+func (UnimplementedSearchServer) Comic(context.Context, *ComicRequest) (*ComicReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Comic not implemented")
+}
+func (UnimplementedSearchServer) DocumentFrequency(context.Context, *DocumentFrequencyRequest) (*DocumentFrequencyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DocumentFrequency not implemented")
+}
+func (UnimplementedSearchServer) BuildHistory(context.Context, *emptypb.Empty) (*BuildHistoryReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildHistory not implemented")
+}
+func (UnimplementedSearchServer) Changes(context.Context, *ChangesRequest) (*ChangesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Changes not implemented")
+}
+func (UnimplementedSearchServer) ReindexComic(context.Context, *ComicRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReindexComic not implemented")
+}
+func (UnimplementedSearchServer) ReindexRange(context.Context, *ReindexRangeRequest) (*ReindexRangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReindexRange not implemented")
+}
+func (UnimplementedSearchServer) ByTitle(context.Context, *TitleRequest) (*SearchReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ByTitle not implemented")
+}
+func (UnimplementedSearchServer) LastID(context.Context, *emptypb.Empty) (*LastIDReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LastID not implemented")
+}
+func (UnimplementedSearchServer) IndexStats(context.Context, *emptypb.Empty) (*IndexStatsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IndexStats not implemented")
+}
 func (UnimplementedSearchServer) mustEmbedUnimplementedSearchServer() {}
 
 // UnsafeSearchServer may be embedded to opt out of forward compatibility for this service.
@@ -113,6 +253,24 @@ func _Search_Ping_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Search_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/Version",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Version(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Search_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SearchRequest)
 	if err := dec(in); err != nil {
@@ -149,6 +307,168 @@ func _Search_SearchIndex_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Search_Comic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ComicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Comic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/Comic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Comic(ctx, req.(*ComicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_DocumentFrequency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DocumentFrequencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).DocumentFrequency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/DocumentFrequency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).DocumentFrequency(ctx, req.(*DocumentFrequencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_BuildHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).BuildHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/BuildHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).BuildHistory(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_Changes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Changes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/Changes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Changes(ctx, req.(*ChangesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_ReindexComic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ComicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).ReindexComic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/ReindexComic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).ReindexComic(ctx, req.(*ComicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_ReindexRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReindexRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).ReindexRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/ReindexRange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).ReindexRange(ctx, req.(*ReindexRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_ByTitle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TitleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).ByTitle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/ByTitle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).ByTitle(ctx, req.(*TitleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_LastID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).LastID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/LastID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).LastID(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_IndexStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).IndexStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.Search/IndexStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).IndexStats(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Search_ServiceDesc is the grpc.ServiceDesc for Search service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -160,6 +480,10 @@ var Search_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Search_Ping_Handler,
 		},
+		{
+			MethodName: "Version",
+			Handler:    _Search_Version_Handler,
+		},
 		{
 			MethodName: "Search",
 			Handler:    _Search_Search_Handler,
@@ -168,6 +492,42 @@ var Search_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SearchIndex",
 			Handler:    _Search_SearchIndex_Handler,
 		},
+		{
+			MethodName: "Comic",
+			Handler:    _Search_Comic_Handler,
+		},
+		{
+			MethodName: "DocumentFrequency",
+			Handler:    _Search_DocumentFrequency_Handler,
+		},
+		{
+			MethodName: "BuildHistory",
+			Handler:    _Search_BuildHistory_Handler,
+		},
+		{
+			MethodName: "Changes",
+			Handler:    _Search_Changes_Handler,
+		},
+		{
+			MethodName: "ReindexComic",
+			Handler:    _Search_ReindexComic_Handler,
+		},
+		{
+			MethodName: "ReindexRange",
+			Handler:    _Search_ReindexRange_Handler,
+		},
+		{
+			MethodName: "ByTitle",
+			Handler:    _Search_ByTitle_Handler,
+		},
+		{
+			MethodName: "LastID",
+			Handler:    _Search_LastID_Handler,
+		},
+		{
+			MethodName: "IndexStats",
+			Handler:    _Search_IndexStats_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/search/search.proto",
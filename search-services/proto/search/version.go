@@ -0,0 +1,5 @@
+package search
+
+// Version is bumped on incompatible changes of the search proto,
+// clients compare it with the one reported by the service.
+const Version = 1
@@ -20,10 +20,18 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type UpdateClient interface {
 	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionReply, error)
 	Status(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusReply, error)
 	Update(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpdateOne(ctx context.Context, in *UpdateOneRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Stats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatsReply, error)
+	ResetStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Drop(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	BrokenLinks(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BrokenLinksReply, error)
+	SetTags(ctx context.Context, in *SetTagsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetTags(ctx context.Context, in *TagsRequest, opts ...grpc.CallOption) (*TagsReply, error)
+	// streams progress of the running update, it ends with the update
+	UpdateProgress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Update_UpdateProgressClient, error)
 }
 
 type updateClient struct {
@@ -43,6 +51,15 @@ func (c *updateClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc
 	return out, nil
 }
 
+func (c *updateClient) Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionReply, error) {
+	out := new(VersionReply)
+	err := c.cc.Invoke(ctx, "/update.Update/Version", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *updateClient) Status(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusReply, error) {
 	out := new(StatusReply)
 	err := c.cc.Invoke(ctx, "/update.Update/Status", in, out, opts...)
@@ -61,6 +78,15 @@ func (c *updateClient) Update(ctx context.Context, in *emptypb.Empty, opts ...gr
 	return out, nil
 }
 
+func (c *updateClient) UpdateOne(ctx context.Context, in *UpdateOneRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/update.Update/UpdateOne", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *updateClient) Stats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatsReply, error) {
 	out := new(StatsReply)
 	err := c.cc.Invoke(ctx, "/update.Update/Stats", in, out, opts...)
@@ -70,6 +96,15 @@ func (c *updateClient) Stats(ctx context.Context, in *emptypb.Empty, opts ...grp
 	return out, nil
 }
 
+func (c *updateClient) ResetStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/update.Update/ResetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *updateClient) Drop(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, "/update.Update/Drop", in, out, opts...)
@@ -79,15 +114,82 @@ func (c *updateClient) Drop(ctx context.Context, in *emptypb.Empty, opts ...grpc
 	return out, nil
 }
 
+func (c *updateClient) BrokenLinks(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*BrokenLinksReply, error) {
+	out := new(BrokenLinksReply)
+	err := c.cc.Invoke(ctx, "/update.Update/BrokenLinks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updateClient) SetTags(ctx context.Context, in *SetTagsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/update.Update/SetTags", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updateClient) GetTags(ctx context.Context, in *TagsRequest, opts ...grpc.CallOption) (*TagsReply, error) {
+	out := new(TagsReply)
+	err := c.cc.Invoke(ctx, "/update.Update/GetTags", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *updateClient) UpdateProgress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Update_UpdateProgressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Update_ServiceDesc.Streams[0], "/update.Update/UpdateProgress", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &updateUpdateProgressClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Update_UpdateProgressClient interface {
+	Recv() (*ProgressReply, error)
+	grpc.ClientStream
+}
+
+type updateUpdateProgressClient struct {
+	grpc.ClientStream
+}
+
+func (x *updateUpdateProgressClient) Recv() (*ProgressReply, error) {
+	m := new(ProgressReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // UpdateServer is the server API for Update service.
 // All implementations must embed UnimplementedUpdateServer
 // for forward compatibility
 type UpdateServer interface {
 	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	Version(context.Context, *emptypb.Empty) (*VersionReply, error)
 	Status(context.Context, *emptypb.Empty) (*StatusReply, error)
 	Update(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	UpdateOne(context.Context, *UpdateOneRequest) (*emptypb.Empty, error)
 	Stats(context.Context, *emptypb.Empty) (*StatsReply, error)
+	ResetStats(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
 	Drop(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	BrokenLinks(context.Context, *emptypb.Empty) (*BrokenLinksReply, error)
+	SetTags(context.Context, *SetTagsRequest) (*emptypb.Empty, error)
+	GetTags(context.Context, *TagsRequest) (*TagsReply, error)
+	// streams progress of the running update, it ends with the update
+	UpdateProgress(*emptypb.Empty, Update_UpdateProgressServer) error
 	mustEmbedUnimplementedUpdateServer()
 }
 
@@ -98,18 +200,39 @@ type UnimplementedUpdateServer struct {
 func (UnimplementedUpdateServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedUpdateServer) Version(context.Context, *emptypb.Empty) (*VersionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
 func (UnimplementedUpdateServer) Status(context.Context, *emptypb.Empty) (*StatusReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
 }
 func (UnimplementedUpdateServer) Update(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
 }
+func (UnimplementedUpdateServer) UpdateOne(context.Context, *UpdateOneRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOne not implemented")
+}
 func (UnimplementedUpdateServer) Stats(context.Context, *emptypb.Empty) (*StatsReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
 }
+func (UnimplementedUpdateServer) ResetStats(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetStats not implemented")
+}
 func (UnimplementedUpdateServer) Drop(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Drop not implemented")
 }
+func (UnimplementedUpdateServer) BrokenLinks(context.Context, *emptypb.Empty) (*BrokenLinksReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BrokenLinks not implemented")
+}
+func (UnimplementedUpdateServer) SetTags(context.Context, *SetTagsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTags not implemented")
+}
+func (UnimplementedUpdateServer) GetTags(context.Context, *TagsRequest) (*TagsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTags not implemented")
+}
+func (UnimplementedUpdateServer) UpdateProgress(*emptypb.Empty, Update_UpdateProgressServer) error {
+	return status.Errorf(codes.Unimplemented, "method UpdateProgress not implemented")
+}
 func (UnimplementedUpdateServer) mustEmbedUnimplementedUpdateServer() {}
 
 // UnsafeUpdateServer may be embedded to opt out of forward compatibility for this service.
@@ -141,6 +264,24 @@ func _Update_Ping_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Update_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdateServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/update.Update/Version",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdateServer).Version(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Update_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -177,6 +318,24 @@ func _Update_Update_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Update_UpdateOne_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdateServer).UpdateOne(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/update.Update/UpdateOne",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdateServer).UpdateOne(ctx, req.(*UpdateOneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Update_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -195,6 +354,24 @@ func _Update_Stats_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Update_ResetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdateServer).ResetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/update.Update/ResetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdateServer).ResetStats(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Update_Drop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -213,6 +390,81 @@ func _Update_Drop_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Update_BrokenLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdateServer).BrokenLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/update.Update/BrokenLinks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdateServer).BrokenLinks(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Update_SetTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdateServer).SetTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/update.Update/SetTags",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdateServer).SetTags(ctx, req.(*SetTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Update_GetTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UpdateServer).GetTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/update.Update/GetTags",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UpdateServer).GetTags(ctx, req.(*TagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Update_UpdateProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UpdateServer).UpdateProgress(m, &updateUpdateProgressServer{stream})
+}
+
+type Update_UpdateProgressServer interface {
+	Send(*ProgressReply) error
+	grpc.ServerStream
+}
+
+type updateUpdateProgressServer struct {
+	grpc.ServerStream
+}
+
+func (x *updateUpdateProgressServer) Send(m *ProgressReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Update_ServiceDesc is the grpc.ServiceDesc for Update service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -224,6 +476,10 @@ var Update_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Update_Ping_Handler,
 		},
+		{
+			MethodName: "Version",
+			Handler:    _Update_Version_Handler,
+		},
 		{
 			MethodName: "Status",
 			Handler:    _Update_Status_Handler,
@@ -232,15 +488,41 @@ var Update_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Update",
 			Handler:    _Update_Update_Handler,
 		},
+		{
+			MethodName: "UpdateOne",
+			Handler:    _Update_UpdateOne_Handler,
+		},
 		{
 			MethodName: "Stats",
 			Handler:    _Update_Stats_Handler,
 		},
+		{
+			MethodName: "ResetStats",
+			Handler:    _Update_ResetStats_Handler,
+		},
 		{
 			MethodName: "Drop",
 			Handler:    _Update_Drop_Handler,
 		},
+		{
+			MethodName: "BrokenLinks",
+			Handler:    _Update_BrokenLinks_Handler,
+		},
+		{
+			MethodName: "SetTags",
+			Handler:    _Update_SetTags_Handler,
+		},
+		{
+			MethodName: "GetTags",
+			Handler:    _Update_GetTags_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UpdateProgress",
+			Handler:       _Update_UpdateProgress_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/update/update.proto",
 }
@@ -0,0 +1,5 @@
+package update
+
+// Version is bumped on incompatible changes of the update proto,
+// clients compare it with the one reported by the service.
+const Version = 1
@@ -0,0 +1,5 @@
+package words
+
+// Version is bumped on incompatible changes of the words proto,
+// clients compare it with the one reported by the service.
+const Version = 1
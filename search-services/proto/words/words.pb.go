@@ -27,6 +27,8 @@ type WordsRequest struct {
 	unknownFields protoimpl.UnknownFields
 
 	Phrase string `protobuf:"bytes,1,opt,name=phrase,proto3" json:"phrase,omitempty"`
+	// ISO 639-1 code, english when empty
+	Language string `protobuf:"bytes,2,opt,name=language,proto3" json:"language,omitempty"`
 }
 
 func (x *WordsRequest) Reset() {
@@ -68,6 +70,13 @@ func (x *WordsRequest) GetPhrase() string {
 	return ""
 }
 
+func (x *WordsRequest) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
 type WordsReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -115,28 +124,251 @@ func (x *WordsReply) GetWords() []string {
 	return nil
 }
 
+type WordsBatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phrases []*WordsRequest `protobuf:"bytes,1,rep,name=phrases,proto3" json:"phrases,omitempty"`
+}
+
+func (x *WordsBatchRequest) Reset() {
+	*x = WordsBatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_words_words_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WordsBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordsBatchRequest) ProtoMessage() {}
+
+func (x *WordsBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordsBatchRequest.ProtoReflect.Descriptor instead.
+func (*WordsBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *WordsBatchRequest) GetPhrases() []*WordsRequest {
+	if x != nil {
+		return x.Phrases
+	}
+	return nil
+}
+
+// result of a single phrase, error is set instead of words when it failed
+type WordsBatchItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Words []string `protobuf:"bytes,1,rep,name=words,proto3" json:"words,omitempty"`
+	Error string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *WordsBatchItem) Reset() {
+	*x = WordsBatchItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_words_words_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WordsBatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordsBatchItem) ProtoMessage() {}
+
+func (x *WordsBatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordsBatchItem.ProtoReflect.Descriptor instead.
+func (*WordsBatchItem) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WordsBatchItem) GetWords() []string {
+	if x != nil {
+		return x.Words
+	}
+	return nil
+}
+
+func (x *WordsBatchItem) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type WordsBatchReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*WordsBatchItem `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+}
+
+func (x *WordsBatchReply) Reset() {
+	*x = WordsBatchReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_words_words_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WordsBatchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordsBatchReply) ProtoMessage() {}
+
+func (x *WordsBatchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordsBatchReply.ProtoReflect.Descriptor instead.
+func (*WordsBatchReply) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WordsBatchReply) GetResults() []*WordsBatchItem {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// version of the proto the service is built with
+type VersionReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version int64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *VersionReply) Reset() {
+	*x = VersionReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_words_words_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionReply) ProtoMessage() {}
+
+func (x *VersionReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_words_words_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionReply.ProtoReflect.Descriptor instead.
+func (*VersionReply) Descriptor() ([]byte, []int) {
+	return file_proto_words_words_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *VersionReply) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 var File_proto_words_words_proto protoreflect.FileDescriptor
 
 var file_proto_words_words_proto_rawDesc = []byte{
 	0x0a, 0x17, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x2f, 0x77, 0x6f,
 	0x72, 0x64, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73,
 	0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x26, 0x0a,
+	0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x42, 0x0a,
 	0x0c, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a,
 	0x06, 0x70, 0x68, 0x72, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70,
-	0x68, 0x72, 0x61, 0x73, 0x65, 0x22, 0x22, 0x0a, 0x0a, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x09, 0x52, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x32, 0x73, 0x0a, 0x05, 0x57, 0x6f, 0x72,
-	0x64, 0x73, 0x12, 0x38, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x00, 0x12, 0x30, 0x0a, 0x04,
-	0x4e, 0x6f, 0x72, 0x6d, 0x12, 0x13, 0x2e, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72,
-	0x64, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x77, 0x6f, 0x72, 0x64,
-	0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x1e,
-	0x5a, 0x1c, 0x79, 0x61, 0x64, 0x72, 0x6f, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6f, 0x75, 0x72,
-	0x73, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x68, 0x72, 0x61, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67,
+	0x65, 0x22, 0x22, 0x0a, 0x0a, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05,
+	0x77, 0x6f, 0x72, 0x64, 0x73, 0x22, 0x42, 0x0a, 0x11, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2d, 0x0a, 0x07, 0x70, 0x68,
+	0x72, 0x61, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x77, 0x6f,
+	0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x52, 0x07, 0x70, 0x68, 0x72, 0x61, 0x73, 0x65, 0x73, 0x22, 0x3c, 0x0a, 0x0e, 0x57, 0x6f, 0x72,
+	0x64, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x14, 0x0a, 0x05, 0x77,
+	0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x77, 0x6f, 0x72, 0x64,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x42, 0x0a, 0x0f, 0x57, 0x6f, 0x72, 0x64, 0x73,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x77, 0x6f,
+	0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x49, 0x74,
+	0x65, 0x6d, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x28, 0x0a, 0x0c, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x32, 0xee, 0x01, 0x0a, 0x05, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x12,
+	0x38, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a,
+	0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x00, 0x12, 0x38, 0x0a, 0x07, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x13, 0x2e, 0x77,
+	0x6f, 0x72, 0x64, 0x73, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x12, 0x30, 0x0a, 0x04, 0x4e, 0x6f, 0x72, 0x6d, 0x12, 0x13, 0x2e, 0x77, 0x6f,
+	0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x11, 0x2e, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x09, 0x4e, 0x6f, 0x72, 0x6d, 0x42, 0x61, 0x74,
+	0x63, 0x68, 0x12, 0x18, 0x2e, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x77,
+	0x6f, 0x72, 0x64, 0x73, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x2c, 0x5a, 0x2a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6c, 0x69, 0x79, 0x30, 0x61, 0x61, 0x79, 0x2f, 0x78, 0x6b, 0x63,
+	0x64, 0x2d, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x77,
+	0x6f, 0x72, 0x64, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -151,22 +383,32 @@ func file_proto_words_words_proto_rawDescGZIP() []byte {
 	return file_proto_words_words_proto_rawDescData
 }
 
-var file_proto_words_words_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_words_words_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_words_words_proto_goTypes = []interface{}{
-	(*WordsRequest)(nil),  // 0: words.WordsRequest
-	(*WordsReply)(nil),    // 1: words.WordsReply
-	(*emptypb.Empty)(nil), // 2: google.protobuf.Empty
+	(*WordsRequest)(nil),      // 0: words.WordsRequest
+	(*WordsReply)(nil),        // 1: words.WordsReply
+	(*WordsBatchRequest)(nil), // 2: words.WordsBatchRequest
+	(*WordsBatchItem)(nil),    // 3: words.WordsBatchItem
+	(*WordsBatchReply)(nil),   // 4: words.WordsBatchReply
+	(*VersionReply)(nil),      // 5: words.VersionReply
+	(*emptypb.Empty)(nil),     // 6: google.protobuf.Empty
 }
 var file_proto_words_words_proto_depIdxs = []int32{
-	2, // 0: words.Words.Ping:input_type -> google.protobuf.Empty
-	0, // 1: words.Words.Norm:input_type -> words.WordsRequest
-	2, // 2: words.Words.Ping:output_type -> google.protobuf.Empty
-	1, // 3: words.Words.Norm:output_type -> words.WordsReply
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: words.WordsBatchRequest.phrases:type_name -> words.WordsRequest
+	3, // 1: words.WordsBatchReply.results:type_name -> words.WordsBatchItem
+	6, // 2: words.Words.Ping:input_type -> google.protobuf.Empty
+	6, // 3: words.Words.Version:input_type -> google.protobuf.Empty
+	0, // 4: words.Words.Norm:input_type -> words.WordsRequest
+	2, // 5: words.Words.NormBatch:input_type -> words.WordsBatchRequest
+	6, // 6: words.Words.Ping:output_type -> google.protobuf.Empty
+	5, // 7: words.Words.Version:output_type -> words.VersionReply
+	1, // 8: words.Words.Norm:output_type -> words.WordsReply
+	4, // 9: words.Words.NormBatch:output_type -> words.WordsBatchReply
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_words_words_proto_init() }
@@ -199,6 +441,54 @@ func file_proto_words_words_proto_init() {
 				return nil
 			}
 		}
+		file_proto_words_words_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WordsBatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_words_words_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WordsBatchItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_words_words_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WordsBatchReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_words_words_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VersionReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -206,7 +496,7 @@ func file_proto_words_words_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_words_words_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
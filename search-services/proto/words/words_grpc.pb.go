@@ -20,8 +20,11 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type WordsClient interface {
 	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionReply, error)
 	// Send name, receive greeting
 	Norm(ctx context.Context, in *WordsRequest, opts ...grpc.CallOption) (*WordsReply, error)
+	// Results are in the order of phrases, each failing on its own
+	NormBatch(ctx context.Context, in *WordsBatchRequest, opts ...grpc.CallOption) (*WordsBatchReply, error)
 }
 
 type wordsClient struct {
@@ -41,6 +44,15 @@ func (c *wordsClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.
 	return out, nil
 }
 
+func (c *wordsClient) Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionReply, error) {
+	out := new(VersionReply)
+	err := c.cc.Invoke(ctx, "/words.Words/Version", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *wordsClient) Norm(ctx context.Context, in *WordsRequest, opts ...grpc.CallOption) (*WordsReply, error) {
 	out := new(WordsReply)
 	err := c.cc.Invoke(ctx, "/words.Words/Norm", in, out, opts...)
@@ -50,13 +62,25 @@ func (c *wordsClient) Norm(ctx context.Context, in *WordsRequest, opts ...grpc.C
 	return out, nil
 }
 
+func (c *wordsClient) NormBatch(ctx context.Context, in *WordsBatchRequest, opts ...grpc.CallOption) (*WordsBatchReply, error) {
+	out := new(WordsBatchReply)
+	err := c.cc.Invoke(ctx, "/words.Words/NormBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WordsServer is the server API for Words service.
 // All implementations must embed UnimplementedWordsServer
 // for forward compatibility
 type WordsServer interface {
 	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	Version(context.Context, *emptypb.Empty) (*VersionReply, error)
 	// Send name, receive greeting
 	Norm(context.Context, *WordsRequest) (*WordsReply, error)
+	// Results are in the order of phrases, each failing on its own
+	NormBatch(context.Context, *WordsBatchRequest) (*WordsBatchReply, error)
 	mustEmbedUnimplementedWordsServer()
 }
 
@@ -67,9 +91,15 @@ type UnimplementedWordsServer struct {
 func (UnimplementedWordsServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedWordsServer) Version(context.Context, *emptypb.Empty) (*VersionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
 func (UnimplementedWordsServer) Norm(context.Context, *WordsRequest) (*WordsReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Norm not implemented")
 }
+func (UnimplementedWordsServer) NormBatch(context.Context, *WordsBatchRequest) (*WordsBatchReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NormBatch not implemented")
+}
 func (UnimplementedWordsServer) mustEmbedUnimplementedWordsServer() {}
 
 // UnsafeWordsServer may be embedded to opt out of forward compatibility for this service.
@@ -101,6 +131,24 @@ func _Words_Ping_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Words_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WordsServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/words.Words/Version",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WordsServer).Version(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Words_Norm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(WordsRequest)
 	if err := dec(in); err != nil {
@@ -119,6 +167,24 @@ func _Words_Norm_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Words_NormBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WordsBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WordsServer).NormBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/words.Words/NormBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WordsServer).NormBatch(ctx, req.(*WordsBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Words_ServiceDesc is the grpc.ServiceDesc for Words service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -130,10 +196,18 @@ var Words_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Words_Ping_Handler,
 		},
+		{
+			MethodName: "Version",
+			Handler:    _Words_Version_Handler,
+		},
 		{
 			MethodName: "Norm",
 			Handler:    _Words_Norm_Handler,
 		},
+		{
+			MethodName: "NormBatch",
+			Handler:    _Words_NormBatch_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/words/words.proto",
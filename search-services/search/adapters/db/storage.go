@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
@@ -13,67 +16,233 @@ import (
 )
 
 type DB struct {
-	log  *slog.Logger
-	conn *sqlx.DB
+	log     *slog.Logger
+	conn    *sqlx.DB
+	replica *sqlx.DB
+	timeout time.Duration
 }
 
-func New(log *slog.Logger, address string) (*DB, error) {
+// New connects to the DB, every query is limited by timeout unless it is zero.
+func New(ctx context.Context, log *slog.Logger, address string, timeout time.Duration) (*DB, error) {
 
-	db, err := sqlx.Connect("pgx", address)
+	db, err := sqlx.ConnectContext(ctx, "pgx", address)
 	if err != nil {
 		log.Error("connection problem", "address", address, "error", err)
 		return nil, err
 	}
 
 	return &DB{
-		log:  log,
-		conn: db,
+		log:     log,
+		conn:    db,
+		timeout: timeout,
 	}, nil
 }
 
+// ConnectReplica sends searches, comics and last ID reads to a read replica,
+// falling back to the primary when the replica fails them.
+func (db *DB) ConnectReplica(ctx context.Context, address string) error {
+	replica, err := sqlx.ConnectContext(ctx, "pgx", address)
+	if err != nil {
+		return err
+	}
+	db.replica = replica
+	return nil
+}
+
+// read runs query on the replica if there is one, and on the primary
+// when there is not or the replica fails. A comics missing on the replica
+// may be not replicated yet, so it is looked up on the primary too.
+func (db *DB) read(ctx context.Context, query func(context.Context, *sqlx.DB) error) error {
+	if db.replica != nil {
+		err := db.query(ctx, db.replica, query)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			db.log.Warn("replica read failed, falling back to primary", "error", err)
+		}
+	}
+	return db.query(ctx, db.conn, query)
+}
+
+func (db *DB) query(ctx context.Context, conn *sqlx.DB, query func(context.Context, *sqlx.DB) error) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	return db.timedOut(ctx, query(ctx, conn))
+}
+
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.timeout)
+}
+
+// timedOut reports statements cancelled by the timeout as core.ErrTimeout.
+func (db *DB) timedOut(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: statement exceeded %s: %v", core.ErrTimeout, db.timeout, err)
+	}
+	return err
+}
+
+func (db *DB) Ping(ctx context.Context) error {
+	return db.conn.PingContext(ctx)
+}
+
 func (db *DB) Close() error {
+	if db.replica != nil {
+		if err := db.replica.Close(); err != nil {
+			db.log.Error("failed to close replica", "error", err)
+		}
+	}
 	return db.conn.Close()
 }
 
+// Search finds comics by keyword, a prefix wildcard like pyth* matches
+// comics with any keyword starting with it.
 func (db *DB) Search(ctx context.Context, keyword string) ([]int, error) {
+	query := "SELECT id FROM comics WHERE $1 = ANY(words)"
+	if prefix, ok := strings.CutSuffix(keyword, "*"); ok && prefix != "" {
+		query = `SELECT id FROM comics WHERE EXISTS (
+			SELECT 1 FROM unnest(words) AS word WHERE word LIKE $1 ESCAPE '\'
+		)`
+		keyword = likeEscaper.Replace(prefix) + "%"
+	}
 	var IDs []int
-	err := db.conn.SelectContext(
-		ctx, &IDs,
-		"SELECT id FROM comics WHERE $1 = ANY(words)",
-		keyword,
-	)
+	err := db.read(ctx, func(ctx context.Context, conn *sqlx.DB) error {
+		IDs = nil
+		return conn.SelectContext(ctx, &IDs, query, keyword)
+	})
 
 	return IDs, err
 }
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type Comics struct {
-	ID       int            `db:"id"`
-	URL      string         `db:"url"`
-	Title    string         `db:"title"`
-	Alt      string         `db:"alt"`
-	Keywords pq.StringArray `db:"words"`
+	ID        int            `db:"id"`
+	URL       string         `db:"url"`
+	Title     string         `db:"title"`
+	Alt       string         `db:"alt"`
+	Keywords  pq.StringArray `db:"words"`
+	Tags      pq.StringArray `db:"tags"`
+	UpdatedAt time.Time      `db:"updated_at"`
+	// Stems are only read for single comics
+	Stems pq.StringArray `db:"stems"`
+}
+
+func (c Comics) toCore() core.Comics {
+	return core.Comics{
+		ID: c.ID, URL: c.URL, Title: c.Title, Alt: c.Alt,
+		Keywords: c.Keywords, Tags: c.Tags, UpdatedAt: c.UpdatedAt, Stems: c.Stems,
+	}
 }
 
 func (db *DB) Get(ctx context.Context, id int) (core.Comics, error) {
 	var comics Comics
-	err := db.conn.GetContext(
-		ctx, &comics,
-		"SELECT id, url, title, alt, words FROM comics WHERE id = $1",
-		id,
-	)
+	err := db.read(ctx, func(ctx context.Context, conn *sqlx.DB) error {
+		return conn.GetContext(
+			ctx, &comics,
+			"SELECT id, url, title, alt, words, tags || categories AS tags, updated_at, stems FROM comics WHERE id = $1",
+			id,
+		)
+	})
 	if errors.Is(err, sql.ErrNoRows) {
 		err = core.ErrNotFound
 	}
 
-	return core.Comics{ID: comics.ID, URL: comics.URL, Title: comics.Title, Alt: comics.Alt, Keywords: comics.Keywords}, err
+	return comics.toCore(), err
+}
+
+type changedComics struct {
+	Comics
+	Deleted bool `db:"deleted"`
+}
+
+func (db *DB) Changes(
+	ctx context.Context, since time.Time, afterID, limit int, tombstones bool,
+) ([]core.Comics, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, url, title, alt, words, tags || categories AS tags, updated_at, false AS deleted FROM comics
+		WHERE (updated_at, id) > ($1, $3) ORDER BY updated_at, id LIMIT $2`
+	if tombstones {
+		query = `SELECT id, url, title, alt, words, tags || categories AS tags, updated_at, false AS deleted FROM comics
+		WHERE (updated_at, id) > ($1, $3)
+		UNION ALL
+		SELECT id, '', '', '', '{}', '{}', deleted_at, true FROM tombstones
+		WHERE (deleted_at, id) > ($1, $3)
+		ORDER BY updated_at, id LIMIT $2`
+	}
+	var rows []changedComics
+	err := db.conn.SelectContext(ctx, &rows, query, since, limit, afterID)
+	err = db.timedOut(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+
+	comics := make([]core.Comics, 0, len(rows))
+	for _, row := range rows {
+		c := row.toCore()
+		c.Deleted = row.Deleted
+		comics = append(comics, c)
+	}
+	return comics, nil
+}
+
+// ByTitle matches titles exactly, ignoring case.
+func (db *DB) ByTitle(ctx context.Context, title string) ([]core.Comics, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var rows []Comics
+	err := db.conn.SelectContext(
+		ctx, &rows,
+		`SELECT id, url, title, alt, words, tags || categories AS tags, updated_at FROM comics
+		WHERE lower(title) = lower($1) ORDER BY id`,
+		title,
+	)
+	err = db.timedOut(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+
+	comics := make([]core.Comics, 0, len(rows))
+	for _, row := range rows {
+		comics = append(comics, row.toCore())
+	}
+	return comics, nil
 }
 
 func (db *DB) LastID(ctx context.Context) (int, error) {
 	var ID int
+	err := db.read(ctx, func(ctx context.Context, conn *sqlx.DB) error {
+		return conn.GetContext(
+			ctx, &ID,
+			"SELECT coalesce(max(id), 0) FROM comics",
+		)
+	})
+
+	return ID, err
+}
+
+func (db *DB) Neighbours(ctx context.Context, id int) (int, int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var nav struct {
+		PrevID int `db:"prev_id"`
+		NextID int `db:"next_id"`
+	}
 	err := db.conn.GetContext(
-		ctx, &ID,
-		"SELECT coalesce(max(id), 0) FROM comics",
+		ctx, &nav,
+		`SELECT coalesce((SELECT max(id) FROM comics WHERE id < $1), 0) AS prev_id,
+		        coalesce((SELECT min(id) FROM comics WHERE id > $1), 0) AS next_id`,
+		id,
 	)
+	err = db.timedOut(ctx, err)
 
-	return ID, err
+	return nav.PrevID, nav.NextID, err
 }
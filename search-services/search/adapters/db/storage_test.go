@@ -0,0 +1,190 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liy0aay/xkcd-search/search/core"
+)
+
+func TestNew_StartupTimeout(t *testing.T) {
+	// accepts connections but never answers postgres handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		var held []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, conn := range held {
+					conn.Close()
+				}
+				return
+			}
+			held = append(held, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = New(ctx, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		"postgres://user:password@"+listener.Addr().String()+"/db?sslmode=disable", time.Second)
+
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestDB_StatementTimeout(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mock.ExpectQuery("SELECT coalesce").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+	db := &DB{
+		log:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		conn:    sqlx.NewDb(conn, "sqlmock"),
+		timeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err = db.LastID(context.Background())
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, core.ErrTimeout)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestDB_Changes(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("WHERE \\(updated_at, id\\) > \\(\\$1, \\$3\\) ORDER BY updated_at, id LIMIT \\$2").
+		WithArgs(since, 10, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "title", "alt", "words", "tags", "updated_at"}).
+			AddRow(3, "u", "t", "a", "{x}", "{}", since.Add(time.Hour)))
+
+	db := &DB{log: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), conn: sqlx.NewDb(conn, "sqlmock")}
+	changed, err := db.Changes(context.Background(), since, 5, 10, false)
+
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, 3, changed[0].ID)
+	assert.Equal(t, since.Add(time.Hour), changed[0].UpdatedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_ChangesTombstones(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("UNION ALL\\s+SELECT .* FROM tombstones\\s+WHERE \\(deleted_at, id\\) > \\(\\$1, \\$3\\)").
+		WithArgs(since, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "title", "alt", "words", "tags", "updated_at", "deleted"}).
+			AddRow(3, "u", "t", "a", "{x}", "{}", since.Add(time.Hour), false).
+			AddRow(7, "", "", "", "{}", "{}", since.Add(2*time.Hour), true))
+
+	db := &DB{log: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), conn: sqlx.NewDb(conn, "sqlmock")}
+	changed, err := db.Changes(context.Background(), since, 0, 10, true)
+
+	require.NoError(t, err)
+	require.Len(t, changed, 2)
+	assert.False(t, changed[0].Deleted)
+	assert.Equal(t, 7, changed[1].ID)
+	assert.True(t, changed[1].Deleted)
+	assert.Equal(t, since.Add(2*time.Hour), changed[1].UpdatedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_ByTitle(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mock.ExpectQuery("WHERE lower\\(title\\) = lower\\(\\$1\\)").
+		WithArgs("Python").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "title", "alt", "words", "tags", "updated_at"}).
+			AddRow(353, "u", "Python", "a", "{}", "{}", time.Now()))
+
+	db := &DB{log: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), conn: sqlx.NewDb(conn, "sqlmock")}
+	found, err := db.ByTitle(context.Background(), "Python")
+
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Python", found[0].Title)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_ReadsFromReplica(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primary.Close()
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+
+	db := &DB{
+		log:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		conn:    sqlx.NewDb(primary, "sqlmock"),
+		replica: sqlx.NewDb(replica, "sqlmock"),
+	}
+
+	replicaMock.ExpectQuery("SELECT coalesce").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	ID, err := db.LastID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 42, ID)
+
+	replicaMock.ExpectQuery("SELECT id FROM comics").
+		WillReturnError(errors.New("replica is down"))
+	primaryMock.ExpectQuery("SELECT id FROM comics").WithArgs("linux").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	IDs, err := db.Search(context.Background(), "linux")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, IDs)
+
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestDB_SearchPrefix(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mock.ExpectQuery("WHERE word LIKE \\$1").
+		WithArgs(`py\_th%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery("WHERE \\$1 = ANY\\(words\\)").
+		WithArgs("python").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	db := &DB{log: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), conn: sqlx.NewDb(conn, "sqlmock")}
+	IDs, err := db.Search(context.Background(), "py_th*")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, IDs)
+
+	IDs, err = db.Search(context.Background(), "python")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, IDs)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
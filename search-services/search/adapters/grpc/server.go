@@ -6,12 +6,18 @@ import (
 
 	searchpb "github.com/liy0aay/xkcd-search/proto/search"
 	"github.com/liy0aay/xkcd-search/search/core"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-const defaultLimit = 10
+const (
+	defaultLimit        = 10
+	defaultChangesLimit = 100
+)
 
 func NewServer(service core.Searcher) *Server {
 	return &Server{service: service}
@@ -22,34 +28,89 @@ type Server struct {
 	service core.Searcher
 }
 
-func (s *Server) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+func (s *Server) Ping(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := s.service.Ready(ctx); err != nil {
+		if errors.Is(err, core.ErrDegraded) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 	return nil, nil
 }
 
+func (s *Server) Version(_ context.Context, _ *emptypb.Empty) (*searchpb.VersionReply, error) {
+	return &searchpb.VersionReply{Version: searchpb.Version}, nil
+}
+
+func searchOptions(req *searchpb.SearchRequest) core.SearchOptions {
+	opts := core.SearchOptions{
+		Tag: req.Tag, Offset: int(req.Offset), MatchAll: req.MatchAll, Language: req.Language,
+		After:  core.Cursor{Score: int(req.AfterScore), ID: int(req.AfterId)},
+		Phrase: req.PhraseMode,
+	}
+	if len(req.Boosts) > 0 {
+		opts.Boosts = make(map[core.Field]int, len(req.Boosts))
+		for field, boost := range req.Boosts {
+			opts.Boosts[core.Field(field)] = int(boost)
+		}
+	}
+	return opts
+}
+
+func searchComics(results []core.Comics, withKeywords bool) []*searchpb.Comics {
+	comics := make([]*searchpb.Comics, 0, len(results))
+	for _, c := range results {
+		found := &searchpb.Comics{
+			Id:    int64(c.ID),
+			Url:   c.URL,
+			Title: c.Title,
+			Alt:   c.Alt,
+			Score: int64(c.Score),
+		}
+		if withKeywords {
+			found.Keywords = c.Keywords
+		}
+		comics = append(comics, found)
+	}
+	return comics
+}
+
+// stopWords is an InvalidArgument status with a reason telling a query of
+// stop words only apart from other bad arguments.
+func stopWords(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: searchpb.ReasonStopWords, Domain: searchpb.ErrorDomain,
+	})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
 func (s *Server) Search(
 	ctx context.Context, req *searchpb.SearchRequest,
 ) (*searchpb.SearchReply, error) {
 	if req.Limit == 0 {
 		req.Limit = defaultLimit
 	}
-	results, err := s.service.Search(ctx, req.Phrase, int(req.Limit))
+	result, err := s.service.Search(ctx, req.Phrase, int(req.Limit), searchOptions(req))
 	if err != nil {
 		if errors.Is(err, core.ErrNotFound) {
 			return nil, status.Error(codes.NotFound, "nothing found")
 		}
+		if errors.Is(err, core.ErrStopWords) {
+			return nil, stopWords(err)
+		}
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, err
 	}
-	comics := make([]*searchpb.Comics, 0, len(results))
-	for _, c := range results {
-		comics = append(comics, &searchpb.Comics{
-			Id:    int64(c.ID),
-			Url:   c.URL,
-			Title: c.Title,
-			Alt:   c.Alt,
-			Score: int64(c.Score),
-		})
-	}
-	return &searchpb.SearchReply{Comics: comics}, nil
+	return &searchpb.SearchReply{
+		Comics: searchComics(result.Comics, req.IncludeKeywords),
+		Total:  int64(result.Total), Errors: int64(result.Failed),
+	}, nil
 }
 
 func (s *Server) SearchIndex(
@@ -58,22 +119,178 @@ func (s *Server) SearchIndex(
 	if req.Limit == 0 {
 		req.Limit = defaultLimit
 	}
-	results, err := s.service.SearchIndex(ctx, req.Phrase, int(req.Limit))
+	result, err := s.service.SearchIndex(ctx, req.Phrase, int(req.Limit), searchOptions(req))
 	if err != nil {
 		if errors.Is(err, core.ErrNotFound) {
 			return nil, status.Error(codes.NotFound, "nothing found")
 		}
+		if errors.Is(err, core.ErrStopWords) {
+			return nil, stopWords(err)
+		}
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, err
 	}
-	comics := make([]*searchpb.Comics, 0, len(results))
-	for _, c := range results {
+	return &searchpb.SearchReply{
+		Comics: searchComics(result.Comics, req.IncludeKeywords),
+		Total:  int64(result.Total), Errors: int64(result.Failed),
+	}, nil
+}
+
+func (s *Server) ReindexComic(
+	ctx context.Context, req *searchpb.ComicRequest,
+) (*emptypb.Empty, error) {
+	if err := s.service.ReindexComic(ctx, int(req.Id)); err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "comics not found")
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *Server) ReindexRange(
+	ctx context.Context, req *searchpb.ReindexRangeRequest,
+) (*searchpb.ReindexRangeReply, error) {
+	comicsCount, err := s.service.ReindexRange(ctx, int(req.From), int(req.To))
+	if err != nil {
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, core.ErrAlreadyExists) {
+			return nil, status.Error(codes.AlreadyExists, "range reindex already runs")
+		}
+		return nil, err
+	}
+	return &searchpb.ReindexRangeReply{Comics: int64(comicsCount)}, nil
+}
+
+func (s *Server) Comic(
+	ctx context.Context, req *searchpb.ComicRequest,
+) (*searchpb.ComicReply, error) {
+	c, err := s.service.Comic(ctx, int(req.Id))
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "comics not found")
+		}
+		return nil, err
+	}
+	reply := &searchpb.ComicReply{
+		Comics: &searchpb.Comics{
+			Id:    int64(c.ID),
+			Url:   c.URL,
+			Title: c.Title,
+			Alt:   c.Alt,
+		},
+	}
+	if req.Nav {
+		prevID, nextID, err := s.service.Neighbours(ctx, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		reply.PrevId = int64(prevID)
+		reply.NextId = int64(nextID)
+	}
+	return reply, nil
+}
+
+func (s *Server) DocumentFrequency(
+	ctx context.Context, req *searchpb.DocumentFrequencyRequest,
+) (*searchpb.DocumentFrequencyReply, error) {
+	frequencies, err := s.service.DocumentFrequency(ctx, req.Term)
+	if err != nil {
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+	reply := &searchpb.DocumentFrequencyReply{Frequencies: make(map[string]int64, len(frequencies))}
+	for keyword, count := range frequencies {
+		reply.Frequencies[keyword] = int64(count)
+	}
+	return reply, nil
+}
+
+func (s *Server) BuildHistory(ctx context.Context, _ *emptypb.Empty) (*searchpb.BuildHistoryReply, error) {
+	history := s.service.BuildHistory(ctx)
+	runs := make([]*searchpb.BuildRun, 0, len(history))
+	for _, run := range history {
+		runs = append(runs, &searchpb.BuildRun{
+			StartedAt: timestamppb.New(run.StartedAt),
+			Duration:  durationpb.New(run.Duration),
+			Comics:    int64(run.Comics),
+			Error:     run.Error,
+		})
+	}
+	return &searchpb.BuildHistoryReply{Runs: runs}, nil
+}
+
+func (s *Server) IndexStats(ctx context.Context, _ *emptypb.Empty) (*searchpb.IndexStatsReply, error) {
+	stats := s.service.IndexStats(ctx)
+	reply := &searchpb.IndexStatsReply{
+		Comics:   int64(stats.Comics),
+		Keywords: int64(stats.Keywords),
+		Disabled: stats.Disabled,
+	}
+	if !stats.BuiltAt.IsZero() {
+		reply.BuiltAt = timestamppb.New(stats.BuiltAt)
+	}
+	return reply, nil
+}
+
+func (s *Server) LastID(ctx context.Context, _ *emptypb.Empty) (*searchpb.LastIDReply, error) {
+	ID, err := s.service.LastID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &searchpb.LastIDReply{Id: int64(ID)}, nil
+}
+
+func (s *Server) ByTitle(ctx context.Context, req *searchpb.TitleRequest) (*searchpb.SearchReply, error) {
+	found, err := s.service.ByTitle(ctx, req.Title)
+	if err != nil {
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, "empty title")
+		}
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "comics not found")
+		}
+		return nil, err
+	}
+	comics := make([]*searchpb.Comics, 0, len(found))
+	for _, c := range found {
 		comics = append(comics, &searchpb.Comics{
 			Id:    int64(c.ID),
 			Url:   c.URL,
 			Title: c.Title,
 			Alt:   c.Alt,
-			Score: int64(c.Score),
 		})
 	}
 	return &searchpb.SearchReply{Comics: comics}, nil
 }
+
+func (s *Server) Changes(ctx context.Context, req *searchpb.ChangesRequest) (*searchpb.ChangesReply, error) {
+	if req.Limit == 0 {
+		req.Limit = defaultChangesLimit
+	}
+	changed, err := s.service.Changes(ctx, req.Since.AsTime(), int(req.AfterId), int(req.Limit), req.Tombstones)
+	if err != nil {
+		if errors.Is(err, core.ErrBadArguments) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+	comics := make([]*searchpb.Comics, 0, len(changed))
+	for _, c := range changed {
+		comics = append(comics, &searchpb.Comics{
+			Id:        int64(c.ID),
+			Url:       c.URL,
+			Title:     c.Title,
+			Alt:       c.Alt,
+			UpdatedAt: timestamppb.New(c.UpdatedAt),
+			Deleted:   c.Deleted,
+		})
+	}
+	return &searchpb.ChangesReply{Comics: comics}, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -25,8 +26,8 @@ func TestSearch_NotFoundMappedToGRPC(t *testing.T) {
 	server := NewServer(mockSvc)
 
 	mockSvc.EXPECT().
-		Search(gomock.Any(), "abc", 10).
-		Return(nil, core.ErrNotFound)
+		Search(gomock.Any(), "abc", 10, core.SearchOptions{}).
+		Return(core.SearchResult{}, core.ErrNotFound)
 
 	_, err := server.Search(context.Background(), &searchpb.SearchRequest{
 		Phrase: "abc",
@@ -49,8 +50,8 @@ func TestSearch_UnexpectedErrorPassedThrough(t *testing.T) {
 	expectedErr := errors.New("boom")
 
 	mockSvc.EXPECT().
-		Search(gomock.Any(), "test", 10).
-		Return(nil, expectedErr)
+		Search(gomock.Any(), "test", 10, core.SearchOptions{}).
+		Return(core.SearchResult{}, expectedErr)
 
 	_, err := server.Search(context.Background(), &searchpb.SearchRequest{
 		Phrase: "test",
@@ -60,3 +61,146 @@ func TestSearch_UnexpectedErrorPassedThrough(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, expectedErr, err)
 }
+
+func TestComic_WithNavigation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		Comic(gomock.Any(), 405).
+		Return(core.Comics{ID: 405, Title: "Off By One"}, nil)
+	mockSvc.EXPECT().
+		Neighbours(gomock.Any(), 405).
+		Return(403, 406, nil)
+
+	reply, err := server.Comic(context.Background(), &searchpb.ComicRequest{Id: 405, Nav: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(405), reply.Comics.Id)
+	assert.Equal(t, int64(403), reply.PrevId)
+	assert.Equal(t, int64(406), reply.NextId)
+}
+
+func TestComic_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		Comic(gomock.Any(), 404).
+		Return(core.Comics{}, core.ErrNotFound)
+
+	_, err := server.Comic(context.Background(), &searchpb.ComicRequest{Id: 404, Nav: true})
+
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSearchIndex_Total(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		SearchIndex(gomock.Any(), "linux", 1, core.SearchOptions{Offset: 2}).
+		Return(core.SearchResult{Comics: []core.Comics{{ID: 3, Score: 1}}, Total: 12}, nil)
+
+	reply, err := server.SearchIndex(context.Background(), &searchpb.SearchRequest{
+		Phrase: "linux",
+		Limit:  1,
+		Offset: 2,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, reply.Comics, 1)
+	assert.EqualValues(t, 12, reply.Total)
+}
+
+func TestSearchIndex_KeywordsOnlyWhenAsked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		SearchIndex(gomock.Any(), "python", 10, core.SearchOptions{}).
+		Return(core.SearchResult{
+			Comics: []core.Comics{{ID: 353, Score: 1, Keywords: []string{"python", "fli"}}}, Total: 1,
+		}, nil).
+		Times(2)
+
+	reply, err := server.SearchIndex(context.Background(), &searchpb.SearchRequest{Phrase: "python", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, reply.Comics[0].Keywords)
+
+	reply, err = server.SearchIndex(context.Background(), &searchpb.SearchRequest{
+		Phrase: "python", Limit: 10, IncludeKeywords: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"python", "fli"}, reply.Comics[0].Keywords)
+}
+
+func TestIndexStats_Keywords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		IndexStats(gomock.Any()).
+		Return(core.IndexStats{Comics: 3000, Keywords: 12000})
+
+	reply, err := server.IndexStats(context.Background(), nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3000, reply.Comics)
+	assert.EqualValues(t, 12000, reply.Keywords)
+	assert.Nil(t, reply.BuiltAt)
+}
+
+func TestSearch_PartialResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		Search(gomock.Any(), "linux", 10, core.SearchOptions{}).
+		Return(core.SearchResult{Comics: []core.Comics{{ID: 1, Score: 1}}, Total: 2, Failed: 1}, nil)
+
+	reply, err := server.Search(context.Background(), &searchpb.SearchRequest{Phrase: "linux", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, reply.Comics, 1)
+	assert.EqualValues(t, 2, reply.Total)
+	assert.EqualValues(t, 1, reply.Errors)
+}
+
+func TestSearch_StopWords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockSearcher(ctrl)
+	server := NewServer(mockSvc)
+
+	mockSvc.EXPECT().
+		SearchIndex(gomock.Any(), "the and or", 10, core.SearchOptions{}).
+		Return(core.SearchResult{}, core.ErrStopWords)
+
+	_, err := server.SearchIndex(context.Background(), &searchpb.SearchRequest{Phrase: "the and or", Limit: 10})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, searchpb.ReasonStopWords, info.Reason)
+	assert.Equal(t, searchpb.ErrorDomain, info.Domain)
+}
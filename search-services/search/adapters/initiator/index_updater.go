@@ -2,19 +2,27 @@ package initiator
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
 	"time"
 
 	"github.com/liy0aay/xkcd-search/search/core"
 )
 
+// RunIndexUpdate rebuilds the index every period. With an index file set
+// the start loads it instead when it was built within maxAge, and every
+// build is saved to it.
 func RunIndexUpdate(
-	ctx context.Context, searcher core.Searcher, period time.Duration, log *slog.Logger,
+	ctx context.Context, searcher core.Searcher, period time.Duration,
+	indexFile string, maxAge time.Duration, log *slog.Logger,
 ) {
 	go func() {
 		// update on start
-		if err := searcher.BuildIndex(ctx); err != nil {
-			log.Error("failed to build index on start", "error", err)
+		if !loadIndex(searcher, indexFile, maxAge, log) {
+			if err := BuildIndex(ctx, searcher, indexFile, log); err != nil {
+				log.Error("failed to build index on start", "error", err)
+			}
 		}
 		ticker := time.NewTicker(period)
 		for {
@@ -23,10 +31,45 @@ func RunIndexUpdate(
 				log.Error("quit updater")
 			case <-ticker.C:
 				log.Info("run index update")
-				if err := searcher.BuildIndex(ctx); err != nil {
+				if err := BuildIndex(ctx, searcher, indexFile, log); err != nil {
 					log.Error("build index failed", "error", err)
 				}
 			}
 		}
 	}()
 }
+
+// BuildIndex rebuilds the index and saves it to indexFile when set, an
+// index failing to be saved is still served.
+func BuildIndex(ctx context.Context, searcher core.Searcher, indexFile string, log *slog.Logger) error {
+	if err := searcher.BuildIndex(ctx); err != nil {
+		return err
+	}
+	if indexFile == "" {
+		return nil
+	}
+	if err := searcher.SaveIndex(indexFile); err != nil {
+		log.Error("failed to save index", "path", indexFile, "error", err)
+	}
+	return nil
+}
+
+func loadIndex(searcher core.Searcher, indexFile string, maxAge time.Duration, log *slog.Logger) bool {
+	if indexFile == "" {
+		return false
+	}
+	builtAt, err := searcher.LoadIndex(indexFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Info("no saved index, building it", "path", indexFile)
+		return false
+	case err != nil:
+		log.Error("failed to load index, building it", "path", indexFile, "error", err)
+		return false
+	case time.Since(builtAt) > maxAge:
+		log.Info("saved index is stale, rebuilding it", "path", indexFile, "built at", builtAt)
+		return false
+	}
+	log.Info("loaded saved index", "path", indexFile, "built at", builtAt)
+	return true
+}
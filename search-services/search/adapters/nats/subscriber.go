@@ -2,63 +2,113 @@ package nats
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/liy0aay/xkcd-search/events"
+	"github.com/liy0aay/xkcd-search/metrics"
 	natslib "github.com/nats-io/nats.go"
 )
 
 type Subscriber struct {
-	nc   *natslib.Conn
-	log  *slog.Logger
-	subs []*natslib.Subscription
-	mu   sync.Mutex
+	nc      *natslib.Conn
+	log     *slog.Logger
+	subs    []*subscription
+	mu      sync.Mutex
+	metrics metrics.NATS
 }
 
-func New(log *slog.Logger, brokerAddress string) (*Subscriber, error) {
+type subscription struct {
+	msgCh chan *natslib.Msg
+	sub   *natslib.Subscription
+}
+
+func New(ctx context.Context, log *slog.Logger, brokerAddress string) (*Subscriber, error) {
+	s := &Subscriber{log: log}
 	opts := []natslib.Option{
 		natslib.Name("search-service"),
 		natslib.ReconnectHandler(func(_ *natslib.Conn) {
 			log.Info("NATS reconnected")
+			// the client replays its subscriptions on reconnect
+			s.metrics.Reconnected()
 		}),
 		natslib.DisconnectErrHandler(func(_ *natslib.Conn, err error) {
+			s.metrics.Disconnected(time.Now())
 			if err != nil {
 				log.Warn("NATS disconnected", "error", err)
 			} else {
 				log.Warn("NATS disconnected")
 			}
 		}),
+		natslib.ClosedHandler(func(_ *natslib.Conn) {
+			s.metrics.Closed()
+		}),
 		natslib.ErrorHandler(func(_ *natslib.Conn, _ *natslib.Subscription, err error) {
 			log.Error("NATS error", "error", err)
 		}),
 	}
 
+	if deadline, ok := ctx.Deadline(); ok {
+		// a zero timeout is no timeout at all, so a passed deadline fails at once
+		timeout := max(time.Until(deadline), 0)
+		if timeout == 0 {
+			return nil, fmt.Errorf("failed to connect to broker: %w", context.DeadlineExceeded)
+		}
+		opts = append(opts, natslib.Timeout(timeout))
+	}
+
 	nc, err := natslib.Connect(brokerAddress, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to broker: %v", err)
 	}
+	s.nc = nc
+	s.metrics.Connected()
 
-	return &Subscriber{nc: nc, log: log}, nil
+	return s, nil
 }
 
-func (s *Subscriber) SubscribeDBUpdateEvent(ctx context.Context) (<-chan struct{}, error) {
+// Metrics reports the broker connection state.
+func (s *Subscriber) Metrics() *metrics.NATS {
+	return &s.metrics
+}
+
+func (s *Subscriber) subscribe(topic string) (*subscription, error) {
 	msgCh := make(chan *natslib.Msg, 10)
-	sub, err := s.nc.ChanSubscribe(events.TopicDBUpdated, msgCh)
+	sub, err := s.nc.ChanSubscribe(topic, msgCh)
 	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe to %s: %v", events.TopicDBUpdated, err)
+		return nil, fmt.Errorf("failed to subscribe to %s: %v", topic, err)
 	}
 
+	subscription := &subscription{msgCh: msgCh, sub: sub}
 	s.mu.Lock()
-	s.subs = append(s.subs, sub)
+	s.subs = append(s.subs, subscription)
 	s.mu.Unlock()
+	return subscription, nil
+}
 
-	outCh := make(chan struct{})
+func (s *Subscriber) unsubscribe(sub *subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !sub.sub.IsValid() {
+		return nil
+	}
+	return sub.sub.Unsubscribe()
+}
+
+func (s *Subscriber) SubscribeDBUpdateEvent(ctx context.Context) (<-chan []int, error) {
+	sub, err := s.subscribe(events.TopicDBUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	outCh := make(chan []int)
 	go func() {
 		defer close(outCh)
 		defer func() {
-			if err := sub.Unsubscribe(); err != nil {
+			if err := s.unsubscribe(sub); err != nil {
 				s.log.Error("failed to unsubscribe from db update event", "error", err)
 			}
 		}()
@@ -68,12 +118,17 @@ func (s *Subscriber) SubscribeDBUpdateEvent(ctx context.Context) (<-chan struct{
 			case <-ctx.Done():
 				s.log.Debug("stopping db update event listener")
 				return
-			case msg := <-msgCh:
+			case msg := <-sub.msgCh:
 				if msg == nil {
 					return
 				}
 				s.log.Debug("received db update event", "data", string(msg.Data))
-				outCh <- struct{}{}
+				// events of older publishers carry no ids and mean a full update
+				var event events.DBUpdated
+				if err := json.Unmarshal(msg.Data, &event); err != nil {
+					s.log.Debug("db update event without ids", "error", err)
+				}
+				outCh <- event.IDs
 			}
 		}
 	}()
@@ -82,21 +137,16 @@ func (s *Subscriber) SubscribeDBUpdateEvent(ctx context.Context) (<-chan struct{
 }
 
 func (s *Subscriber) SubscribeDBDropEvent(ctx context.Context) (<-chan struct{}, error) {
-	msgCh := make(chan *natslib.Msg, 10)
-	sub, err := s.nc.ChanSubscribe(events.TopicDBDropped, msgCh)
+	sub, err := s.subscribe(events.TopicDBDropped)
 	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe to %s: %v", events.TopicDBDropped, err)
+		return nil, err
 	}
 
-	s.mu.Lock()
-	s.subs = append(s.subs, sub)
-	s.mu.Unlock()
-
 	outCh := make(chan struct{})
 	go func() {
 		defer close(outCh)
 		defer func() {
-			if err := sub.Unsubscribe(); err != nil {
+			if err := s.unsubscribe(sub); err != nil {
 				s.log.Error("failed to unsubscribe from db drop event", "error", err)
 			}
 		}()
@@ -106,7 +156,7 @@ func (s *Subscriber) SubscribeDBDropEvent(ctx context.Context) (<-chan struct{},
 			case <-ctx.Done():
 				s.log.Debug("stopping db drop event listener")
 				return
-			case msg := <-msgCh:
+			case msg := <-sub.msgCh:
 				if msg == nil {
 					return
 				}
@@ -119,7 +169,7 @@ func (s *Subscriber) SubscribeDBDropEvent(ctx context.Context) (<-chan struct{},
 	return outCh, nil
 }
 
-func (s *Subscriber) RunEventHandlers(ctx context.Context, updateHandler func(), dropHandler func()) error {
+func (s *Subscriber) RunEventHandlers(ctx context.Context, updateHandler func(ids []int), dropHandler func()) error {
 	updateCh, err := s.SubscribeDBUpdateEvent(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to db update events: %v", err)
@@ -136,9 +186,9 @@ func (s *Subscriber) RunEventHandlers(ctx context.Context, updateHandler func(),
 			case <-ctx.Done():
 				s.log.Debug("stopping event listener")
 				return
-			case <-updateCh:
-				s.log.Info("handling db update event")
-				updateHandler()
+			case ids := <-updateCh:
+				s.log.Info("handling db update event", "comics", len(ids))
+				updateHandler(ids)
 			case <-dropCh:
 				s.log.Info("handling db drop event")
 				dropHandler()
@@ -149,13 +199,13 @@ func (s *Subscriber) RunEventHandlers(ctx context.Context, updateHandler func(),
 	return nil
 }
 
-	func (s *Subscriber) Close() error {
+func (s *Subscriber) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for _, sub := range s.subs {
-		if sub != nil {
-			if err := sub.Unsubscribe(); err != nil {
+		if sub.sub.IsValid() {
+			if err := sub.sub.Unsubscribe(); err != nil {
 				s.log.Error("failed to unsubscribe", "error", err)
 			}
 		}
@@ -164,5 +214,6 @@ func (s *Subscriber) RunEventHandlers(ctx context.Context, updateHandler func(),
 
 	if s.nc != nil {
 		s.nc.Close()
-	}	return nil
+	}
+	return nil
 }
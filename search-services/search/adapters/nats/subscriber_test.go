@@ -0,0 +1,153 @@
+package nats
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/events"
+	"github.com/nats-io/nats-server/v2/server"
+	natslib "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func runServer(t *testing.T, port int) *server.Server {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: port, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("nats server: %v", err)
+	}
+	ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+	return ns
+}
+
+func publish(t *testing.T, url string) {
+	t.Helper()
+	publishData(t, url, []byte("{}"))
+}
+
+func publishData(t *testing.T, url string, data []byte) {
+	t.Helper()
+	nc, err := natslib.Connect(url)
+	if err != nil {
+		t.Fatalf("publisher connect: %v", err)
+	}
+	defer nc.Close()
+	if err := nc.Publish(events.TopicDBUpdated, data); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+}
+
+func receive(t *testing.T, ch <-chan []int) []int {
+	t.Helper()
+	select {
+	case ids := <-ch:
+		return ids
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+		return nil
+	}
+}
+
+func TestSubscriber_EventsFlowAfterReconnect(t *testing.T) {
+	port := freePort(t)
+	ns := runServer(t, port)
+	url := ns.ClientURL()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sub, err := New(context.Background(), log, url)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := sub.SubscribeDBUpdateEvent(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	publish(t, url)
+	receive(t, updates)
+
+	ns.Shutdown()
+	ns.WaitForShutdown()
+	ns = runServer(t, port)
+	defer ns.Shutdown()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for sub.Metrics().Reconnects() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber did not reconnect")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := sub.nc.Flush(); err != nil {
+		t.Fatalf("flush after reconnect: %v", err)
+	}
+
+	publish(t, url)
+	receive(t, updates)
+
+	m := sub.Metrics()
+	assert.True(t, m.IsConnected())
+	assert.EqualValues(t, 1, m.Reconnects())
+	assert.EqualValues(t, 1, m.Disconnects())
+	assert.False(t, m.LastDisconnect().IsZero())
+}
+
+func TestSubscriber_UpdateEventIDs(t *testing.T) {
+	ns := runServer(t, freePort(t))
+	defer ns.Shutdown()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sub, err := New(context.Background(), log, ns.ClientURL())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := sub.SubscribeDBUpdateEvent(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	publishData(t, ns.ClientURL(), []byte(`{"ids":[3,4]}`))
+	assert.Equal(t, []int{3, 4}, receive(t, updates))
+
+	publishData(t, ns.ClientURL(), []byte("updated"))
+	assert.Empty(t, receive(t, updates))
+}
+
+func TestNew_StartupDeadlinePassed(t *testing.T) {
+	ns := runServer(t, freePort(t))
+	defer ns.Shutdown()
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err := New(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), ns.ClientURL())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,44 @@
+package synonyms
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	wordsnorm "github.com/liy0aay/xkcd-search/words/words"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads a YAML map of terms to their synonyms and normalizes both sides
+// the same way comics keywords are normalized. When bidirectional is set,
+// every synonym also expands back to its term.
+func Load(path string, bidirectional bool) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read synonyms file: %v", err)
+	}
+	var raw map[string][]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse synonyms file: %v", err)
+	}
+
+	synonyms := make(map[string][]string)
+	add := func(from, to string) {
+		if from != to && !slices.Contains(synonyms[from], to) {
+			synonyms[from] = append(synonyms[from], to)
+		}
+	}
+	for term, list := range raw {
+		for _, from := range wordsnorm.Norm(term) {
+			for _, synonym := range list {
+				for _, to := range wordsnorm.Norm(synonym) {
+					add(from, to)
+					if bidirectional {
+						add(to, from)
+					}
+				}
+			}
+		}
+	}
+	return synonyms, nil
+}
@@ -0,0 +1,44 @@
+package synonyms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "synonyms.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_OneWay(t *testing.T) {
+	path := writeFile(t, "js: [javascript]\n")
+
+	synonyms, err := Load(path, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"javascript"}, synonyms["js"])
+	assert.Empty(t, synonyms["javascript"])
+}
+
+func TestLoad_Bidirectional(t *testing.T) {
+	path := writeFile(t, "js: [javascript]\n")
+
+	synonyms, err := Load(path, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"javascript"}, synonyms["js"])
+	assert.Equal(t, []string{"js"}, synonyms["javascript"])
+}
+
+func TestLoad_BadFile(t *testing.T) {
+	path := writeFile(t, "js: javascript: [\n")
+
+	_, err := Load(path, false)
+
+	require.Error(t, err)
+}
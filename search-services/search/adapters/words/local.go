@@ -0,0 +1,32 @@
+package words
+
+import (
+	"context"
+	"errors"
+
+	"github.com/liy0aay/xkcd-search/search/core"
+	wordsnorm "github.com/liy0aay/xkcd-search/words/words"
+)
+
+// Local normalizes phrases in process, without the words service.
+type Local struct{}
+
+func (Local) Norm(_ context.Context, phrase, language string) ([]string, error) {
+	words, err := wordsnorm.NormLanguage(phrase, language)
+	if errors.Is(err, wordsnorm.ErrUnsupportedLanguage) {
+		return nil, core.ErrBadArguments
+	}
+	return words, err
+}
+
+func (l Local) NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	results := make([][]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		words, err := l.Norm(ctx, phrase, language)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, words)
+	}
+	return results, nil
+}
@@ -7,13 +7,55 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+type Synonyms struct {
+	File          string `yaml:"file" env:"SYNONYMS_FILE"`
+	Bidirectional bool   `yaml:"bidirectional" env:"SYNONYMS_BIDIRECTIONAL" env-default:"true"`
+}
+
+// IndexBuild paces index builds, fast ones fetch comics as quick as the
+// DB serves them and background ones at most RPS comics per second.
+type IndexBuild struct {
+	Mode string `yaml:"mode" env:"INDEX_BUILD_MODE" env-default:"fast"`
+	RPS  int    `yaml:"rps" env:"INDEX_BUILD_RPS" env-default:"50"`
+}
+
+// BM25 ranks search results by BM25 instead of counting keyword findings,
+// keyword frequencies need the update service to store stems.
+type BM25 struct {
+	Enabled bool    `yaml:"enabled" env:"SEARCH_BM25" env-default:"false"`
+	K1      float64 `yaml:"k1" env:"SEARCH_BM25_K1" env-default:"1.2"`
+	B       float64 `yaml:"b" env:"SEARCH_BM25_B" env-default:"0.75"`
+}
+
 type Config struct {
-	LogLevel      string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
-	IndexTTL      time.Duration `yaml:"index_ttl" env:"INDEX_TTL" env-default:"24h"`
-	Address       string        `yaml:"search_address" env:"SEARCH_ADDRESS" env-default:"localhost:80"`
-	DBAddress     string        `yaml:"db_address" env:"DB_ADDRESS" env-default:"localhost:82"`
-	WordsAddress  string        `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"localhost:81"`
-	BrokerAddress string        `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
+	LogLevel       string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"STARTUP_TIMEOUT" env-default:"30s"`
+	IndexTTL       time.Duration `yaml:"index_ttl" env:"INDEX_TTL" env-default:"24h"`
+	Address        string        `yaml:"search_address" env:"SEARCH_ADDRESS" env-default:"localhost:80"`
+	DBAddress      string        `yaml:"db_address" env:"DB_ADDRESS" env-default:"localhost:82"`
+	DBTimeout      time.Duration `yaml:"db_timeout" env:"DB_TIMEOUT" env-default:"5s"`
+	// DBReplicaAddress serves reads when set, the primary takes over its failures.
+	DBReplicaAddress string        `yaml:"db_replica_address" env:"DB_REPLICA_ADDRESS"`
+	WordsAddress     string        `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"localhost:81"`
+	MetricsAddress   string        `yaml:"metrics_address" env:"METRICS_ADDRESS"`
+	BrokerAddress    string        `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
+	WordsFallback    bool          `yaml:"words_fallback" env:"WORDS_FALLBACK" env-default:"false"`
+	Synonyms         Synonyms      `yaml:"synonyms"`
+	BM25             BM25          `yaml:"bm25"`
+	IndexBuild       IndexBuild    `yaml:"index_build"`
+	EarlyTermination bool          `yaml:"early_termination" env:"SEARCH_EARLY_TERMINATION" env-default:"false"`
+	DisableIndex     bool          `yaml:"disable_index" env:"DISABLE_INDEX" env-default:"false"`
+	MaxTerms         int           `yaml:"max_terms" env:"SEARCH_MAX_TERMS" env-default:"0"`
+	SlowSearch       time.Duration `yaml:"slow_search_threshold" env:"SLOW_SEARCH_THRESHOLD" env-default:"0s"`
+	LenientSearch    bool          `yaml:"lenient_search" env:"SEARCH_LENIENT" env-default:"false"`
+	RejectStopWords  bool          `yaml:"reject_stop_words" env:"SEARCH_REJECT_STOP_WORDS" env-default:"false"`
+	// NGrams searches up to n adjacent words as keywords, zero disables,
+	// update must index the same n.
+	NGrams int `yaml:"ngrams" env:"INDEX_NGRAMS" env-default:"0"`
+	// IndexFile keeps the index between restarts when set, one built
+	// within IndexFileMaxAge is loaded on start instead of rebuilt.
+	IndexFile       string        `yaml:"index_file" env:"INDEX_FILE"`
+	IndexFileMaxAge time.Duration `yaml:"index_file_max_age" env:"INDEX_FILE_MAX_AGE" env-default:"1h"`
 }
 
 func MustLoad(configPath string) Config {
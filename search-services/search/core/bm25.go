@@ -0,0 +1,46 @@
+package core
+
+import "math"
+
+// bm25Scale keeps three decimals of BM25 values in integer scores.
+const bm25Scale = 1000
+
+// rankBM25 rescores comics by BM25 of the keywords against the index, a
+// boosted field multiplies the value of keywords found in it. Comics
+// missing in the index keep their scores.
+func (s *Service) rankBM25(scores map[int]int, keywords []string, boosts map[Field]int) map[int]int {
+	comicsCount := float64(s.index.Size())
+	avgLength := s.index.AvgLength()
+	if comicsCount == 0 || avgLength == 0 {
+		return scores
+	}
+	idf := make(map[string]float64, len(keywords))
+	for _, keyword := range keywords {
+		df := float64(s.index.DocFreq(keyword))
+		idf[keyword] = math.Log(1 + (comicsCount-df+0.5)/(df+0.5))
+	}
+
+	for ID := range scores {
+		comics, ok := s.index.Comic(ID)
+		if !ok {
+			continue
+		}
+		norm := s.k1 * (1 - s.b + s.b*float64(s.index.Length(ID))/avgLength)
+		var score float64
+		for _, keyword := range keywords {
+			tf := float64(s.index.TermFreq(ID, keyword))
+			if tf == 0 {
+				continue
+			}
+			weight := 1
+			for field, boost := range boosts {
+				if matches(comics.Fields[field], keyword) {
+					weight = max(weight, boost)
+				}
+			}
+			score += float64(weight) * idf[keyword] * tf * (s.k1 + 1) / (tf + norm)
+		}
+		scores[ID] = int(math.Round(score * bm25Scale))
+	}
+	return scores
+}
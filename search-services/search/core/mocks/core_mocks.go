@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	core "github.com/liy0aay/xkcd-search/search/core"
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +42,20 @@ func (m *MockSearcher) EXPECT() *MockSearcherMockRecorder {
 	return m.recorder
 }
 
+// BuildHistory mocks base method.
+func (m *MockSearcher) BuildHistory(ctx context.Context) []core.BuildRun {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BuildHistory", ctx)
+	ret0, _ := ret[0].([]core.BuildRun)
+	return ret0
+}
+
+// BuildHistory indicates an expected call of BuildHistory.
+func (mr *MockSearcherMockRecorder) BuildHistory(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildHistory", reflect.TypeOf((*MockSearcher)(nil).BuildHistory), ctx)
+}
+
 // BuildIndex mocks base method.
 func (m *MockSearcher) BuildIndex(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -55,34 +70,225 @@ func (mr *MockSearcherMockRecorder) BuildIndex(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildIndex", reflect.TypeOf((*MockSearcher)(nil).BuildIndex), ctx)
 }
 
-// Search mocks base method.
-func (m *MockSearcher) Search(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
+// ByTitle mocks base method.
+func (m *MockSearcher) ByTitle(ctx context.Context, title string) ([]core.Comics, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Search", ctx, phrase, limit)
+	ret := m.ctrl.Call(m, "ByTitle", ctx, title)
 	ret0, _ := ret[0].([]core.Comics)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// ByTitle indicates an expected call of ByTitle.
+func (mr *MockSearcherMockRecorder) ByTitle(ctx, title any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByTitle", reflect.TypeOf((*MockSearcher)(nil).ByTitle), ctx, title)
+}
+
+// Changes mocks base method.
+func (m *MockSearcher) Changes(ctx context.Context, since time.Time, afterID, limit int, tombstones bool) ([]core.Comics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", ctx, since, afterID, limit, tombstones)
+	ret0, _ := ret[0].([]core.Comics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockSearcherMockRecorder) Changes(ctx, since, afterID, limit, tombstones any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockSearcher)(nil).Changes), ctx, since, afterID, limit, tombstones)
+}
+
+// ClearIndex mocks base method.
+func (m *MockSearcher) ClearIndex(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearIndex", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearIndex indicates an expected call of ClearIndex.
+func (mr *MockSearcherMockRecorder) ClearIndex(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearIndex", reflect.TypeOf((*MockSearcher)(nil).ClearIndex), ctx)
+}
+
+// Comic mocks base method.
+func (m *MockSearcher) Comic(ctx context.Context, ID int) (core.Comics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Comic", ctx, ID)
+	ret0, _ := ret[0].(core.Comics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Comic indicates an expected call of Comic.
+func (mr *MockSearcherMockRecorder) Comic(ctx, ID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Comic", reflect.TypeOf((*MockSearcher)(nil).Comic), ctx, ID)
+}
+
+// DocumentFrequency mocks base method.
+func (m *MockSearcher) DocumentFrequency(ctx context.Context, term string) (map[string]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DocumentFrequency", ctx, term)
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DocumentFrequency indicates an expected call of DocumentFrequency.
+func (mr *MockSearcherMockRecorder) DocumentFrequency(ctx, term any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DocumentFrequency", reflect.TypeOf((*MockSearcher)(nil).DocumentFrequency), ctx, term)
+}
+
+// IndexStats mocks base method.
+func (m *MockSearcher) IndexStats(ctx context.Context) core.IndexStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IndexStats", ctx)
+	ret0, _ := ret[0].(core.IndexStats)
+	return ret0
+}
+
+// IndexStats indicates an expected call of IndexStats.
+func (mr *MockSearcherMockRecorder) IndexStats(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IndexStats", reflect.TypeOf((*MockSearcher)(nil).IndexStats), ctx)
+}
+
+// LastID mocks base method.
+func (m *MockSearcher) LastID(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastID", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastID indicates an expected call of LastID.
+func (mr *MockSearcherMockRecorder) LastID(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastID", reflect.TypeOf((*MockSearcher)(nil).LastID), ctx)
+}
+
+// LoadIndex mocks base method.
+func (m *MockSearcher) LoadIndex(path string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadIndex", path)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadIndex indicates an expected call of LoadIndex.
+func (mr *MockSearcherMockRecorder) LoadIndex(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadIndex", reflect.TypeOf((*MockSearcher)(nil).LoadIndex), path)
+}
+
+// Neighbours mocks base method.
+func (m *MockSearcher) Neighbours(ctx context.Context, ID int) (int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Neighbours", ctx, ID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Neighbours indicates an expected call of Neighbours.
+func (mr *MockSearcherMockRecorder) Neighbours(ctx, ID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Neighbours", reflect.TypeOf((*MockSearcher)(nil).Neighbours), ctx, ID)
+}
+
+// Ready mocks base method.
+func (m *MockSearcher) Ready(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ready", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ready indicates an expected call of Ready.
+func (mr *MockSearcherMockRecorder) Ready(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ready", reflect.TypeOf((*MockSearcher)(nil).Ready), ctx)
+}
+
+// ReindexComic mocks base method.
+func (m *MockSearcher) ReindexComic(ctx context.Context, ID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReindexComic", ctx, ID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReindexComic indicates an expected call of ReindexComic.
+func (mr *MockSearcherMockRecorder) ReindexComic(ctx, ID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReindexComic", reflect.TypeOf((*MockSearcher)(nil).ReindexComic), ctx, ID)
+}
+
+// ReindexRange mocks base method.
+func (m *MockSearcher) ReindexRange(ctx context.Context, from, to int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReindexRange", ctx, from, to)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReindexRange indicates an expected call of ReindexRange.
+func (mr *MockSearcherMockRecorder) ReindexRange(ctx, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReindexRange", reflect.TypeOf((*MockSearcher)(nil).ReindexRange), ctx, from, to)
+}
+
+// SaveIndex mocks base method.
+func (m *MockSearcher) SaveIndex(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveIndex", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveIndex indicates an expected call of SaveIndex.
+func (mr *MockSearcherMockRecorder) SaveIndex(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIndex", reflect.TypeOf((*MockSearcher)(nil).SaveIndex), path)
+}
+
+// Search mocks base method.
+func (m *MockSearcher) Search(ctx context.Context, phrase string, limit int, opts core.SearchOptions) (core.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, phrase, limit, opts)
+	ret0, _ := ret[0].(core.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // Search indicates an expected call of Search.
-func (mr *MockSearcherMockRecorder) Search(ctx, phrase, limit any) *gomock.Call {
+func (mr *MockSearcherMockRecorder) Search(ctx, phrase, limit, opts any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockSearcher)(nil).Search), ctx, phrase, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockSearcher)(nil).Search), ctx, phrase, limit, opts)
 }
 
 // SearchIndex mocks base method.
-func (m *MockSearcher) SearchIndex(ctx context.Context, phrase string, limit int) ([]core.Comics, error) {
+func (m *MockSearcher) SearchIndex(ctx context.Context, phrase string, limit int, opts core.SearchOptions) (core.SearchResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchIndex", ctx, phrase, limit)
-	ret0, _ := ret[0].([]core.Comics)
+	ret := m.ctrl.Call(m, "SearchIndex", ctx, phrase, limit, opts)
+	ret0, _ := ret[0].(core.SearchResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SearchIndex indicates an expected call of SearchIndex.
-func (mr *MockSearcherMockRecorder) SearchIndex(ctx, phrase, limit any) *gomock.Call {
+func (mr *MockSearcherMockRecorder) SearchIndex(ctx, phrase, limit, opts any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchIndex", reflect.TypeOf((*MockSearcher)(nil).SearchIndex), ctx, phrase, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchIndex", reflect.TypeOf((*MockSearcher)(nil).SearchIndex), ctx, phrase, limit, opts)
 }
 
 // MockDB is a mock of DB interface.
@@ -109,6 +315,36 @@ func (m *MockDB) EXPECT() *MockDBMockRecorder {
 	return m.recorder
 }
 
+// ByTitle mocks base method.
+func (m *MockDB) ByTitle(ctx context.Context, title string) ([]core.Comics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ByTitle", ctx, title)
+	ret0, _ := ret[0].([]core.Comics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ByTitle indicates an expected call of ByTitle.
+func (mr *MockDBMockRecorder) ByTitle(ctx, title any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByTitle", reflect.TypeOf((*MockDB)(nil).ByTitle), ctx, title)
+}
+
+// Changes mocks base method.
+func (m *MockDB) Changes(ctx context.Context, since time.Time, afterID, limit int, tombstones bool) ([]core.Comics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", ctx, since, afterID, limit, tombstones)
+	ret0, _ := ret[0].([]core.Comics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockDBMockRecorder) Changes(ctx, since, afterID, limit, tombstones any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockDB)(nil).Changes), ctx, since, afterID, limit, tombstones)
+}
+
 // Get mocks base method.
 func (m *MockDB) Get(ctx context.Context, ID int) (core.Comics, error) {
 	m.ctrl.T.Helper()
@@ -139,6 +375,36 @@ func (mr *MockDBMockRecorder) LastID(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastID", reflect.TypeOf((*MockDB)(nil).LastID), ctx)
 }
 
+// Neighbours mocks base method.
+func (m *MockDB) Neighbours(ctx context.Context, ID int) (int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Neighbours", ctx, ID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Neighbours indicates an expected call of Neighbours.
+func (mr *MockDBMockRecorder) Neighbours(ctx, ID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Neighbours", reflect.TypeOf((*MockDB)(nil).Neighbours), ctx, ID)
+}
+
+// Ping mocks base method.
+func (m *MockDB) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockDBMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDB)(nil).Ping), ctx)
+}
+
 // Search mocks base method.
 func (m *MockDB) Search(ctx context.Context, keyword string) ([]int, error) {
 	m.ctrl.T.Helper()
@@ -179,18 +445,33 @@ func (m *MockWords) EXPECT() *MockWordsMockRecorder {
 }
 
 // Norm mocks base method.
-func (m *MockWords) Norm(ctx context.Context, phrase string) ([]string, error) {
+func (m *MockWords) Norm(ctx context.Context, phrase, language string) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Norm", ctx, phrase)
+	ret := m.ctrl.Call(m, "Norm", ctx, phrase, language)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Norm indicates an expected call of Norm.
-func (mr *MockWordsMockRecorder) Norm(ctx, phrase any) *gomock.Call {
+func (mr *MockWordsMockRecorder) Norm(ctx, phrase, language any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Norm", reflect.TypeOf((*MockWords)(nil).Norm), ctx, phrase, language)
+}
+
+// NormBatch mocks base method.
+func (m *MockWords) NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NormBatch", ctx, phrases, language)
+	ret0, _ := ret[0].([][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NormBatch indicates an expected call of NormBatch.
+func (mr *MockWordsMockRecorder) NormBatch(ctx, phrases, language any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Norm", reflect.TypeOf((*MockWords)(nil).Norm), ctx, phrase)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NormBatch", reflect.TypeOf((*MockWords)(nil).NormBatch), ctx, phrases, language)
 }
 
 // MockEventSubscriber is a mock of EventSubscriber interface.
@@ -247,10 +528,10 @@ func (mr *MockEventSubscriberMockRecorder) SubscribeDBDropEvent(ctx any) *gomock
 }
 
 // SubscribeDBUpdateEvent mocks base method.
-func (m *MockEventSubscriber) SubscribeDBUpdateEvent(ctx context.Context) (<-chan struct{}, error) {
+func (m *MockEventSubscriber) SubscribeDBUpdateEvent(ctx context.Context) (<-chan []int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SubscribeDBUpdateEvent", ctx)
-	ret0, _ := ret[0].(<-chan struct{})
+	ret0, _ := ret[0].(<-chan []int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1,46 +1,329 @@
 package core
 
 import (
+	"maps"
 	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/ngrams"
 )
 
 type Comics struct {
-	ID       int
-	URL      string
-	Title    string
-	Alt      string
-	Keywords []string
-	Score    int
+	ID        int
+	URL       string
+	Title     string
+	Alt       string
+	Keywords  []string
+	Tags      []string
+	Score     int
+	UpdatedAt time.Time
+	// Deleted marks a tombstone of a removed comics, only ID and UpdatedAt are set.
+	Deleted bool
+	// Fields keeps normalized keywords per field for boosted scoring.
+	Fields map[Field][]string
+	// Stems are the normalized words in order, for phrase searches.
+	Stems []string
+}
+
+// SearchResult is a page of found comics and the Total of matching ones
+// regardless of paging. Failed matching comics could not be fetched by a
+// lenient search and are missing from the page.
+type SearchResult struct {
+	Comics []Comics
+	Total  int
+	Failed int
+}
+
+// Field is a part of comics that can be boosted in searches.
+type Field string
+
+const (
+	FieldTitle Field = "title"
+	FieldAlt   Field = "alt"
+)
+
+// SearchOptions restrict search results, MatchAll keeps only comics
+// found by every query keyword.
+type SearchOptions struct {
+	Tag      string
+	Offset   int
+	MatchAll bool
+	Language string
+	// After continues results strictly after the cursor, when set.
+	After Cursor
+	// Boosts weight keywords found in a field, unboosted matches weigh 1.
+	Boosts map[Field]int
+	// Phrase keeps only comics with the query words next to each other, in order.
+	Phrase bool
+}
+
+// Cursor marks a result position, results are ordered by score
+// descending and then by ID ascending.
+type Cursor struct {
+	Score int
+	ID    int
+}
+
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// before tells whether a result of score and ID is at or before the cursor.
+func (c Cursor) before(score, ID int) bool {
+	return score > c.Score || score == c.Score && ID <= c.ID
 }
 
+type BuildRun struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Comics    int
+	Error     string
+}
+
+// IndexStats tells how many comics and unique keywords are indexed and
+// when the index was last built successfully, BuiltAt is zero before the
+// first build.
+type IndexStats struct {
+	Comics   int
+	Keywords int
+	BuiltAt  time.Time
+	Disabled bool
+}
+
+// Index keeps comics metadata next to the keywords so it can serve
+// searches without the DB.
 type Index struct {
-	index map[string][]int
+	index  map[string][]int
+	comics map[int]Comics
+	// docs counts comics per keyword, length the terms of all comics.
+	docs   map[string]int
+	length int
+	// terms counts occurrences of each keyword per comics.
+	terms map[int]map[string]int
 	lock  sync.RWMutex
 }
 
 func NewIndex() *Index {
 	return &Index{
-		index: make(map[string][]int),
+		index:  make(map[string][]int),
+		comics: make(map[int]Comics),
+		docs:   make(map[string]int),
+		terms:  make(map[int]map[string]int),
 	}
 }
 
 func (i *Index) Clear() {
 	i.lock.Lock()
 	i.index = make(map[string][]int)
+	i.comics = make(map[int]Comics)
+	i.docs = make(map[string]int)
+	i.terms = make(map[int]map[string]int)
+	i.length = 0
+	i.lock.Unlock()
+}
+
+// Replace swaps in the contents of a separately built index at once, so
+// searches never see a partial one. other must not be used afterwards.
+func (i *Index) Replace(other *Index) {
+	other.lock.Lock()
+	defer other.lock.Unlock()
+	i.lock.Lock()
+	i.index, i.comics, i.docs, i.length = other.index, other.comics, other.docs, other.length
+	i.terms = other.terms
 	i.lock.Unlock()
 }
 
-func (i *Index) Put(id int, keywords []string) {
+// Put adds comics to the index, replacing postings of a previous version.
+func (i *Index) Put(comics Comics) {
 	i.lock.Lock()
-	for _, keyword := range keywords {
-		i.index[keyword] = append(i.index[keyword], id)
+	i.remove(comics.ID)
+	for _, keyword := range comics.Keywords {
+		i.index[keyword] = append(i.index[keyword], comics.ID)
 	}
+	for _, keyword := range distinct(comics.Keywords) {
+		i.docs[keyword]++
+	}
+	terms := termCounts(comics)
+	for _, count := range terms {
+		i.length += count
+	}
+	i.terms[comics.ID] = terms
+	i.comics[comics.ID] = comics
+	i.lock.Unlock()
+}
+
+// Delete removes comics from the index, the stored keywords of each
+// comics serve as the reverse map to its posting lists.
+func (i *Index) Delete(id int) {
+	i.lock.Lock()
+	i.remove(id)
 	i.lock.Unlock()
 }
 
+// remove drops id from the posting lists of its indexed keywords, the
+// caller must hold the write lock.
+func (i *Index) remove(id int) {
+	old, ok := i.comics[id]
+	if !ok {
+		return
+	}
+	for _, keyword := range old.Keywords {
+		ids := slices.DeleteFunc(i.index[keyword], func(other int) bool { return other == id })
+		if len(ids) == 0 {
+			delete(i.index, keyword)
+			continue
+		}
+		i.index[keyword] = ids
+	}
+	for _, keyword := range distinct(old.Keywords) {
+		if i.docs[keyword]--; i.docs[keyword] <= 0 {
+			delete(i.docs, keyword)
+		}
+	}
+	for _, count := range i.terms[id] {
+		i.length -= count
+	}
+	delete(i.terms, id)
+	delete(i.comics, id)
+}
+
+func (i *Index) Comic(id int) (Comics, bool) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	comics, ok := i.comics[id]
+	return comics, ok
+}
+
+// All returns every indexed comics.
+func (i *Index) All() []Comics {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return slices.Collect(maps.Values(i.comics))
+}
+
+func (i *Index) Size() int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return len(i.comics)
+}
+
+// Keywords counts the unique keywords with postings.
+func (i *Index) Keywords() int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return len(i.index)
+}
+
+// Get returns comics indexed under keyword, a prefix wildcard like pyth*
+// gets the union of all keywords starting with it.
 func (i *Index) Get(keyword string) []int {
 	i.lock.RLock()
 	defer i.lock.RUnlock()
+	if isPrefix(keyword) {
+		return i.scan(strings.TrimSuffix(keyword, "*"))
+	}
 	return slices.Clone(i.index[keyword])
 }
+
+func (i *Index) Count(keyword string) int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if isPrefix(keyword) {
+		return len(i.scan(strings.TrimSuffix(keyword, "*")))
+	}
+	return len(i.index[keyword])
+}
+
+// DocFreq counts comics containing keyword, a prefix wildcard counts
+// comics containing any keyword starting with it.
+func (i *Index) DocFreq(keyword string) int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if isPrefix(keyword) {
+		return len(i.scan(strings.TrimSuffix(keyword, "*")))
+	}
+	return i.docs[keyword]
+}
+
+// TermFreq counts occurrences of keyword in comics id, a prefix wildcard
+// counts the keywords starting with it.
+func (i *Index) TermFreq(id int, keyword string) int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	prefix, wildcard := strings.CutSuffix(keyword, "*")
+	if !wildcard {
+		return i.terms[id][keyword]
+	}
+	var count int
+	for term, n := range i.terms[id] {
+		if strings.HasPrefix(term, prefix) {
+			count += n
+		}
+	}
+	return count
+}
+
+// Length counts the terms of comics id.
+func (i *Index) Length(id int) int {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	var length int
+	for _, count := range i.terms[id] {
+		length += count
+	}
+	return length
+}
+
+// AvgLength is the mean number of terms of indexed comics.
+func (i *Index) AvgLength() float64 {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if len(i.comics) == 0 {
+		return 0
+	}
+	return float64(i.length) / float64(len(i.comics))
+}
+
+// termCounts counts occurrences of every keyword of comics in its stems,
+// n-gram keywords by their runs of stems. Keywords missing in the stems,
+// as for comics stored without them, count once.
+func termCounts(comics Comics) map[string]int {
+	size := 1
+	for _, keyword := range comics.Keywords {
+		size = max(size, strings.Count(keyword, " ")+1)
+	}
+	found := make(map[string]int, len(comics.Stems))
+	for _, stem := range comics.Stems {
+		found[stem]++
+	}
+	for _, gram := range ngrams.Join(comics.Stems, size) {
+		found[gram]++
+	}
+	counts := make(map[string]int, len(comics.Keywords))
+	for _, keyword := range comics.Keywords {
+		counts[keyword] = max(found[keyword], 1)
+	}
+	return counts
+}
+
+func distinct(keywords []string) []string {
+	unique := slices.Clone(keywords)
+	slices.Sort(unique)
+	return slices.Compact(unique)
+}
+
+// scan collects comics of keys starting with prefix, the caller must hold the read lock.
+func (i *Index) scan(prefix string) []int {
+	found := make(map[int]struct{})
+	for key, ids := range i.index {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for _, id := range ids {
+			found[id] = struct{}{}
+		}
+	}
+	return slices.Sorted(maps.Keys(found))
+}
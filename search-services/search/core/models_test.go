@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_Delete(t *testing.T) {
+	index := NewIndex()
+	index.Put(Comics{ID: 1, Keywords: []string{"happy", "year"}})
+	index.Put(Comics{ID: 2, Keywords: []string{"year", "new"}})
+
+	index.Delete(1)
+
+	assert.Empty(t, index.Get("happy"))
+	assert.Equal(t, 0, index.Count("happy"))
+	assert.Equal(t, []int{2}, index.Get("year"))
+	assert.Equal(t, []int{2}, index.Get("new"))
+	_, ok := index.Comic(1)
+	assert.False(t, ok)
+	assert.Equal(t, 1, index.Size())
+}
+
+func TestIndex_DeleteUnknown(t *testing.T) {
+	index := NewIndex()
+	index.Put(Comics{ID: 1, Keywords: []string{"happy"}})
+
+	index.Delete(42)
+
+	assert.Equal(t, []int{1}, index.Get("happy"))
+	assert.Equal(t, 1, index.Size())
+}
+
+func TestIndex_DocFreq(t *testing.T) {
+	index := NewIndex()
+	index.Put(Comics{ID: 1, Keywords: []string{"happy", "year"}, Stems: []string{"happy", "happy", "year"}})
+	index.Put(Comics{ID: 2, Keywords: []string{"year"}})
+
+	assert.Equal(t, 2, index.TermFreq(1, "happy"))
+	assert.Equal(t, 2, index.TermFreq(1, "ha*"))
+	assert.Equal(t, 1, index.TermFreq(2, "year"), "comics without stems count keywords once")
+	assert.Equal(t, 1, index.DocFreq("happy"))
+	assert.Equal(t, 2, index.DocFreq("year"))
+	assert.Equal(t, 1, index.DocFreq("ha*"))
+	assert.InDelta(t, 2.0, index.AvgLength(), 1e-9)
+
+	index.Delete(1)
+
+	assert.Equal(t, 0, index.DocFreq("happy"))
+	assert.Equal(t, 1, index.DocFreq("year"))
+	assert.InDelta(t, 1.0, index.AvgLength(), 1e-9)
+}
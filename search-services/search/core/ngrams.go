@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"slices"
+
+	"github.com/liy0aay/xkcd-search/ngrams"
+)
+
+// ordered returns the phrase stems in order when n-grams or a phrase
+// search need them.
+func (s *Service) ordered(ctx context.Context, phrase string, opts SearchOptions) ([]string, error) {
+	if s.ngrams < 2 && !opts.Phrase {
+		return nil, nil
+	}
+	return s.stems(ctx, phrase, opts.Language)
+}
+
+// stems normalizes the phrase terms one by one in a batch to keep them in
+// order, prefix wildcards are left out.
+func (s *Service) stems(ctx context.Context, phrase, language string) ([]string, error) {
+	terms, _ := parseQuery(phrase)
+	terms, _ = splitPrefixes(terms)
+	normalized, err := s.normalizeBatch(ctx, ngrams.Tokens(terms), language)
+	if err != nil {
+		return nil, err
+	}
+	var stems []string
+	for _, keywords := range normalized {
+		stems = append(stems, keywords...)
+	}
+	return stems, nil
+}
+
+// contains tells whether stems hold the phrase stems next to each other, in order.
+func contains(stems, phrase []string) bool {
+	if len(phrase) == 0 {
+		return true
+	}
+	for i := 0; i+len(phrase) <= len(stems); i++ {
+		if slices.Equal(stems[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
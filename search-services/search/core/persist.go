@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// indexFile is the saved index, postings are rebuilt from the keywords
+// of the comics when it is loaded.
+type indexFile struct {
+	BuiltAt time.Time
+	Comics  []Comics
+}
+
+// SaveIndex writes the index and its last build time to path, replacing
+// the file at once so a crash cannot leave it half written.
+func (s *Service) SaveIndex(path string) error {
+	if s.noIndex {
+		return nil
+	}
+	s.historyLock.Lock()
+	saved := indexFile{BuiltAt: s.builtAt, Comics: s.index.All()}
+	s.historyLock.Unlock()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(saved); err != nil {
+		return fmt.Errorf("cannot encode index: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot save index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot save index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot save index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot save index: %w", err)
+	}
+	s.log.Debug("saved index", "path", path, "comics count", len(saved.Comics))
+	return nil
+}
+
+// LoadIndex replaces the index with the one saved at path and returns
+// when it was built, a missing file fails with os.ErrNotExist.
+func (s *Service) LoadIndex(path string) (time.Time, error) {
+	if s.noIndex {
+		return time.Time{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot load index: %w", err)
+	}
+	var loaded indexFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&loaded); err != nil {
+		return time.Time{}, fmt.Errorf("cannot decode index %s: %w", path, err)
+	}
+
+	index := NewIndex()
+	for _, comics := range loaded.Comics {
+		index.Put(comics)
+	}
+	s.index.Replace(index)
+	s.historyLock.Lock()
+	s.builtAt = loaded.BuiltAt
+	s.historyLock.Unlock()
+	s.log.Debug("loaded index", "path", path, "comics count", len(loaded.Comics), "built at", loaded.BuiltAt)
+	return loaded.BuiltAt, nil
+}
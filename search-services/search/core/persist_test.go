@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_SaveAndLoadIndex(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.gob")
+	db := &FakeDB{
+		lastID: 2,
+		comics: map[int]Comics{
+			1: {ID: 1, Title: "Linux", Keywords: []string{"linux", "kernel"}},
+			2: {ID: 2, Title: "Cats", Keywords: []string{"cat"}},
+		},
+	}
+	built, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+	require.NoError(t, built.BuildIndex(ctx))
+	require.NoError(t, built.SaveIndex(path))
+
+	// the DB is not needed to serve the loaded index
+	loaded, err := NewService(noopLogger, &FakeDB{}, &FakeWords{normalized: []string{"linux"}})
+	require.NoError(t, err)
+	builtAt, err := loaded.LoadIndex(path)
+	require.NoError(t, err)
+
+	assert.True(t, builtAt.Equal(built.IndexStats(ctx).BuiltAt))
+	assert.True(t, loaded.IndexStats(ctx).BuiltAt.Equal(builtAt))
+	assert.Equal(t, 2, loaded.index.Size())
+	assert.Equal(t, []int{1}, loaded.index.Get("kernel"))
+	result, total, err := unpack(loaded.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, result, 1)
+	assert.Equal(t, "Linux", result[0].Title)
+}
+
+func TestService_LoadIndex_Missing(t *testing.T) {
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{})
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"linux"}})
+
+	_, err = svc.LoadIndex(filepath.Join(t.TempDir(), "missing.gob"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, 1, svc.index.Size(), "index is kept")
+
+	broken := filepath.Join(t.TempDir(), "broken.gob")
+	require.NoError(t, os.WriteFile(broken, []byte("not gob"), 0o600))
+	_, err = svc.LoadIndex(broken)
+	assert.Error(t, err)
+	assert.Equal(t, 1, svc.index.Size(), "index is kept")
+}
@@ -2,26 +2,53 @@ package core
 
 import (
 	"context"
+	"time"
 )
 
 type Searcher interface {
-	Search(ctx context.Context, phrase string, limit int) ([]Comics, error)
-	SearchIndex(ctx context.Context, phrase string, limit int) ([]Comics, error)
+	Search(ctx context.Context, phrase string, limit int, opts SearchOptions) (SearchResult, error)
+	SearchIndex(ctx context.Context, phrase string, limit int, opts SearchOptions) (SearchResult, error)
 	BuildIndex(ctx context.Context) error
+	ReindexComic(ctx context.Context, ID int) error
+	ReindexRange(ctx context.Context, from, to int) (int, error)
+	ClearIndex(ctx context.Context) error
+	SaveIndex(path string) error
+	// LoadIndex returns when the loaded index was built.
+	LoadIndex(path string) (time.Time, error)
+	Comic(ctx context.Context, ID int) (Comics, error)
+	Neighbours(ctx context.Context, ID int) (prevID int, nextID int, err error)
+	DocumentFrequency(ctx context.Context, term string) (map[string]int, error)
+	BuildHistory(ctx context.Context) []BuildRun
+	IndexStats(ctx context.Context) IndexStats
+	Ready(ctx context.Context) error
+	// Changes continues after comics afterID changed at since, 0 starts at since.
+	Changes(ctx context.Context, since time.Time, afterID, limit int, tombstones bool) ([]Comics, error)
+	ByTitle(ctx context.Context, title string) ([]Comics, error)
+	LastID(ctx context.Context) (int, error)
 }
 
 type DB interface {
 	Search(ctx context.Context, keyword string) ([]int, error)
 	Get(ctx context.Context, ID int) (Comics, error)
 	LastID(ctx context.Context) (int, error)
+	Neighbours(ctx context.Context, ID int) (prevID int, nextID int, err error)
+	Ping(ctx context.Context) error
+	// Changes includes tombstones of deleted comics when asked to, ordered
+	// by change time and ID after the (since, afterID) cursor.
+	Changes(ctx context.Context, since time.Time, afterID, limit int, tombstones bool) ([]Comics, error)
+	ByTitle(ctx context.Context, title string) ([]Comics, error)
 }
 
 type Words interface {
-	Norm(ctx context.Context, phrase string) ([]string, error)
+	// Norm uses the default language when language is empty.
+	Norm(ctx context.Context, phrase, language string) ([]string, error)
+	// NormBatch normalizes phrases at once, results in the order of phrases.
+	NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error)
 }
 
 type EventSubscriber interface {
-	SubscribeDBUpdateEvent(ctx context.Context) (<-chan struct{}, error)
+	// SubscribeDBUpdateEvent delivers ids of added comics, none for a full update.
+	SubscribeDBUpdateEvent(ctx context.Context) (<-chan []int, error)
 	SubscribeDBDropEvent(ctx context.Context) (<-chan struct{}, error)
 	Close() error
 }
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"slices"
+	"strings"
+)
+
+// parseQuery splits field:term tokens, like title:python, off the bare
+// terms of a phrase. Terms of both kinds are returned in terms, field ones
+// are also grouped per field. Tokens not starting with a known field and a
+// colon, like re:zero, or looking like URLs, are bare terms, so plain
+// phrases are searched unchanged.
+func parseQuery(phrase string) (terms string, fields map[Field]string) {
+	tokens := strings.Fields(phrase)
+	bare := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		name, term, ok := strings.Cut(token, ":")
+		field := Field(strings.ToLower(name))
+		if !ok || term == "" || (field != FieldTitle && field != FieldAlt) || strings.HasPrefix(term, "//") {
+			bare = append(bare, token)
+			continue
+		}
+		if fields == nil {
+			fields = make(map[Field]string)
+		}
+		fields[field] = strings.TrimSpace(fields[field] + " " + term)
+		bare = append(bare, term)
+	}
+	return strings.Join(bare, " "), fields
+}
+
+// splitPrefixes takes prefix wildcard terms, like pyth*, off a phrase.
+// Stemming a part of a word would break it, so prefixes are only lower
+// cased and keep the trailing star marking them among keywords.
+func splitPrefixes(phrase string) (terms string, prefixes []string) {
+	tokens := strings.Fields(phrase)
+	bare := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		prefix, ok := strings.CutSuffix(token, "*")
+		if !ok || prefix == "" || strings.ContainsRune(prefix, '*') {
+			bare = append(bare, token)
+			continue
+		}
+		prefixes = append(prefixes, strings.ToLower(prefix)+"*")
+	}
+	return strings.Join(bare, " "), prefixes
+}
+
+// isPrefix tells whether keyword is a prefix wildcard.
+func isPrefix(keyword string) bool {
+	return len(keyword) > 1 && strings.HasSuffix(keyword, "*")
+}
+
+// matches tells whether keywords contain keyword, or a keyword starting
+// with it for a prefix wildcard.
+func matches(keywords []string, keyword string) bool {
+	if !isPrefix(keyword) {
+		return slices.Contains(keywords, keyword)
+	}
+	prefix := strings.TrimSuffix(keyword, "*")
+	return slices.ContainsFunc(keywords, func(other string) bool {
+		return strings.HasPrefix(other, prefix)
+	})
+}
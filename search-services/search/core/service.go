@@ -2,37 +2,199 @@ package core
 
 import (
 	"cmp"
+	"container/heap"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"maps"
 	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/ngrams"
+	"golang.org/x/time/rate"
 )
 
+const buildHistorySize = 10
+
+// maxCountFetches bounds comics fetched outside of the page only to be
+// counted in the total.
+const maxCountFetches = 100
+
 type Service struct {
-	log   *slog.Logger
-	db    DB
-	words Words
-	index *Index
+	log      *slog.Logger
+	db       DB
+	words    Words
+	fallback Words
+	synonyms map[string][]string
+	index    *Index
+
+	earlyTermination bool
+	slowSearch       time.Duration
+	noIndex          bool
+	maxTerms         int
+	ngrams           int
+	bm25             bool
+	k1, b            float64
+	buildLimiter     *rate.Limiter
+	lenient          bool
+	rejectStopWords  bool
+
+	history     []BuildRun
+	builtAt     time.Time
+	historyLock sync.Mutex
+
+	reindexLock sync.Mutex
+	// buildLock serializes builds with reindexes, a build swapping its
+	// index in would drop comics reindexed meanwhile
+	buildLock sync.Mutex
+
+	// fields keeps normalized fields of comics by ID for searches and
+	// builds, so they are normalized again only once the comics changes
+	fields     map[int]normalizedFields
+	fieldsLock sync.Mutex
+}
+
+// normalizedFields are fields of a comics normalized of its title and alt.
+type normalizedFields struct {
+	title, alt string
+	fields     map[Field][]string
+}
+
+type Option func(*Service)
+
+// WithWordsFallback sets a normalizer used while the words service is unavailable.
+func WithWordsFallback(fallback Words) Option {
+	return func(s *Service) {
+		s.fallback = fallback
+	}
+}
+
+// WithSynonyms sets normalized keyword synonyms used to expand queries.
+func WithSynonyms(synonyms map[string][]string) Option {
+	return func(s *Service) {
+		s.synonyms = synonyms
+	}
+}
+
+// WithEarlyTermination lets index searches stop collecting candidates
+// once the top results can no longer be overtaken.
+func WithEarlyTermination() Option {
+	return func(s *Service) {
+		s.earlyTermination = true
+	}
+}
+
+// WithSlowSearchLog warns about searches taking longer than threshold.
+func WithSlowSearchLog(threshold time.Duration) Option {
+	return func(s *Service) {
+		s.slowSearch = threshold
+	}
+}
+
+// WithMaxTerms limits keywords searched after synonym and n-gram expansion,
+// query keywords are always kept and then the most distinctive expansions.
+func WithMaxTerms(n int) Option {
+	return func(s *Service) {
+		s.maxTerms = n
+	}
+}
+
+// WithNGrams also searches every 2 up to n adjacent query keywords as
+// a keyword, comics indexed with the same n-grams rank higher.
+func WithNGrams(n int) Option {
+	return func(s *Service) {
+		s.ngrams = n
+	}
+}
+
+// WithBM25 ranks comics by BM25 of the query keywords instead of
+// counting them, k1 saturates keyword frequencies and b normalizes
+// comics lengths. Scores are BM25 values times 1000. Keyword frequencies
+// are counted in the stems stored with comics, keywords of comics stored
+// without stems count once.
+func WithBM25(k1, b float64) Option {
+	return func(s *Service) {
+		s.bm25 = true
+		s.k1 = k1
+		s.b = b
+	}
+}
+
+// WithBuildRate fetches at most rps comics per second while building or
+// reindexing, so slower background builds leave the DB to searches.
+func WithBuildRate(rps int) Option {
+	return func(s *Service) {
+		s.buildLimiter = rate.NewLimiter(rate.Limit(rps), 1)
+	}
 }
 
-func NewService(log *slog.Logger, db DB, words Words) (*Service, error) {
+// WithLenientFetch keeps searching when some found comics fail to be
+// fetched, the others are returned along with the count of failed ones.
+func WithLenientFetch() Option {
+	return func(s *Service) {
+		s.lenient = true
+	}
+}
 
-	return &Service{
-		log:   log,
-		db:    db,
-		words: words,
-		index: NewIndex(),
-	}, nil
+// WithStopWordsRejected fails searches of phrases normalized to no
+// keywords, field ones included, with ErrStopWords before any keyword lookup.
+func WithStopWordsRejected() Option {
+	return func(s *Service) {
+		s.rejectStopWords = true
+	}
 }
 
-func (s *Service) Search(ctx context.Context, phrase string, limit int) ([]Comics, error) {
+// WithoutIndex disables the in-memory index, index searches go to the DB
+// and index maintenance does nothing.
+func WithoutIndex() Option {
+	return func(s *Service) {
+		s.noIndex = true
+	}
+}
 
-	keywords, err := s.words.Norm(ctx, phrase)
+func NewService(log *slog.Logger, db DB, words Words, opts ...Option) (*Service, error) {
+
+	s := &Service{
+		log:    log,
+		db:     db,
+		words:  words,
+		index:  NewIndex(),
+		fields: map[int]normalizedFields{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Search returns comics up to limit after the cursor and offset, and the
+// total of matching comics regardless of them.
+func (s *Service) Search(
+	ctx context.Context, phrase string, limit int, opts SearchOptions,
+) (result SearchResult, err error) {
+	var keywords []string
+	defer func(start time.Time) {
+		s.logSlow(start, phrase, keywords, result.Comics)
+	}(time.Now())
+
+	query, fields, err := s.parse(ctx, phrase, opts.Language)
 	if err != nil {
 		s.log.Error("failed to find keywords", "error", err)
-		return nil, err
+		return SearchResult{}, err
+	}
+	if s.rejectStopWords && len(query) == 0 && len(fields) == 0 {
+		s.log.Warn("rejected query of stop words only", "phrase", phrase)
+		return SearchResult{}, ErrStopWords
 	}
+	stems, err := s.ordered(ctx, phrase, opts)
+	if err != nil {
+		s.log.Error("failed to find keywords in order", "error", err)
+		return SearchResult{}, err
+	}
+	keywords = s.capTerms(query, slices.Concat(s.expand(query)[len(query):], ngrams.Join(stems, s.ngrams)))
 	s.log.Debug("normalized query", "keywords", keywords)
 
 	// comics ID -> number of findings
@@ -41,88 +203,749 @@ func (s *Service) Search(ctx context.Context, phrase string, limit int) ([]Comic
 		IDs, err := s.db.Search(ctx, keyword)
 		if err != nil {
 			s.log.Error("failed to search keyword in DB", "error", err)
-			return nil, err
+			return SearchResult{}, err
 		}
 		for _, ID := range IDs {
 			scores[ID]++
 		}
 	}
 
-	return s.fetch(ctx, scores, limit)
+	scores = s.rescore(s.filter(scores, query, keywords, opts), keywords, opts.Boosts)
+	get := s.db.Get
+	if len(fields) > 0 {
+		// field terms are matched against normalized fields of the DB comics
+		get = func(ctx context.Context, ID int) (Comics, error) {
+			comics, err := s.db.Get(ctx, ID)
+			if err != nil {
+				return Comics{}, err
+			}
+			return s.withFields(ctx, comics), nil
+		}
+	}
+	return s.fetch(ctx, scores, limit, opts, s.matcher(query, fields, stems, opts), get)
 }
 
-func (s *Service) SearchIndex(ctx context.Context, phrase string, limit int) ([]Comics, error) {
+func (s *Service) SearchIndex(
+	ctx context.Context, phrase string, limit int, opts SearchOptions,
+) (result SearchResult, err error) {
+	if s.noIndex {
+		return s.Search(ctx, phrase, limit, opts)
+	}
+	var keywords []string
+	defer func(start time.Time) {
+		s.logSlow(start, phrase, keywords, result.Comics)
+	}(time.Now())
 
-	keywords, err := s.words.Norm(ctx, phrase)
+	query, fields, err := s.parse(ctx, phrase, opts.Language)
 	if err != nil {
 		s.log.Error("failed to find keywords", "error", err)
-		return nil, err
+		return SearchResult{}, err
 	}
+	if s.rejectStopWords && len(query) == 0 && len(fields) == 0 {
+		s.log.Warn("rejected query of stop words only", "phrase", phrase)
+		return SearchResult{}, ErrStopWords
+	}
+	stems, err := s.ordered(ctx, phrase, opts)
+	if err != nil {
+		s.log.Error("failed to find keywords in order", "error", err)
+		return SearchResult{}, err
+	}
+	keywords = s.capTerms(query, slices.Concat(s.expand(query)[len(query):], ngrams.Join(stems, s.ngrams)))
 	s.log.Debug("normalized query", "keywords", keywords)
 
+	// filtered out comics may leave early terminated results short
+	early := s.earlyTermination && !s.bm25 && opts.Tag == "" && opts.Offset == 0 && !opts.MatchAll &&
+		!opts.Phrase && opts.After.IsZero() && len(opts.Boosts) == 0 && len(fields) == 0
+	scores, candidates := s.scoreIndex(keywords, limit, early)
+	scores = s.rescore(s.filter(scores, query, keywords, opts), keywords, opts.Boosts)
+	result, err = s.fetch(ctx, scores, limit, opts, s.matcher(query, fields, stems, opts), s.indexed)
+	if early {
+		// only the top comics may be scored, but every candidate matches
+		result.Total = candidates
+	}
+	return result, err
+}
+
+func (s *Service) logSlow(start time.Time, phrase string, keywords []string, result []Comics) {
+	if s.slowSearch <= 0 {
+		return
+	}
+	if duration := time.Since(start); duration > s.slowSearch {
+		s.log.Warn("slow search",
+			"phrase", phrase, "keywords", keywords, "results", len(result), "duration", duration)
+	}
+}
+
+// filter drops comics that cannot match every searched query keyword,
+// as each matched one adds at least a finding.
+func (s *Service) filter(scores map[int]int, query, keywords []string, opts SearchOptions) map[int]int {
+	if opts.MatchAll {
+		var searched int
+		for _, keyword := range query {
+			if slices.Contains(keywords, keyword) {
+				searched++
+			}
+		}
+		maps.DeleteFunc(scores, func(_ int, score int) bool {
+			return score < searched
+		})
+	}
+	return scores
+}
+
+// rescore ranks comics by BM25 when enabled, by boosted findings otherwise.
+func (s *Service) rescore(scores map[int]int, keywords []string, boosts map[Field]int) map[int]int {
+	if s.bm25 {
+		return s.rankBM25(scores, keywords, boosts)
+	}
+	return s.boost(scores, keywords, boosts)
+}
+
+// boost rescores comics, each matched keyword weighs the most boosted
+// indexed field containing it. Comics missing in the index keep scores.
+func (s *Service) boost(scores map[int]int, keywords []string, boosts map[Field]int) map[int]int {
+	if len(boosts) == 0 {
+		return scores
+	}
+	for ID := range scores {
+		comics, ok := s.index.Comic(ID)
+		if !ok {
+			continue
+		}
+		var score int
+		for _, keyword := range keywords {
+			if !matches(comics.Keywords, keyword) {
+				continue
+			}
+			weight := 1
+			for field, boost := range boosts {
+				if matches(comics.Fields[field], keyword) {
+					weight = max(weight, boost)
+				}
+			}
+			score += weight
+		}
+		scores[ID] = score
+	}
+	return scores
+}
+
+// matcher checks fetched comics against options and field terms, a query
+// keyword is also matched by any of its synonyms. Phrase searches match
+// the stems in order. The tag is compared the way tags are stored,
+// lowercased and trimmed. It is nil when every scored comics matches.
+func (s *Service) matcher(
+	query []string, fields map[Field][]string, stems []string, opts SearchOptions,
+) func(Comics) bool {
+	tag := strings.ToLower(strings.TrimSpace(opts.Tag))
+	if tag == "" && len(fields) == 0 && !opts.MatchAll && !opts.Phrase {
+		return nil
+	}
+	return func(comics Comics) bool {
+		if tag != "" && !slices.Contains(comics.Tags, tag) {
+			return false
+		}
+		if opts.Phrase && !contains(comics.Stems, stems) {
+			return false
+		}
+		for field, keywords := range fields {
+			for _, keyword := range keywords {
+				if !matches(comics.Fields[field], keyword) {
+					return false
+				}
+			}
+		}
+		if !opts.MatchAll {
+			return true
+		}
+		for _, keyword := range query {
+			found := matches(comics.Keywords, keyword)
+			for _, synonym := range s.synonyms[keyword] {
+				found = found || slices.Contains(comics.Keywords, synonym)
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// scoreIndex counts keyword findings per comics ID and the comics found by
+// any keyword. With early termination rarest keywords go first, and once
+// the top limit comics cannot be overtaken the remaining keywords only
+// finish their scores.
+func (s *Service) scoreIndex(keywords []string, limit int, early bool) (map[int]int, int) {
+	postings := make([][]int, 0, len(keywords))
+	for _, keyword := range keywords {
+		postings = append(postings, s.index.Get(keyword))
+	}
+
 	// comics ID -> number of findings
 	scores := map[int]int{}
-	for _, keyword := range keywords {
+	if !early {
+		for _, posting := range postings {
+			for _, ID := range posting {
+				scores[ID]++
+			}
+		}
+		return scores, len(scores)
+	}
 
-		for _, ID := range s.index.Get(keyword) {
+	slices.SortFunc(postings, func(a, b []int) int {
+		return cmp.Compare(len(a), len(b))
+	})
+	for i, posting := range postings {
+		for _, ID := range posting {
 			scores[ID]++
 		}
+		remaining := len(postings) - i - 1
+		if remaining == 0 {
+			break
+		}
+		top, settled := topScores(scores, limit, remaining)
+		if !settled {
+			continue
+		}
+		s.log.Debug("terminated search early", "keywords left", remaining)
+		unscored := make(map[int]struct{})
+		for _, posting := range postings[i+1:] {
+			for _, ID := range posting {
+				if _, ok := top[ID]; ok {
+					top[ID]++
+				} else if _, ok := scores[ID]; !ok {
+					unscored[ID] = struct{}{}
+				}
+			}
+		}
+		return top, len(scores) + len(unscored)
 	}
+	return scores, len(scores)
+}
 
-	return s.fetch(ctx, scores, limit)
+// topScores returns the limit best scores if no other comics can beat them
+// with the remaining number of keywords. A tie could outrank them by ID,
+// so other comics must stay below.
+func topScores(scores map[int]int, limit, remaining int) (map[int]int, bool) {
+	if limit <= 0 || len(scores) < limit {
+		return nil, false
+	}
+	// the limit best comics and the next one, found without sorting all
+	best := &ranked{scores: scores}
+	for ID := range scores {
+		if best.Len() <= limit {
+			heap.Push(best, ID)
+			continue
+		}
+		if !best.below(best.IDs[0], ID) {
+			continue
+		}
+		best.IDs[0] = ID
+		heap.Fix(best, 0)
+	}
+	var bound int
+	if best.Len() > limit {
+		bound = scores[heap.Pop(best).(int)]
+	}
+	if scores[best.IDs[0]] <= bound+remaining {
+		return nil, false
+	}
+	top := make(map[int]int, limit)
+	for _, ID := range best.IDs {
+		top[ID] = scores[ID]
+	}
+	return top, true
 }
 
-func (s *Service) fetch(ctx context.Context, scores map[int]int, limit int) ([]Comics, error) {
-	s.log.Debug("relevant comics", "count", len(scores))
+// ranked is a heap of comics IDs with the one ranking last on top.
+type ranked struct {
+	IDs    []int
+	scores map[int]int
+}
+
+// below tells comics a ranks below comics b in the order of rank.
+func (r *ranked) below(a, b int) bool {
+	return cmp.Or(cmp.Compare(r.scores[a], r.scores[b]), cmp.Compare(b, a)) < 0
+}
+
+func (r *ranked) Len() int { return len(r.IDs) }
+
+func (r *ranked) Less(i, j int) bool { return r.below(r.IDs[i], r.IDs[j]) }
 
-	// sort by number of findings
-	sorted := slices.SortedFunc(maps.Keys(scores), func(a, b int) int {
-		return cmp.Compare(scores[b], scores[a]) // desc
+func (r *ranked) Swap(i, j int) { r.IDs[i], r.IDs[j] = r.IDs[j], r.IDs[i] }
+
+func (r *ranked) Push(ID any) { r.IDs = append(r.IDs, ID.(int)) }
+
+func (r *ranked) Pop() any {
+	ID := r.IDs[len(r.IDs)-1]
+	r.IDs = r.IDs[:len(r.IDs)-1]
+	return ID
+}
+
+// rank orders comics IDs by score descending and then by ID ascending.
+// The order is total, so repeated searches return results in the same
+// order and pages stay stable.
+func rank(scores map[int]int) []int {
+	return slices.SortedFunc(maps.Keys(scores), func(a, b int) int {
+		return cmp.Or(cmp.Compare(scores[b], scores[a]), cmp.Compare(a, b))
 	})
+}
 
-	// limit results
-	if len(sorted) < limit {
-		limit = len(sorted)
+// indexed serves comics from the index, so the DB is only needed for comics missing there.
+func (s *Service) indexed(ctx context.Context, ID int) (Comics, error) {
+	if comics, ok := s.index.Comic(ID); ok {
+		return comics, nil
 	}
-	sorted = sorted[:limit]
+	return s.db.Get(ctx, ID)
+}
 
-	// fetch comics
-	result := make([]Comics, 0, len(sorted))
+// parse normalizes all terms of a phrase and separately those qualified by fields.
+func (s *Service) parse(ctx context.Context, phrase, language string) ([]string, map[Field][]string, error) {
+	terms, fields := parseQuery(phrase)
+	query, err := s.normalizeTerms(ctx, terms, language)
+	if err != nil {
+		return nil, nil, err
+	}
+	var normalized map[Field][]string
+	for field, terms := range fields {
+		keywords, err := s.normalizeTerms(ctx, terms, language)
+		if err != nil {
+			return nil, nil, err
+		}
+		if normalized == nil {
+			normalized = make(map[Field][]string, len(fields))
+		}
+		normalized[field] = keywords
+	}
+	return query, normalized, nil
+}
+
+// normalizeTerms normalizes a phrase keeping its prefix wildcards as they are.
+func (s *Service) normalizeTerms(ctx context.Context, phrase, language string) ([]string, error) {
+	terms, prefixes := splitPrefixes(phrase)
+	if terms == "" {
+		return prefixes, nil
+	}
+	keywords, err := s.normalize(ctx, terms, language)
+	if err != nil {
+		return nil, err
+	}
+	return append(keywords, prefixes...), nil
+}
+
+func (s *Service) normalize(ctx context.Context, phrase, language string) ([]string, error) {
+	keywords, err := s.words.Norm(ctx, phrase, language)
+	if errors.Is(err, ErrUnavailable) && s.fallback != nil {
+		s.log.Warn("words service is unavailable, running in degraded mode", "error", err)
+		return s.fallback.Norm(ctx, phrase, language)
+	}
+	return keywords, err
+}
+
+func (s *Service) normalizeBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	normalized, err := s.words.NormBatch(ctx, phrases, language)
+	if errors.Is(err, ErrUnavailable) && s.fallback != nil {
+		s.log.Warn("words service is unavailable, running in degraded mode", "error", err)
+		return s.fallback.NormBatch(ctx, phrases, language)
+	}
+	return normalized, err
+}
+
+func (s *Service) expand(keywords []string) []string {
+	if len(s.synonyms) == 0 {
+		return keywords
+	}
+	expanded := slices.Clone(keywords)
+	for _, keyword := range keywords {
+		for _, synonym := range s.synonyms[keyword] {
+			if !slices.Contains(expanded, synonym) {
+				expanded = append(expanded, synonym)
+			}
+		}
+	}
+	return expanded
+}
+
+// capTerms keeps every query keyword and, up to maxTerms keywords in all,
+// the expansions found in the fewest indexed comics. Expansions missing in
+// the index go last, kept ones stay in order after the query keywords.
+func (s *Service) capTerms(query, expansions []string) []string {
+	if s.maxTerms <= 0 || len(query)+len(expansions) <= s.maxTerms {
+		return slices.Concat(query, expansions)
+	}
+	counts := make(map[string]int, len(expansions))
+	for _, keyword := range expansions {
+		counts[keyword] = s.index.Count(keyword)
+	}
+	ranked := slices.Clone(expansions)
+	slices.SortStableFunc(ranked, func(a, b string) int {
+		if (counts[a] == 0) != (counts[b] == 0) {
+			return cmp.Compare(counts[b], counts[a])
+		}
+		return cmp.Compare(counts[a], counts[b])
+	})
+	kept := ranked[:max(s.maxTerms-len(query), 0)]
+	capped := slices.Clone(query)
+	for _, keyword := range expansions {
+		if slices.Contains(kept, keyword) {
+			capped = append(capped, keyword)
+		}
+	}
+	s.log.Debug("capped search terms", "keywords", len(query)+len(expansions), "kept", capped)
+	return capped
+}
+
+// fetch returns comics up to limit after the cursor, skipping offset
+// matching ones, and the total of matching comics. Comics outside of the
+// page are only counted, see counts. Lenient fetches skip comics failing
+// to be fetched unless all of them fail.
+func (s *Service) fetch(
+	ctx context.Context, scores map[int]int, limit int, opts SearchOptions, match func(Comics) bool,
+	get func(context.Context, int) (Comics, error),
+) (SearchResult, error) {
+	s.log.Debug("relevant comics", "count", len(scores))
+
+	sorted := rank(scores)
+
+	result := make([]Comics, 0, min(limit, len(sorted)))
+	var total int
+	if match == nil {
+		total = len(sorted)
+	}
+	skip := opts.Offset
+	var fetched, failed, countFetches int
+	var lastErr error
 	for _, ID := range sorted {
-		comics, err := s.db.Get(ctx, ID)
+		inPage := len(result) < limit && (opts.After.IsZero() || !opts.After.before(scores[ID], ID))
+		if !inPage {
+			if match != nil && s.counts(ctx, ID, match, get, &countFetches) {
+				total++
+			}
+			continue
+		}
+		comics, err := get(ctx, ID)
 		if err != nil {
 			s.log.Error("failed to fetch comics", "id", ID, "error", err)
-			return nil, err
+			if !s.lenient || ctx.Err() != nil {
+				return SearchResult{}, err
+			}
+			failed++
+			lastErr = err
+			continue
+		}
+		fetched++
+		if match != nil {
+			if !match(comics) {
+				continue
+			}
+			total++
+		}
+		if skip > 0 {
+			skip--
+			continue
 		}
 		comics.Score = scores[ID]
 		result = append(result, comics)
 	}
-	s.log.Debug("returning comics", "count", len(result))
+	s.log.Debug("returning comics", "count", len(result), "total", total)
+
+	if failed > 0 {
+		if fetched == 0 {
+			return SearchResult{}, lastErr
+		}
+		s.log.Warn("returning partial results", "count", len(result), "failed", failed, "error", lastErr)
+	}
+	return SearchResult{Comics: result, Total: total, Failed: failed}, nil
+}
+
+// counts tells whether comics outside of the page matches, judged by its
+// indexed version. Up to maxCountFetches comics missing in the index are
+// fetched, the others and those failing to be fetched count as matching.
+func (s *Service) counts(
+	ctx context.Context, ID int, match func(Comics) bool,
+	get func(context.Context, int) (Comics, error), fetches *int,
+) bool {
+	if comics, ok := s.index.Comic(ID); ok {
+		return match(comics)
+	}
+	if *fetches >= maxCountFetches {
+		return true
+	}
+	*fetches++
+	comics, err := get(ctx, ID)
+	if err != nil {
+		s.log.Warn("failed to fetch comics to count", "id", ID, "error", err)
+		return true
+	}
+	return match(comics)
+}
+
+func (s *Service) Comic(ctx context.Context, ID int) (Comics, error) {
+	comics, err := s.db.Get(ctx, ID)
+	if err != nil {
+		s.log.Error("failed to fetch comics", "id", ID, "error", err)
+		return Comics{}, err
+	}
+	return comics, nil
+}
+
+// Neighbours returns the closest existing comics IDs around ID, 0 if there is none.
+func (s *Service) Neighbours(ctx context.Context, ID int) (int, int, error) {
+	prevID, nextID, err := s.db.Neighbours(ctx, ID)
+	if err != nil {
+		s.log.Error("failed to fetch neighbours", "id", ID, "error", err)
+		return 0, 0, err
+	}
+	return prevID, nextID, nil
+}
 
-	return result, nil
+// DocumentFrequency reports how many indexed comics contain each normalized keyword of term.
+func (s *Service) DocumentFrequency(ctx context.Context, term string) (map[string]int, error) {
+	keywords, err := s.normalize(ctx, term, "")
+	if err != nil {
+		s.log.Error("failed to find keywords", "error", err)
+		return nil, err
+	}
+	frequencies := make(map[string]int, len(keywords))
+	for _, keyword := range keywords {
+		frequencies[keyword] = s.index.Count(keyword)
+	}
+	return frequencies, nil
+}
+
+// Changes returns comics modified after since, oldest change first,
+// tombstones tell about comics deleted meanwhile. Comics changed at the
+// same time as since continue after afterID, so pages never skip them.
+func (s *Service) Changes(
+	ctx context.Context, since time.Time, afterID, limit int, tombstones bool,
+) ([]Comics, error) {
+	if limit < 1 || afterID < 0 {
+		return nil, ErrBadArguments
+	}
+	comics, err := s.db.Changes(ctx, since, afterID, limit, tombstones)
+	if err != nil {
+		s.log.Error("failed to fetch changed comics", "since", since, "error", err)
+		return nil, err
+	}
+	return comics, nil
+}
+
+// LastID returns the newest stored comics ID, 0 when there is none.
+func (s *Service) LastID(ctx context.Context) (int, error) {
+	ID, err := s.db.LastID(ctx)
+	if err != nil {
+		s.log.Error("failed to get last comics ID", "error", err)
+		return 0, err
+	}
+	return ID, nil
+}
+
+func (s *Service) ByTitle(ctx context.Context, title string) ([]Comics, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, ErrBadArguments
+	}
+	comics, err := s.db.ByTitle(ctx, title)
+	if err != nil {
+		s.log.Error("failed to find comics by title", "title", title, "error", err)
+		return nil, err
+	}
+	if len(comics) == 0 {
+		return nil, ErrNotFound
+	}
+	return comics, nil
+}
+
+// Ready reports ErrDegraded when the DB is down but the index can still serve searches.
+func (s *Service) Ready(ctx context.Context) error {
+	if err := s.db.Ping(ctx); err != nil {
+		if s.index.Size() > 0 {
+			return fmt.Errorf("%w: %v", ErrDegraded, err)
+		}
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
 }
 
 func (s *Service) BuildIndex(ctx context.Context) error {
+	if s.noIndex {
+		return nil
+	}
+
+	s.buildLock.Lock()
+	start := time.Now()
+	comicsCount, err := s.buildIndex(ctx)
+	s.buildLock.Unlock()
+	run := BuildRun{StartedAt: start, Duration: time.Since(start), Comics: comicsCount}
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	s.historyLock.Lock()
+	if err == nil {
+		s.builtAt = start
+	}
+	s.history = append(s.history, run)
+	if len(s.history) > buildHistorySize {
+		s.history = s.history[len(s.history)-buildHistorySize:]
+	}
+	s.historyLock.Unlock()
+	return err
+}
 
+// BuildHistory returns recent index builds, oldest first.
+func (s *Service) BuildHistory(_ context.Context) []BuildRun {
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+	return slices.Clone(s.history)
+}
+
+func (s *Service) IndexStats(_ context.Context) IndexStats {
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+	return IndexStats{
+		Comics:   s.index.Size(),
+		Keywords: s.index.Keywords(),
+		BuiltAt:  s.builtAt,
+		Disabled: s.noIndex,
+	}
+}
+
+// ClearIndex empties the index at once, searches fall back to nothing found.
+func (s *Service) ClearIndex(_ context.Context) error {
 	s.index.Clear()
-	lastID, err := s.db.LastID(ctx)
+	s.log.Debug("cleared index")
+	return nil
+}
+
+// ReindexComic refreshes a single comics in the index from the DB, comics
+// gone from the DB are dropped from the index and fail with ErrNotFound.
+func (s *Service) ReindexComic(ctx context.Context, ID int) error {
+	if s.noIndex {
+		return nil
+	}
+	s.buildLock.Lock()
+	defer s.buildLock.Unlock()
+	comics, err := s.db.Get(ctx, ID)
+	if errors.Is(err, ErrNotFound) {
+		s.index.Delete(ID)
+		s.log.Debug("dropped comics gone from DB", "id", ID)
+		return err
+	}
 	if err != nil {
+		s.log.Error("failed to fetch comics for reindex", "id", ID, "error", err)
 		return err
 	}
+	s.index.Put(s.withFields(ctx, comics))
+	s.log.Debug("reindexed comics", "id", ID)
+	return nil
+}
+
+// maxReindexRange is the most comics a range reindex refreshes.
+const maxReindexRange = 1000
+
+// ReindexRange refreshes comics with IDs from to to in the index, comics
+// gone from the DB are dropped. It returns how many comics were indexed,
+// a range reindex already running fails with ErrAlreadyExists. Ranges
+// over maxReindexRange comics fail with ErrBadArguments.
+func (s *Service) ReindexRange(ctx context.Context, from, to int) (int, error) {
+	if from < 1 || to < from {
+		return 0, fmt.Errorf("%w: bad range %d-%d", ErrBadArguments, from, to)
+	}
+	if to-from >= maxReindexRange {
+		return 0, fmt.Errorf("%w: range %d-%d is over %d comics", ErrBadArguments, from, to, maxReindexRange)
+	}
+	if s.noIndex {
+		return 0, nil
+	}
+	if ok := s.reindexLock.TryLock(); !ok {
+		s.log.Error("service already reindexes a range")
+		return 0, ErrAlreadyExists
+	}
+	defer s.reindexLock.Unlock()
+	s.buildLock.Lock()
+	defer s.buildLock.Unlock()
+
+	var comicsCount int
+	for ID := from; ID <= to; ID++ {
+		if err := s.throttle(ctx); err != nil {
+			return comicsCount, err
+		}
+		comics, err := s.db.Get(ctx, ID)
+		if errors.Is(err, ErrNotFound) {
+			s.index.Delete(ID)
+			continue
+		}
+		if err != nil {
+			s.log.Error("failed to fetch comics for reindex", "id", ID, "error", err)
+			return comicsCount, err
+		}
+		s.index.Put(s.withFields(ctx, comics))
+		comicsCount++
+	}
+	s.log.Debug("reindexed comics range", "from", from, "to", to, "comics count", comicsCount)
+	return comicsCount, nil
+}
+
+// withFields normalizes boostable fields of comics in one batch, fields of
+// a comics with the same title and alt are reused. Fields failing to
+// normalize are left out and just cannot be boosted.
+func (s *Service) withFields(ctx context.Context, comics Comics) Comics {
+	s.fieldsLock.Lock()
+	cached, ok := s.fields[comics.ID]
+	s.fieldsLock.Unlock()
+	if ok && cached.title == comics.Title && cached.alt == comics.Alt {
+		comics.Fields = cached.fields
+		return comics
+	}
+
+	normalized, err := s.normalizeBatch(ctx, []string{comics.Title, comics.Alt}, "")
+	if err != nil {
+		s.log.Warn("failed to normalize fields", "id", comics.ID, "error", err)
+		comics.Fields = map[Field][]string{}
+		return comics
+	}
+	comics.Fields = map[Field][]string{FieldTitle: normalized[0], FieldAlt: normalized[1]}
+	s.fieldsLock.Lock()
+	s.fields[comics.ID] = normalizedFields{title: comics.Title, alt: comics.Alt, fields: comics.Fields}
+	s.fieldsLock.Unlock()
+	return comics
+}
+
+// buildIndex fills a fresh index and swaps it in once complete, the
+// current one keeps serving meanwhile and stays when the build fails.
+func (s *Service) buildIndex(ctx context.Context) (int, error) {
+	index := NewIndex()
+	lastID, err := s.db.LastID(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var comicsCount int
 	for ID := 1; ID <= lastID; ID++ {
+		if err := s.throttle(ctx); err != nil {
+			return comicsCount, err
+		}
 		comics, err := s.db.Get(ctx, ID)
 		if err != nil {
 			if errors.Is(err, ErrNotFound) {
 				continue
 			}
 			s.log.Error("failed to fetch comics", "id", ID, "error", err)
-			return err
+			return comicsCount, err
 		}
-		s.index.Put(ID, comics.Keywords)
+		index.Put(s.withFields(ctx, comics))
 		comicsCount++
 	}
+	s.index.Replace(index)
 
 	s.log.Debug("rebuilt index", "comics count", comicsCount)
-	return nil
+	return comicsCount, nil
+}
+
+// throttle waits for the build rate, builds are not limited by default.
+func (s *Service) throttle(ctx context.Context) error {
+	if s.buildLimiter == nil {
+		return nil
+	}
+	return s.buildLimiter.Wait(ctx)
 }
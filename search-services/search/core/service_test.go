@@ -2,29 +2,59 @@ package core
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/liy0aay/xkcd-search/ngrams"
+	wordsnorm "github.com/liy0aay/xkcd-search/words/words"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 var noopLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
 
+// unpack splits a search result for terse assertions.
+func unpack(result SearchResult, err error) ([]Comics, int, error) {
+	return result.Comics, result.Total, err
+}
+
 type FakeWords struct {
 	normalized []string
 	err        error
+	language   string
 }
 
-func (fw *FakeWords) Norm(ctx context.Context, phrase string) ([]string, error) {
+func (fw *FakeWords) Norm(ctx context.Context, phrase, language string) ([]string, error) {
+	fw.language = language
 	if fw.err != nil {
 		return nil, fw.err
 	}
 	return fw.normalized, nil
 }
 
+func (fw *FakeWords) NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	return normEach(ctx, fw, phrases, language)
+}
+
+// normEach normalizes phrases one by one for fakes of Words.
+func normEach(ctx context.Context, words Words, phrases []string, language string) ([][]string, error) {
+	results := make([][]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		keywords, err := words.Norm(ctx, phrase, language)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, keywords)
+	}
+	return results, nil
+}
+
 type FakeDB struct {
 	searchResults map[string][]int
 	comics        map[int]Comics
@@ -32,6 +62,7 @@ type FakeDB struct {
 	searchErr     error
 	getErr        error
 	lastIDErr     error
+	pingErr       error
 }
 
 func (fd *FakeDB) Search(ctx context.Context, keyword string) ([]int, error) {
@@ -59,6 +90,49 @@ func (fd *FakeDB) LastID(ctx context.Context) (int, error) {
 	return fd.lastID, nil
 }
 
+func (fd *FakeDB) Neighbours(ctx context.Context, id int) (int, int, error) {
+	var prevID, nextID int
+	for ID := range fd.comics {
+		if ID < id && ID > prevID {
+			prevID = ID
+		}
+		if ID > id && (nextID == 0 || ID < nextID) {
+			nextID = ID
+		}
+	}
+	return prevID, nextID, nil
+}
+
+func (fd *FakeDB) Changes(
+	ctx context.Context, since time.Time, afterID, limit int, tombstones bool,
+) ([]Comics, error) {
+	var changed []Comics
+	for _, comics := range fd.comics {
+		after := comics.UpdatedAt.After(since) || comics.UpdatedAt.Equal(since) && comics.ID > afterID
+		if after && (tombstones || !comics.Deleted) {
+			changed = append(changed, comics)
+		}
+	}
+	slices.SortFunc(changed, func(a, b Comics) int {
+		return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return changed[:min(limit, len(changed))], nil
+}
+
+func (fd *FakeDB) ByTitle(ctx context.Context, title string) ([]Comics, error) {
+	var found []Comics
+	for _, comics := range fd.comics {
+		if strings.EqualFold(comics.Title, title) {
+			found = append(found, comics)
+		}
+	}
+	return found, nil
+}
+
+func (fd *FakeDB) Ping(ctx context.Context) error {
+	return fd.pingErr
+}
+
 func TestService_Search_HappyPath(t *testing.T) {
 	ctx := context.Background()
 	db := &FakeDB{
@@ -75,7 +149,7 @@ func TestService_Search_HappyPath(t *testing.T) {
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
-	result, err := svc.Search(ctx, "happy year", 10)
+	result, _, err := unpack(svc.Search(ctx, "happy year", 10, SearchOptions{}))
 
 	require.NoError(t, err)
 	require.Len(t, result, 2)
@@ -85,6 +159,313 @@ func TestService_Search_HappyPath(t *testing.T) {
 	assert.Equal(t, 1, result[1].Score)
 }
 
+func TestService_SearchIndex_Language(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"chat"}}
+	svc, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+
+	_, _, err = unpack(svc.SearchIndex(ctx, "chats", 10, SearchOptions{Language: "fr"}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "fr", words.language)
+}
+
+func TestService_SearchIndex_CursorAcrossIndexChange(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"happy", "year"}}
+	svc, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+	for ID, keywords := range map[int][]string{
+		1: {"happy", "year"}, 2: {"happy"}, 3: {"happy", "year"}, 4: {"year"}, 5: {"happy"},
+	} {
+		svc.index.Put(Comics{ID: ID, Keywords: keywords})
+	}
+
+	var seen []int
+	var after Cursor
+	for page := 0; ; page++ {
+		result, _, err := unpack(svc.SearchIndex(ctx, "happy year", 2, SearchOptions{After: after}))
+		require.NoError(t, err)
+		if len(result) == 0 {
+			break
+		}
+		for _, comics := range result {
+			seen = append(seen, comics.ID)
+		}
+		last := result[len(result)-1]
+		after = Cursor{Score: last.Score, ID: last.ID}
+		if page == 0 {
+			// dropping a seen comics shifts offsets, not cursors
+			svc.index.Delete(1)
+		}
+	}
+
+	assert.Equal(t, []int{1, 3, 2, 4, 5}, seen)
+}
+
+func TestService_SearchIndex_TitleBoostReorders(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{normalized: []string{"linux"}})
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"linux", "kernel"}, Fields: map[Field][]string{FieldTitle: {"kernel"}}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"linux"}, Fields: map[Field][]string{FieldTitle: {"linux"}}})
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, []int{1, 2}, []int{result[0].ID, result[1].ID})
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{Boosts: map[Field]int{FieldTitle: 3}}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, []int{2, 1}, []int{result[0].ID, result[1].ID})
+	assert.Equal(t, 3, result[0].Score)
+}
+
+// stemWords normalizes with the stemmers of the words service.
+type stemWords struct{}
+
+func (stemWords) Norm(_ context.Context, phrase, language string) ([]string, error) {
+	return wordsnorm.NormLanguage(phrase, language)
+}
+
+func (sw stemWords) NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	return normEach(ctx, sw, phrases, language)
+}
+
+// normalized builds comics the way the update service stores them, with
+// deduplicated keywords and the stems of the text in order.
+func normalized(id int, text string) Comics {
+	var stems []string
+	for _, token := range ngrams.Tokens(text) {
+		stems = append(stems, wordsnorm.Norm(token)...)
+	}
+	return Comics{ID: id, Keywords: wordsnorm.Norm(text), Stems: stems}
+}
+
+func TestService_SearchIndex_BM25(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 4,
+		comics: map[int]Comics{
+			1: normalized(1, "Linux, linux and more Linux kernels"),
+			2: normalized(2, "A Linux desktop"),
+			3: normalized(3, "The cat sat"),
+			4: normalized(4, "A cat and a dog"),
+		},
+	}
+
+	counting, err := NewService(noopLogger, db, stemWords{})
+	require.NoError(t, err)
+	require.NoError(t, counting.BuildIndex(ctx))
+	result, _, err := unpack(counting.SearchIndex(ctx, "linux", 2, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, []int{1, 2}, []int{result[0].ID, result[1].ID})
+	assert.Equal(t, 1, result[0].Score, "hit counts ignore repeats")
+	assert.Equal(t, 1, result[1].Score)
+
+	ranked, err := NewService(noopLogger, db, stemWords{}, WithBM25(1.2, 0.75))
+	require.NoError(t, err)
+	require.NoError(t, ranked.BuildIndex(ctx))
+	assert.Equal(t, 3, ranked.index.TermFreq(1, "linux"))
+	assert.Equal(t, 1, ranked.index.TermFreq(2, "linux"))
+	result, total, err := unpack(ranked.SearchIndex(ctx, "linux", 2, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, result[0].ID, "repeated keywords outweigh a shorter comics")
+	assert.Greater(t, result[0].Score, result[1].Score)
+}
+
+func TestService_SearchIndex_Phrase(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, splitWords{})
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"self", "driving", "car"}, Stems: []string{"self", "driving", "car"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"car", "driving", "self"}, Stems: []string{"car", "driving", "self"}})
+	svc.index.Put(Comics{
+		ID: 3, Keywords: []string{"self", "parking", "driving", "car"},
+		Stems: []string{"self", "parking", "driving", "car"},
+	})
+
+	ids := func(result []Comics) []int {
+		var found []int
+		for _, c := range result {
+			found = append(found, c.ID)
+		}
+		return found
+	}
+
+	result, total, err := unpack(svc.SearchIndex(ctx, "self driving", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, 3, total, "bag of words without phrase mode")
+	assert.ElementsMatch(t, []int{1, 2, 3}, ids(result))
+
+	result, total, err = unpack(svc.SearchIndex(ctx, "self driving", 10, SearchOptions{Phrase: true}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []int{1}, ids(result), "reversed and apart words do not match")
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "Driving car", 10, SearchOptions{Phrase: true}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, ids(result))
+}
+
+func TestService_Search_Phrase(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		searchResults: map[string][]int{"happy": {1, 2}, "year": {1, 2}},
+		comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"happy", "year"}, Stems: []string{"year", "happy"}},
+			2: {ID: 2, Keywords: []string{"happy", "year"}, Stems: []string{"new", "happy", "year"}},
+		},
+	}
+	svc, err := NewService(noopLogger, db, splitWords{})
+	require.NoError(t, err)
+
+	result, total, err := unpack(svc.Search(ctx, "happy year", 10, SearchOptions{Phrase: true}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, result, 1)
+	assert.Equal(t, 2, result[0].ID)
+}
+
+// splitWords normalizes by lowercasing and splitting on spaces.
+type splitWords struct{}
+
+func (splitWords) Norm(_ context.Context, phrase, _ string) ([]string, error) {
+	return strings.Fields(strings.ToLower(phrase)), nil
+}
+
+func (sw splitWords) NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	return normEach(ctx, sw, phrases, language)
+}
+
+func TestService_SearchIndex_FieldQuery(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, splitWords{})
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"python", "snake"},
+		Fields: map[Field][]string{FieldTitle: {"python"}, FieldAlt: {"snake"}}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"python", "snake"},
+		Fields: map[Field][]string{FieldTitle: {"snake"}, FieldAlt: {"python"}}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"python", "snake", "code"},
+		Fields: map[Field][]string{FieldTitle: {"python", "code"}, FieldAlt: {"snake"}}})
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "title:python alt:snake code", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, []int{3, 1}, []int{result[0].ID, result[1].ID})
+	assert.Equal(t, 3, result[0].Score)
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "python snake", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Len(t, result, 3, "bare terms match any field")
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "transcript:python", 10, SearchOptions{}))
+	require.NoError(t, err, "unknown fields are plain terms")
+	assert.Empty(t, result)
+}
+
+// batchCountingWords counts batches of splitWords.
+type batchCountingWords struct {
+	splitWords
+	batches int
+}
+
+func (w *batchCountingWords) NormBatch(ctx context.Context, phrases []string, language string) ([][]string, error) {
+	w.batches++
+	return w.splitWords.NormBatch(ctx, phrases, language)
+}
+
+func TestService_Search_FieldsNormalizedOnce(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		searchResults: map[string][]int{"python": {1}},
+		comics:        map[int]Comics{1: {ID: 1, Title: "Python", Alt: "snake", Keywords: []string{"python"}}},
+	}
+	words := &batchCountingWords{}
+	svc, err := NewService(noopLogger, db, words)
+	require.NoError(t, err)
+
+	for range 2 {
+		result, _, err := unpack(svc.Search(ctx, "title:python", 10, SearchOptions{}))
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+	}
+	assert.Equal(t, 1, words.batches, "title and alt in one batch, reused by the next search")
+
+	db.comics[1] = Comics{ID: 1, Title: "Python 3", Alt: "snake", Keywords: []string{"python"}}
+	_, _, err = unpack(svc.Search(ctx, "title:python", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, words.batches, "a changed title is normalized again")
+}
+
+func TestService_SearchIndex_StemsInOneBatch(t *testing.T) {
+	words := &batchCountingWords{}
+	svc, err := NewService(noopLogger, &FakeDB{}, words, WithNGrams(2))
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"self", "drive", "self drive"}})
+
+	result, _, err := unpack(svc.SearchIndex(context.Background(), "self drive", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 3, result[0].Score)
+	assert.Equal(t, 1, words.batches)
+}
+
+func TestService_SearchIndex_NGrams(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, splitWords{}, WithNGrams(2))
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"drive", "self", "car", "self car"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"self", "drive", "car", "self drive", "drive car"}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"car"}})
+
+	result, total, err := unpack(svc.SearchIndex(ctx, "self drive", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, result, 2)
+	assert.Equal(t, []int{2, 1}, []int{result[0].ID, result[1].ID})
+	assert.Equal(t, []int{3, 2}, []int{result[0].Score, result[1].Score})
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "self drive", 10, SearchOptions{MatchAll: true}))
+	require.NoError(t, err)
+	assert.Len(t, result, 2, "n-grams are not required to match all")
+
+	disabled, err := NewService(noopLogger, &FakeDB{}, splitWords{})
+	require.NoError(t, err)
+	disabled.index = svc.index
+	result, _, err = unpack(disabled.SearchIndex(ctx, "self drive", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, []int{result[0].ID, result[1].ID}, "ties are ordered by ID")
+}
+
+func TestParseQuery(t *testing.T) {
+	terms, fields := parseQuery("Title:python  alt:snake code 10:30 http://xkcd.com")
+	assert.Equal(t, "python snake code 10:30 http://xkcd.com", terms)
+	assert.Equal(t, map[Field]string{FieldTitle: "python", FieldAlt: "snake"}, fields)
+
+	terms, fields = parseQuery("re:zero author:randall")
+	assert.Equal(t, "re:zero author:randall", terms)
+	assert.Nil(t, fields)
+}
+
+func TestService_ReindexComic_Fields(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{comics: map[int]Comics{7: {ID: 7, Title: "Linux", Keywords: []string{"linux"}}}}
+	svc, err := NewService(noopLogger, db, &FakeWords{normalized: []string{"linux"}})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.ReindexComic(ctx, 7))
+
+	comics, ok := svc.index.Comic(7)
+	require.True(t, ok)
+	assert.Equal(t, []string{"linux"}, comics.Fields[FieldTitle])
+}
+
 func TestService_Search_NormalizationError(t *testing.T) {
 	ctx := context.Background()
 	db := &FakeDB{}
@@ -92,7 +473,7 @@ func TestService_Search_NormalizationError(t *testing.T) {
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
-	result, err := svc.Search(ctx, "invalid", 10)
+	result, _, err := unpack(svc.Search(ctx, "invalid", 10, SearchOptions{}))
 
 	require.Error(t, err)
 	require.Nil(t, result)
@@ -106,7 +487,7 @@ func TestService_Search_DBSearchError(t *testing.T) {
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
-	result, err := svc.Search(ctx, "test", 10)
+	result, _, err := unpack(svc.Search(ctx, "test", 10, SearchOptions{}))
 
 	require.Error(t, err)
 	require.Nil(t, result)
@@ -123,7 +504,7 @@ func TestService_Search_DBGetError(t *testing.T) {
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
-	result, err := svc.Search(ctx, "test", 10)
+	result, _, err := unpack(svc.Search(ctx, "test", 10, SearchOptions{}))
 
 	require.Error(t, err)
 	require.Nil(t, result)
@@ -148,12 +529,44 @@ func TestService_Search_LimitApplied(t *testing.T) {
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
-	result, err := svc.Search(ctx, "tree", 2)
+	result, _, err := unpack(svc.Search(ctx, "tree", 2, SearchOptions{}))
 
 	require.NoError(t, err)
 	require.Len(t, result, 2)
 }
 
+func TestService_Search_WordsFallback(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		searchResults: map[string][]int{"happy": {1}},
+		comics: map[int]Comics{
+			1: {ID: 1, URL: "http://xkcd.com/1", Keywords: []string{"happy"}},
+		},
+	}
+	words := &FakeWords{err: ErrUnavailable}
+	fallback := &FakeWords{normalized: []string{"happy"}}
+	svc, err := NewService(noopLogger, db, words, WithWordsFallback(fallback))
+	require.NoError(t, err)
+
+	result, _, err := unpack(svc.Search(ctx, "happy", 10, SearchOptions{}))
+
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 1, result[0].ID)
+}
+
+func TestService_Search_WordsUnavailableWithoutFallback(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{err: ErrUnavailable}
+	svc, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+
+	result, _, err := unpack(svc.Search(ctx, "happy", 10, SearchOptions{}))
+
+	require.ErrorIs(t, err, ErrUnavailable)
+	require.Nil(t, result)
+}
+
 func TestService_SearchIndex_HappyPath(t *testing.T) {
 	ctx := context.Background()
 	db := &FakeDB{
@@ -166,10 +579,10 @@ func TestService_SearchIndex_HappyPath(t *testing.T) {
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
-	svc.index.Put(1, []string{"happy"})
-	svc.index.Put(2, []string{"happy", "year"})
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"happy"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"happy", "year"}})
 
-	result, err := svc.SearchIndex(ctx, "happy year", 10)
+	result, _, err := unpack(svc.SearchIndex(ctx, "happy year", 10, SearchOptions{}))
 
 	require.NoError(t, err)
 	require.Len(t, result, 2)
@@ -177,75 +590,991 @@ func TestService_SearchIndex_HappyPath(t *testing.T) {
 	assert.Equal(t, 1, result[1].ID)
 }
 
-func TestService_BuildIndex_HappyPath(t *testing.T) {
+func TestService_SearchIndex_DBUnavailable(t *testing.T) {
 	ctx := context.Background()
 	db := &FakeDB{
 		lastID: 2,
 		comics: map[int]Comics{
-			1: {ID: 1, URL: "http://xkcd.com/1", Keywords: []string{"happy"}},
-			2: {ID: 2, URL: "http://xkcd.com/2", Keywords: []string{"new", "year"}},
+			1: {ID: 1, URL: "http://xkcd.com/1", Title: "Barrel", Keywords: []string{"barrel"}},
+			2: {ID: 2, URL: "http://xkcd.com/2", Keywords: []string{"petit"}},
 		},
 	}
-	words := &FakeWords{}
+	words := &FakeWords{normalized: []string{"barrel"}}
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
+	require.NoError(t, svc.BuildIndex(ctx))
 
-	err = svc.BuildIndex(ctx)
+	db.getErr = errors.New("db unavailable")
+	db.pingErr = errors.New("db unavailable")
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "barrel", 10, SearchOptions{}))
 
 	require.NoError(t, err)
-	assert.Len(t, svc.index.Get("happy"), 1)
-	assert.Len(t, svc.index.Get("new"), 1)
-	assert.Len(t, svc.index.Get("year"), 1)
+	require.Len(t, result, 1)
+	assert.Equal(t, "Barrel", result[0].Title)
+	assert.ErrorIs(t, svc.Ready(ctx), ErrDegraded)
+
+	svc.index.Clear()
+	assert.ErrorIs(t, svc.Ready(ctx), ErrUnavailable)
 }
 
-func TestService_BuildIndex_IgnoresNotFound(t *testing.T) {
+func TestService_SearchIndex_EarlyTermination(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"common", "often", "rare", "scarce"}}
+	exhaustive, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+	early, err := NewService(noopLogger, &FakeDB{}, words, WithEarlyTermination())
+	require.NoError(t, err)
+
+	for ID := 1; ID <= 1000; ID++ {
+		keywords := []string{"common"}
+		if ID <= 50 {
+			keywords = append(keywords, "often")
+		}
+		if ID == 7 || ID == 42 {
+			keywords = append(keywords, "rare", "scarce")
+		}
+		exhaustive.index.Put(Comics{ID: ID, Keywords: keywords})
+		early.index.Put(Comics{ID: ID, Keywords: keywords})
+	}
+
+	want, wantTotal, err := unpack(exhaustive.SearchIndex(ctx, "common often rare scarce", 2, SearchOptions{}))
+	require.NoError(t, err)
+	got, total, err := unpack(early.SearchIndex(ctx, "common often rare scarce", 2, SearchOptions{}))
+	require.NoError(t, err)
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, []int{7, 42}, []int{got[0].ID, got[1].ID})
+	assert.Equal(t, 4, got[0].Score)
+	assert.Equal(t, 1000, wantTotal)
+	assert.Equal(t, 1000, total, "early termination still counts every candidate")
+	top, candidates := early.scoreIndex([]string{"common", "often", "rare", "scarce"}, 2, true)
+	assert.Len(t, top, 2)
+	assert.Equal(t, 1000, candidates)
+}
+
+func TestTopScores(t *testing.T) {
+	scores := map[int]int{1: 1, 2: 5, 3: 3, 4: 5, 5: 2}
+
+	top, settled := topScores(scores, 2, 1)
+	assert.True(t, settled)
+	assert.Equal(t, map[int]int{2: 5, 4: 5}, top)
+
+	_, settled = topScores(scores, 2, 2)
+	assert.False(t, settled, "comics 3 could tie")
+	_, settled = topScores(scores, 3, 1)
+	assert.False(t, settled, "comics 5 could outrank comics 3 by ID")
+
+	_, settled = topScores(scores, 5, 1)
+	assert.False(t, settled, "unseen comics could tie comics 1")
+	top, settled = topScores(scores, 5, 0)
+	assert.True(t, settled)
+	assert.Equal(t, scores, top)
+}
+
+func TestService_SearchIndex_TagFilter(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"love"}}
+	svc, err := NewService(noopLogger, &FakeDB{}, words, WithEarlyTermination())
+	require.NoError(t, err)
+
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"love"}, Tags: []string{"romance"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"love"}, Tags: []string{"math"}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"love"}, Tags: []string{"math", "romance"}})
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "love", 10, SearchOptions{Tag: "math"}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.ElementsMatch(t, []int{2, 3}, []int{result[0].ID, result[1].ID})
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "love", 1, SearchOptions{Tag: "romance"}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Contains(t, result[0].Tags, "romance")
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "love", 10, SearchOptions{Tag: " Math "}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.ElementsMatch(t, []int{2, 3}, []int{result[0].ID, result[1].ID}, "tags are matched like they are stored")
+}
+
+func TestService_SearchIndex_MatchAllAndOffset(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"math", "love"}}
+	svc, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"math", "love"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"math"}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"love", "math", "cat"}})
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "math love", 10, SearchOptions{MatchAll: true}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.ElementsMatch(t, []int{1, 3}, []int{result[0].ID, result[1].ID})
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "math love", 10, SearchOptions{Offset: 2}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 2, result[0].ID)
+}
+
+func TestService_SearchIndex_Synonyms(t *testing.T) {
 	ctx := context.Background()
 	db := &FakeDB{
-		lastID: 3,
 		comics: map[int]Comics{
-			1: {ID: 1, Keywords: []string{"a"}},
-			3: {ID: 3, Keywords: []string{"b"}},
+			1: {ID: 1, URL: "http://xkcd.com/1"},
+			2: {ID: 2, URL: "http://xkcd.com/2"},
 		},
 	}
-	words := &FakeWords{}
-	svc, err := NewService(noopLogger, db, words)
+	words := &FakeWords{normalized: []string{"js"}}
+	svc, err := NewService(noopLogger, db, words, WithSynonyms(map[string][]string{
+		"js": {"javascript"},
+	}))
 	require.NoError(t, err)
 
-	err = svc.BuildIndex(ctx)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"javascript"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"python"}})
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "js", 10, SearchOptions{}))
 
 	require.NoError(t, err)
-	assert.Len(t, svc.index.Get("a"), 1)
-	assert.Len(t, svc.index.Get("b"), 1)
+	require.Len(t, result, 1)
+	assert.Equal(t, 1, result[0].ID)
 }
 
-func TestService_BuildIndex_LastIDError(t *testing.T) {
+func TestService_BuildIndex_HappyPath(t *testing.T) {
 	ctx := context.Background()
-	db := &FakeDB{lastIDErr: errors.New("db error")}
+	db := &FakeDB{
+		lastID: 2,
+		comics: map[int]Comics{
+			1: {ID: 1, URL: "http://xkcd.com/1", Keywords: []string{"happy"}},
+			2: {ID: 2, URL: "http://xkcd.com/2", Keywords: []string{"new", "year"}},
+		},
+	}
 	words := &FakeWords{}
 	svc, err := NewService(noopLogger, db, words)
 	require.NoError(t, err)
 
 	err = svc.BuildIndex(ctx)
 
-	require.Error(t, err)
-	assert.Equal(t, "db error", err.Error())
+	require.NoError(t, err)
+	assert.Len(t, svc.index.Get("happy"), 1)
+	assert.Len(t, svc.index.Get("new"), 1)
+	assert.Len(t, svc.index.Get("year"), 1)
 }
 
-func TestService_BuildIndex_GetError(t *testing.T) {
+func TestService_ReindexComic(t *testing.T) {
 	ctx := context.Background()
 	db := &FakeDB{
 		lastID: 2,
 		comics: map[int]Comics{
-			1: {ID: 1, Keywords: []string{"a"}},
+			1: {ID: 1, Keywords: []string{"happy", "year"}},
+			2: {ID: 2, Keywords: []string{"year"}},
 		},
-		getErr: errors.New("fetch error"),
 	}
-	words := &FakeWords{}
-	svc, err := NewService(noopLogger, db, words)
+	svc, err := NewService(noopLogger, db, &FakeWords{})
 	require.NoError(t, err)
+	require.NoError(t, svc.BuildIndex(ctx))
 
-	err = svc.BuildIndex(ctx)
+	db.comics[1] = Comics{ID: 1, Keywords: []string{"sad", "year"}}
+	err = svc.ReindexComic(ctx, 1)
 
-	require.Error(t, err)
-	assert.Equal(t, "fetch error", err.Error())
+	require.NoError(t, err)
+	assert.Empty(t, svc.index.Get("happy"))
+	assert.Equal(t, []int{1}, svc.index.Get("sad"))
+	assert.ElementsMatch(t, []int{1, 2}, svc.index.Get("year"))
+	assert.Equal(t, 1, svc.index.Count("sad"))
+	comics, ok := svc.index.Comic(1)
+	require.True(t, ok)
+	assert.Equal(t, []string{"sad", "year"}, comics.Keywords)
+}
+
+func TestService_ReindexComic_GoneFromDB(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 2,
+		comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"happy", "year"}},
+			2: {ID: 2, Keywords: []string{"year"}},
+		},
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+	require.NoError(t, svc.BuildIndex(ctx))
+
+	delete(db.comics, 1)
+	err = svc.ReindexComic(ctx, 1)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, ok := svc.index.Comic(1)
+	assert.False(t, ok)
+	assert.Empty(t, svc.index.Get("happy"))
+	assert.Equal(t, []int{2}, svc.index.Get("year"))
+}
+
+func TestService_ClearIndex(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 1,
+		comics: map[int]Comics{1: {ID: 1, Keywords: []string{"happy"}}},
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{normalized: []string{"happy"}})
+	require.NoError(t, err)
+	require.NoError(t, svc.BuildIndex(ctx))
+
+	// the drop event arrives before the DB is refilled
+	require.NoError(t, svc.ClearIndex(ctx))
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "happy", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Zero(t, svc.index.Size())
+}
+
+func TestService_ReindexComic_NotFound(t *testing.T) {
+	svc, err := NewService(noopLogger, &FakeDB{comics: map[int]Comics{}}, &FakeWords{})
+	require.NoError(t, err)
+
+	err = svc.ReindexComic(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_ReindexRange(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 4,
+		comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"old"}},
+			2: {ID: 2, Keywords: []string{"old"}},
+			3: {ID: 3, Keywords: []string{"old"}},
+			4: {ID: 4, Keywords: []string{"old"}},
+		},
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+	require.NoError(t, svc.BuildIndex(ctx))
+
+	for ID := 1; ID <= 4; ID++ {
+		db.comics[ID] = Comics{ID: ID, Keywords: []string{"new"}}
+	}
+	delete(db.comics, 3)
+	comicsCount, err := svc.ReindexRange(ctx, 2, 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, comicsCount)
+	assert.Equal(t, []int{1, 4}, svc.index.Get("old"))
+	assert.Equal(t, []int{2}, svc.index.Get("new"))
+	_, ok := svc.index.Comic(3)
+	assert.False(t, ok)
+}
+
+func TestService_ReindexRange_BadRange(t *testing.T) {
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{})
+	require.NoError(t, err)
+
+	_, err = svc.ReindexRange(context.Background(), 0, 3)
+	assert.ErrorIs(t, err, ErrBadArguments)
+	_, err = svc.ReindexRange(context.Background(), 5, 4)
+	assert.ErrorIs(t, err, ErrBadArguments)
+	_, err = svc.ReindexRange(context.Background(), 1, maxReindexRange+1)
+	assert.ErrorIs(t, err, ErrBadArguments)
+}
+
+// blockingDB holds Get until released, after telling it started.
+type blockingDB struct {
+	*FakeDB
+	started chan struct{}
+	release chan struct{}
+}
+
+func (db *blockingDB) Get(ctx context.Context, id int) (Comics, error) {
+	db.started <- struct{}{}
+	<-db.release
+	return db.FakeDB.Get(ctx, id)
+}
+
+func TestService_ReindexRange_Overlapping(t *testing.T) {
+	ctx := context.Background()
+	db := &blockingDB{
+		FakeDB:  &FakeDB{comics: map[int]Comics{1: {ID: 1, Keywords: []string{"a"}}}},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := svc.ReindexRange(ctx, 1, 1)
+		done <- err
+	}()
+	<-db.started
+
+	_, err = svc.ReindexRange(ctx, 1, 1)
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	close(db.release)
+	require.NoError(t, <-done)
+}
+
+func TestService_ReindexRange_DuringBuild(t *testing.T) {
+	ctx := context.Background()
+	db := &blockingDB{
+		FakeDB: &FakeDB{lastID: 2, comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"old"}},
+			2: {ID: 2, Keywords: []string{"old"}},
+		}},
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	built := make(chan error)
+	go func() {
+		built <- svc.BuildIndex(ctx)
+	}()
+	<-db.started
+	db.release <- struct{}{}
+	<-db.started
+	// the build already has the old comics 1
+	db.comics[1] = Comics{ID: 1, Keywords: []string{"new"}}
+	reindexed := make(chan error)
+	go func() {
+		_, err := svc.ReindexRange(ctx, 1, 1)
+		reindexed <- err
+	}()
+
+	close(db.release)
+	require.NoError(t, <-built)
+	require.NoError(t, <-reindexed)
+	assert.Equal(t, []int{1}, svc.index.Get("new"))
+}
+
+// timedDB records when comics are fetched.
+type timedDB struct {
+	*FakeDB
+	gets []time.Time
+}
+
+func (db *timedDB) Get(ctx context.Context, id int) (Comics, error) {
+	db.gets = append(db.gets, time.Now())
+	return db.FakeDB.Get(ctx, id)
+}
+
+func TestService_BuildIndex_BuildRate(t *testing.T) {
+	ctx := context.Background()
+	comics := map[int]Comics{}
+	for ID := 1; ID <= 5; ID++ {
+		comics[ID] = Comics{ID: ID, Keywords: []string{"linux"}}
+	}
+	db := &timedDB{FakeDB: &FakeDB{lastID: 5, comics: comics}}
+	svc, err := NewService(noopLogger, db, &FakeWords{}, WithBuildRate(50))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.BuildIndex(ctx))
+
+	assert.Equal(t, 5, svc.index.Size())
+	require.Len(t, db.gets, 5)
+	// single waits jitter, the limiter keeps the pace over the whole build
+	assert.GreaterOrEqual(t, db.gets[4].Sub(db.gets[0]), 70*time.Millisecond)
+}
+
+func TestService_BuildIndex_BuildRateCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := &FakeDB{lastID: 100, comics: map[int]Comics{}}
+	svc, err := NewService(noopLogger, db, &FakeWords{}, WithBuildRate(1))
+	require.NoError(t, err)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	assert.ErrorIs(t, svc.BuildIndex(ctx), context.Canceled)
+}
+
+// failingDB fails to get comics of some IDs.
+type failingDB struct {
+	*FakeDB
+	failing map[int]bool
+}
+
+func (db *failingDB) Get(ctx context.Context, id int) (Comics, error) {
+	if db.failing[id] {
+		return Comics{}, errors.New("connection reset")
+	}
+	return db.FakeDB.Get(ctx, id)
+}
+
+func TestService_Search_Lenient(t *testing.T) {
+	ctx := context.Background()
+	db := &failingDB{
+		FakeDB: &FakeDB{
+			searchResults: map[string][]int{"linux": {1, 2, 3}},
+			comics: map[int]Comics{
+				1: {ID: 1, Keywords: []string{"linux"}},
+				2: {ID: 2, Keywords: []string{"linux"}},
+				3: {ID: 3, Keywords: []string{"linux"}},
+			},
+		},
+		failing: map[int]bool{2: true},
+	}
+	words := &FakeWords{normalized: []string{"linux"}}
+
+	strict, err := NewService(noopLogger, db, words)
+	require.NoError(t, err)
+	_, _, err = unpack(strict.Search(ctx, "linux", 10, SearchOptions{}))
+	require.Error(t, err)
+
+	lenient, err := NewService(noopLogger, db, words, WithLenientFetch())
+	require.NoError(t, err)
+	found, err := lenient.Search(ctx, "linux", 10, SearchOptions{})
+	require.NoError(t, err, "partial results are no failure")
+	assert.Equal(t, 1, found.Failed)
+	assert.Equal(t, 3, found.Total)
+	require.Len(t, found.Comics, 2)
+	assert.Equal(t, []int{1, 3}, []int{found.Comics[0].ID, found.Comics[1].ID})
+
+	db.failing = map[int]bool{1: true, 2: true, 3: true}
+	_, _, err = unpack(lenient.Search(ctx, "linux", 10, SearchOptions{}))
+	require.Error(t, err, "nothing fetched is a failure")
+}
+
+func TestService_Search_StopWordsRejected(t *testing.T) {
+	ctx := context.Background()
+	// any keyword lookup or fetch fails the search with a DB error
+	db := &FakeDB{searchErr: errors.New("db searched"), getErr: errors.New("db fetched")}
+	words := &FakeWords{}
+
+	svc, err := NewService(noopLogger, db, words, WithStopWordsRejected())
+	require.NoError(t, err)
+	_, _, err = unpack(svc.Search(ctx, "the and or", 10, SearchOptions{}))
+	require.ErrorIs(t, err, ErrStopWords)
+	assert.ErrorIs(t, err, ErrBadArguments)
+	_, _, err = unpack(svc.SearchIndex(ctx, "the and or", 10, SearchOptions{Phrase: true}))
+	require.ErrorIs(t, err, ErrStopWords)
+
+	words.normalized = []string{"linux"}
+	_, _, err = unpack(svc.Search(ctx, "the linux", 10, SearchOptions{}))
+	assert.EqualError(t, err, "db searched")
+	_, _, err = unpack(svc.Search(ctx, "title:linux", 10, SearchOptions{}))
+	assert.EqualError(t, err, "db searched", "field terms are not stop words")
+	_, _, err = unpack(svc.SearchIndex(ctx, "title:linux", 10, SearchOptions{}))
+	assert.NoError(t, err)
+
+	lax, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+	result, total, err := unpack(lax.Search(ctx, "the and or", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Zero(t, total)
+}
+
+func TestService_BuildIndex_IgnoresNotFound(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 3,
+		comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"a"}},
+			3: {ID: 3, Keywords: []string{"b"}},
+		},
+	}
+	words := &FakeWords{}
+	svc, err := NewService(noopLogger, db, words)
+	require.NoError(t, err)
+
+	err = svc.BuildIndex(ctx)
+
+	require.NoError(t, err)
+	assert.Len(t, svc.index.Get("a"), 1)
+	assert.Len(t, svc.index.Get("b"), 1)
+}
+
+func TestService_LastID(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{lastID: 3002}, &FakeWords{})
+	require.NoError(t, err)
+
+	ID, err := svc.LastID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3002, ID)
+
+	svc, err = NewService(noopLogger, &FakeDB{}, &FakeWords{})
+	require.NoError(t, err)
+
+	ID, err = svc.LastID(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, ID)
+}
+
+func TestService_Changes(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &FakeDB{comics: map[int]Comics{
+		1: {ID: 1, UpdatedAt: base},
+		2: {ID: 2, UpdatedAt: base.Add(2 * time.Hour)},
+		3: {ID: 3, UpdatedAt: base.Add(time.Hour)},
+	}}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	changed, err := svc.Changes(ctx, base, 0, 1, false)
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, 1, changed[0].ID, "no after ID starts at since")
+
+	changed, err = svc.Changes(ctx, base, 1, 1, false)
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, 3, changed[0].ID)
+
+	changed, err = svc.Changes(ctx, changed[0].UpdatedAt, changed[0].ID, 10, false)
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, 2, changed[0].ID)
+
+	_, err = svc.Changes(ctx, base, 0, 0, false)
+	assert.ErrorIs(t, err, ErrBadArguments)
+	_, err = svc.Changes(ctx, base, -1, 10, false)
+	assert.ErrorIs(t, err, ErrBadArguments)
+}
+
+func TestService_Changes_SameTimestamp(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &FakeDB{comics: map[int]Comics{}}
+	for ID := 1; ID <= 5; ID++ {
+		db.comics[ID] = Comics{ID: ID, UpdatedAt: base.Add(time.Hour)}
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	var seen []int
+	since, afterID := base, 0
+	for range 3 {
+		changed, err := svc.Changes(ctx, since, afterID, 2, false)
+		require.NoError(t, err)
+		for _, c := range changed {
+			seen = append(seen, c.ID)
+			since, afterID = c.UpdatedAt, c.ID
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, seen, "comics changed together span pages")
+}
+
+func TestService_ChangesTombstones(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &FakeDB{comics: map[int]Comics{
+		1: {ID: 1, UpdatedAt: base.Add(time.Hour)},
+		2: {ID: 2, UpdatedAt: base.Add(2 * time.Hour), Deleted: true},
+	}}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	changed, err := svc.Changes(ctx, base, 0, 10, false)
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, 1, changed[0].ID)
+
+	changed, err = svc.Changes(ctx, base, 0, 10, true)
+	require.NoError(t, err)
+	require.Len(t, changed, 2)
+	assert.Equal(t, 2, changed[1].ID)
+	assert.True(t, changed[1].Deleted)
+}
+
+func TestService_BuildHistory(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 1,
+		comics: map[int]Comics{1: {ID: 1, Keywords: []string{"a"}}},
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.BuildIndex(ctx))
+	db.lastIDErr = errors.New("db error")
+	require.Error(t, svc.BuildIndex(ctx))
+
+	history := svc.BuildHistory(ctx)
+	require.Len(t, history, 2)
+	assert.Equal(t, 1, history[0].Comics)
+	assert.Empty(t, history[0].Error)
+	assert.Equal(t, "db error", history[1].Error)
+	assert.False(t, history[1].StartedAt.Before(history[0].StartedAt))
+}
+
+func TestService_BuildIndex_LastIDError(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{lastIDErr: errors.New("db error")}
+	words := &FakeWords{}
+	svc, err := NewService(noopLogger, db, words)
+	require.NoError(t, err)
+
+	err = svc.BuildIndex(ctx)
+
+	require.Error(t, err)
+	assert.Equal(t, "db error", err.Error())
+}
+
+func TestService_BuildIndex_GetError(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		lastID: 2,
+		comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"a"}},
+		},
+		getErr: errors.New("fetch error"),
+	}
+	words := &FakeWords{}
+	svc, err := NewService(noopLogger, db, words)
+	require.NoError(t, err)
+
+	err = svc.BuildIndex(ctx)
+
+	require.Error(t, err)
+	assert.Equal(t, "fetch error", err.Error())
+}
+
+// gatedDB holds fetching comics ID until released.
+type gatedDB struct {
+	*FakeDB
+	ID       int
+	reached  chan struct{}
+	released chan struct{}
+}
+
+func (db *gatedDB) Get(ctx context.Context, id int) (Comics, error) {
+	if id == db.ID {
+		close(db.reached)
+		<-db.released
+	}
+	return db.FakeDB.Get(ctx, id)
+}
+
+func TestService_BuildIndex_SwapsWhenComplete(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"linux"}}
+	svc, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 9, Keywords: []string{"linux"}})
+
+	db := &gatedDB{
+		FakeDB: &FakeDB{lastID: 3, comics: map[int]Comics{
+			1: {ID: 1, Keywords: []string{"linux"}},
+			3: {ID: 3, Keywords: []string{"linux"}},
+		}},
+		ID: 3, reached: make(chan struct{}), released: make(chan struct{}),
+	}
+	svc.db = db
+	built := make(chan error)
+	go func() { built <- svc.BuildIndex(ctx) }()
+
+	<-db.reached
+	result, _, err := unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 9, result[0].ID, "the old index serves during a build")
+	close(db.released)
+	require.NoError(t, <-built)
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, []int{1, 3}, []int{result[0].ID, result[1].ID})
+
+	db.getErr = errors.New("fetch error")
+	require.Error(t, svc.BuildIndex(ctx))
+	assert.Equal(t, 2, svc.index.Size(), "a failed build keeps the index")
+}
+
+func TestService_Neighbours_AcrossGap(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		comics: map[int]Comics{
+			403: {ID: 403},
+			405: {ID: 405},
+			406: {ID: 406},
+		},
+	}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	prevID, nextID, err := svc.Neighbours(ctx, 405)
+	require.NoError(t, err)
+	assert.Equal(t, 403, prevID)
+	assert.Equal(t, 406, nextID)
+
+	prevID, nextID, err = svc.Neighbours(ctx, 403)
+	require.NoError(t, err)
+	assert.Equal(t, 0, prevID)
+	assert.Equal(t, 405, nextID)
+}
+
+func TestService_DocumentFrequency(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{})
+	require.NoError(t, err)
+
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"common", "rare"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"common"}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"common"}})
+
+	svc.words = &FakeWords{normalized: []string{"common"}}
+	common, err := svc.DocumentFrequency(ctx, "common")
+	require.NoError(t, err)
+
+	svc.words = &FakeWords{normalized: []string{"rare"}}
+	rare, err := svc.DocumentFrequency(ctx, "rare")
+	require.NoError(t, err)
+
+	svc.words = &FakeWords{normalized: []string{"unknown"}}
+	unknown, err := svc.DocumentFrequency(ctx, "unknown")
+	require.NoError(t, err)
+
+	assert.Greater(t, common["common"], rare["rare"])
+	assert.Equal(t, 3, common["common"])
+	assert.Equal(t, 1, rare["rare"])
+	assert.Equal(t, map[string]int{"unknown": 0}, unknown)
+}
+
+func TestService_DocumentFrequencyReturnsCopy(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{normalized: []string{"common"}})
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"common"}})
+
+	first, err := svc.DocumentFrequency(ctx, "common")
+	require.NoError(t, err)
+	first["common"] = 100
+	first["injected"] = 1
+
+	second, err := svc.DocumentFrequency(ctx, "common")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"common": 1}, second)
+	assert.Equal(t, 1, svc.index.Count("common"))
+}
+
+func TestService_ByTitle(t *testing.T) {
+	db := &FakeDB{comics: map[int]Comics{
+		353: {ID: 353, Title: "Python"},
+		354: {ID: 354, Title: "Python Environment"},
+	}}
+	svc, err := NewService(noopLogger, db, &FakeWords{})
+	require.NoError(t, err)
+
+	found, err := svc.ByTitle(context.Background(), " python ")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, 353, found[0].ID)
+
+	_, err = svc.ByTitle(context.Background(), "Pytho")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = svc.ByTitle(context.Background(), " ")
+	assert.ErrorIs(t, err, ErrBadArguments)
+}
+
+type slowWords struct {
+	FakeWords
+	delay time.Duration
+}
+
+func (sw *slowWords) Norm(ctx context.Context, phrase, language string) ([]string, error) {
+	time.Sleep(sw.delay)
+	return sw.FakeWords.Norm(ctx, phrase, language)
+}
+
+func TestService_SlowSearchLog(t *testing.T) {
+	ctx := context.Background()
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	words := &slowWords{FakeWords: FakeWords{normalized: []string{"linux"}}}
+	svc, err := NewService(log, &FakeDB{}, words, WithSlowSearchLog(20*time.Millisecond))
+	require.NoError(t, err)
+
+	_, _, err = unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.NotContains(t, logs.String(), "slow search")
+
+	words.delay = 30 * time.Millisecond
+	_, _, err = unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "level=WARN msg=\"slow search\" phrase=linux keywords=[linux] results=0")
+}
+
+func TestService_WithoutIndex(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{
+		searchResults: map[string][]int{"linux": {1}},
+		comics:        map[int]Comics{1: {ID: 1, URL: "http://xkcd.com/1"}},
+		lastIDErr:     errors.New("must not be called"),
+	}
+	words := &FakeWords{normalized: []string{"linux"}}
+	svc, err := NewService(noopLogger, db, words, WithoutIndex())
+	require.NoError(t, err)
+
+	require.NoError(t, svc.BuildIndex(ctx))
+	require.NoError(t, svc.ReindexComic(ctx, 1))
+	assert.Empty(t, svc.BuildHistory(ctx))
+	assert.Zero(t, svc.index.Size())
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "linux", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 1, result[0].ID)
+}
+
+func TestService_IndexStats(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{lastID: 1, comics: map[int]Comics{1: {ID: 1, URL: "http://xkcd.com/1", Keywords: []string{"linux", "kernel"}}}}
+	svc, err := NewService(noopLogger, db, &FakeWords{normalized: []string{"linux"}})
+	require.NoError(t, err)
+
+	assert.True(t, svc.IndexStats(ctx).BuiltAt.IsZero())
+
+	require.NoError(t, svc.BuildIndex(ctx))
+	stats := svc.IndexStats(ctx)
+	assert.Equal(t, 1, stats.Comics)
+	assert.Equal(t, 2, stats.Keywords)
+	assert.False(t, stats.BuiltAt.IsZero())
+}
+
+func TestService_MaxTermsKeepsDistinctive(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{normalized: []string{"car"}},
+		WithSynonyms(map[string][]string{"car": {"auto", "vehicle", "automobile"}}),
+		WithMaxTerms(2),
+	)
+	require.NoError(t, err)
+	// car is in 3 comics, vehicle in 2, automobile in 1, auto in none
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"car", "vehicle", "automobile"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"car", "vehicle"}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"car"}})
+
+	assert.Equal(t, []string{"car", "automobile"},
+		svc.capTerms([]string{"car"}, []string{"auto", "vehicle", "automobile"}), "query keywords are kept")
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "car", 10, SearchOptions{MatchAll: true}))
+	require.NoError(t, err)
+	assert.Len(t, result, 3, "every comics with car is found")
+}
+
+func TestService_SearchIndex_PrefixWildcard(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, splitWords{})
+	require.NoError(t, err)
+	svc.index.Put(Comics{ID: 1, Keywords: []string{"python", "snake"}})
+	svc.index.Put(Comics{ID: 2, Keywords: []string{"pythonic", "code"}})
+	svc.index.Put(Comics{ID: 3, Keywords: []string{"pyramid"}})
+	svc.index.Put(Comics{ID: 4, Keywords: []string{"python", "pythons"}})
+
+	result, _, err := unpack(svc.SearchIndex(ctx, "Pyth*", 10, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, []int{1, 2, 4}, []int{result[0].ID, result[1].ID, result[2].ID})
+	assert.Equal(t, 1, result[2].Score, "keys sharing the prefix count once")
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "pyth* snake", 10, SearchOptions{MatchAll: true}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 1, result[0].ID)
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "python", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Len(t, result, 2, "bare terms match exactly")
+
+	result, _, err = unpack(svc.SearchIndex(ctx, "pyth", 10, SearchOptions{}))
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestSplitPrefixes(t *testing.T) {
+	terms, prefixes := splitPrefixes("Pyth* snake * a**")
+	assert.Equal(t, "snake * a**", terms)
+	assert.Equal(t, []string{"pyth*"}, prefixes)
+}
+
+func TestService_SearchIndex_StableOrder(t *testing.T) {
+	ctx := context.Background()
+	words := &FakeWords{normalized: []string{"linux", "cat"}}
+	exhaustive, err := NewService(noopLogger, &FakeDB{}, words)
+	require.NoError(t, err)
+	early, err := NewService(noopLogger, &FakeDB{}, words, WithEarlyTermination())
+	require.NoError(t, err)
+	for ID := 1; ID <= 200; ID++ {
+		keywords := []string{"linux"}
+		if ID%3 == 0 {
+			keywords = append(keywords, "cat")
+		}
+		exhaustive.index.Put(Comics{ID: ID, Keywords: keywords})
+		early.index.Put(Comics{ID: ID, Keywords: keywords})
+	}
+
+	first, _, err := unpack(exhaustive.SearchIndex(ctx, "linux cat", 20, SearchOptions{}))
+	require.NoError(t, err)
+	require.Len(t, first, 20)
+	assert.Equal(t, []int{3, 6, 9}, []int{first[0].ID, first[1].ID, first[2].ID})
+	for range 50 {
+		again, _, err := unpack(exhaustive.SearchIndex(ctx, "linux cat", 20, SearchOptions{}))
+		require.NoError(t, err)
+		require.Equal(t, first, again)
+		again, _, err = unpack(early.SearchIndex(ctx, "linux cat", 20, SearchOptions{}))
+		require.NoError(t, err)
+		require.Equal(t, first, again, "early termination keeps the order")
+	}
+}
+
+func TestService_Search_TotalCountedFromIndex(t *testing.T) {
+	ctx := context.Background()
+	comics := map[int]Comics{}
+	var IDs []int
+	for ID := 1; ID <= 10; ID++ {
+		var tags []string
+		if ID%2 == 0 {
+			tags = []string{"even"}
+		}
+		comics[ID] = Comics{ID: ID, Keywords: []string{"linux"}, Tags: tags}
+		IDs = append(IDs, ID)
+	}
+	db := &timedDB{FakeDB: &FakeDB{searchResults: map[string][]int{"linux": IDs}, comics: comics}}
+	svc, err := NewService(noopLogger, db, &FakeWords{normalized: []string{"linux"}})
+	require.NoError(t, err)
+	for _, c := range comics {
+		svc.index.Put(c)
+	}
+
+	result, total, err := unpack(svc.Search(ctx, "linux", 2, SearchOptions{Tag: "even"}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 4}, []int{result[0].ID, result[1].ID})
+	assert.Equal(t, 5, total)
+	assert.Len(t, db.gets, 4, "only comics up to the page end are fetched")
+}
+
+func TestService_SearchIndex_TotalBeforePaging(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(noopLogger, &FakeDB{}, &FakeWords{normalized: []string{"linux"}})
+	require.NoError(t, err)
+	for ID := 1; ID <= 10; ID++ {
+		var tags []string
+		if ID%2 == 0 {
+			tags = []string{"even"}
+		}
+		svc.index.Put(Comics{ID: ID, Keywords: []string{"linux"}, Tags: tags})
+	}
+
+	result, total, err := unpack(svc.SearchIndex(ctx, "linux", 3, SearchOptions{Offset: 3}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 5, 6}, []int{result[0].ID, result[1].ID, result[2].ID})
+	assert.Equal(t, 10, total)
+
+	result, total, err = unpack(svc.SearchIndex(ctx, "linux", 2, SearchOptions{Tag: "even", Offset: 1}))
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 6}, []int{result[0].ID, result[1].ID})
+	assert.Equal(t, 5, total, "only comics matching the tag count")
+
+	result, total, err = unpack(svc.SearchIndex(ctx, "linux", 3, SearchOptions{Offset: 20}))
+	require.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, 10, total)
 }
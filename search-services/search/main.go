@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -10,14 +11,18 @@ import (
 	"os/signal"
 
 	"github.com/liy0aay/xkcd-search/closers"
+	"github.com/liy0aay/xkcd-search/metrics"
 	searchpb "github.com/liy0aay/xkcd-search/proto/search"
 	"github.com/liy0aay/xkcd-search/search/adapters/db"
 	searchgrpc "github.com/liy0aay/xkcd-search/search/adapters/grpc"
 	"github.com/liy0aay/xkcd-search/search/adapters/initiator"
 	searchnats "github.com/liy0aay/xkcd-search/search/adapters/nats"
+	"github.com/liy0aay/xkcd-search/search/adapters/synonyms"
 	"github.com/liy0aay/xkcd-search/search/adapters/words"
 	"github.com/liy0aay/xkcd-search/search/config"
 	"github.com/liy0aay/xkcd-search/search/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -47,49 +52,128 @@ func run(cfg config.Config, log *slog.Logger) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	// dependencies have to be ready within startup timeout
+	startCtx, cancel := context.WithTimeout(ctx, cfg.StartupTimeout)
+	defer cancel()
+
 	// database adapter
-	storage, err := db.New(log, cfg.DBAddress)
+	storage, err := db.New(startCtx, log, cfg.DBAddress, cfg.DBTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to connect to db: %v", err)
 	}
 	defer closers.CloseOrLog(storage, log)
+	if cfg.DBReplicaAddress != "" {
+		if err := storage.ConnectReplica(startCtx, cfg.DBReplicaAddress); err != nil {
+			log.Warn("cannot connect to db replica, reading from primary", "error", err)
+		}
+	}
 
 	// words adapter
-	words, err := words.NewClient(cfg.WordsAddress, log)
+	wordsClient, err := words.NewClient(cfg.WordsAddress, log)
 	if err != nil {
 		return fmt.Errorf("failed create Words client: %v", err)
 	}
-	defer closers.CloseOrLog(words, log)
+	defer closers.CloseOrLog(wordsClient, log)
 
 	// nats subscriber
-	subscriber, err := searchnats.New(log, cfg.BrokerAddress)
+	subscriber, err := searchnats.New(startCtx, log, cfg.BrokerAddress)
 	if err != nil {
 		return fmt.Errorf("failed to create NATS subscriber: %v", err)
 	}
 	defer closers.CloseOrLog(subscriber, log)
+	if cfg.MetricsAddress != "" {
+		prometheus.MustRegister(subscriber.Metrics())
+		if err := metrics.Serve(ctx, cfg.MetricsAddress, promhttp.Handler(), log); err != nil {
+			return fmt.Errorf("failed to serve metrics: %v", err)
+		}
+	}
 
 	// service
-	searcher, err := core.NewService(log, storage, words)
+	var opts []core.Option
+	if cfg.WordsFallback {
+		opts = append(opts, core.WithWordsFallback(words.Local{}))
+	}
+	if cfg.Synonyms.File != "" {
+		synonymsMap, err := synonyms.Load(cfg.Synonyms.File, cfg.Synonyms.Bidirectional)
+		if err != nil {
+			return fmt.Errorf("failed to load synonyms: %v", err)
+		}
+		log.Info("loaded synonyms", "terms", len(synonymsMap))
+		opts = append(opts, core.WithSynonyms(synonymsMap))
+	}
+	if cfg.EarlyTermination {
+		opts = append(opts, core.WithEarlyTermination())
+	}
+	if cfg.DisableIndex {
+		log.Info("in-memory index disabled, searching the DB")
+		opts = append(opts, core.WithoutIndex())
+	}
+	if cfg.MaxTerms > 0 {
+		opts = append(opts, core.WithMaxTerms(cfg.MaxTerms))
+	}
+	if cfg.NGrams > 1 {
+		opts = append(opts, core.WithNGrams(cfg.NGrams))
+	}
+	switch cfg.IndexBuild.Mode {
+	case "fast":
+	case "background":
+		if cfg.IndexBuild.RPS <= 0 {
+			return fmt.Errorf("wrong index build rate: %d", cfg.IndexBuild.RPS)
+		}
+		opts = append(opts, core.WithBuildRate(cfg.IndexBuild.RPS))
+	default:
+		return fmt.Errorf("unknown index build mode %q", cfg.IndexBuild.Mode)
+	}
+	if cfg.BM25.Enabled {
+		opts = append(opts, core.WithBM25(cfg.BM25.K1, cfg.BM25.B))
+	}
+	if cfg.LenientSearch {
+		opts = append(opts, core.WithLenientFetch())
+	}
+	if cfg.RejectStopWords {
+		opts = append(opts, core.WithStopWordsRejected())
+	}
+	if cfg.SlowSearch > 0 {
+		opts = append(opts, core.WithSlowSearchLog(cfg.SlowSearch))
+	}
+	searcher, err := core.NewService(log, storage, wordsClient, opts...)
 	if err != nil {
 		return fmt.Errorf("failed create Update service: %v", err)
 	}
 
 	// initiator
-	initiator.RunIndexUpdate(ctx, searcher, cfg.IndexTTL, log)
+	if !cfg.DisableIndex {
+		initiator.RunIndexUpdate(ctx, searcher, cfg.IndexTTL, cfg.IndexFile, cfg.IndexFileMaxAge, log)
+	}
 
 	// nats event index update
 	if err := subscriber.RunEventHandlers(ctx,
-		func() {
+		func(ids []int) {
+			if len(ids) > 0 {
+				log.Info("reindexing comics after db update checkpoint", "comics", len(ids))
+				for _, id := range ids {
+					if err := searcher.ReindexComic(ctx, id); err != nil && !errors.Is(err, core.ErrNotFound) {
+						log.Error("failed to reindex comics", "id", id, "error", err)
+					}
+				}
+				return
+			}
 			log.Info("rebuilding index after db update")
-			if err := searcher.BuildIndex(ctx); err != nil {
+			if err := initiator.BuildIndex(ctx, searcher, cfg.IndexFile, log); err != nil {
 				log.Error("failed to rebuild index", "error", err)
 			}
 		},
 		func() {
 			log.Info("clearing index after db drop")
-			if err := searcher.BuildIndex(ctx); err != nil {
+			if err := searcher.ClearIndex(ctx); err != nil {
 				log.Error("failed to clear index", "error", err)
 			}
+			// a restart before the db is refilled must not load dropped comics
+			if cfg.IndexFile != "" {
+				if err := os.Remove(cfg.IndexFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+					log.Error("failed to remove saved index", "path", cfg.IndexFile, "error", err)
+				}
+			}
 		},
 	); err != nil {
 		return fmt.Errorf("failed to run eventhandlers: %v", err)
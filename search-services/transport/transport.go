@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Config tunes connection reuse of outbound HTTP clients, zero fields
+// keep the net/http defaults.
+type Config struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+}
+
+// New returns a transport to share between clients, so sequential requests
+// to a host reuse kept alive connections instead of dialing and resolving
+// the host again.
+func New(cfg Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.KeepAlive > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: cfg.KeepAlive}
+		transport.DialContext = dialer.DialContext
+	}
+	return transport
+}
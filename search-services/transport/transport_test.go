@@ -0,0 +1,47 @@
+package transport
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_ReusesConnections(t *testing.T) {
+	var dials atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := http.Client{Transport: New(Config{MaxIdleConnsPerHost: 4, IdleConnTimeout: time.Minute})}
+	for range 5 {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	assert.Equal(t, int64(1), dials.Load())
+}
+
+func TestNew_KeepsDefaults(t *testing.T) {
+	transport := New(Config{})
+	defaults := http.DefaultTransport.(*http.Transport)
+
+	assert.Equal(t, defaults.MaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, defaults.IdleConnTimeout, transport.IdleConnTimeout)
+}
@@ -0,0 +1 @@
+ALTER TABLE comics DROP COLUMN tags;
\ No newline at end of file
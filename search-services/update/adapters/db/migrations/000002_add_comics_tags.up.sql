@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE comics ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX comics_updated_at_idx ON comics (updated_at, id);
\ No newline at end of file
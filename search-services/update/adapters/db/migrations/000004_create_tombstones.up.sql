@@ -0,0 +1,5 @@
+CREATE TABLE tombstones (
+    id int PRIMARY KEY,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX tombstones_deleted_at_idx ON tombstones (deleted_at, id);
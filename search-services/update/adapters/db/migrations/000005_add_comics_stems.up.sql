@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN stems TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS comics_lower_title_idx;
@@ -0,0 +1 @@
+CREATE INDEX comics_lower_title_idx ON comics (lower(title));
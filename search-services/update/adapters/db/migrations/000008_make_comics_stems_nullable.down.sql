@@ -0,0 +1,2 @@
+UPDATE comics SET stems = '{}' WHERE stems IS NULL;
+ALTER TABLE comics ALTER COLUMN stems SET DEFAULT '{}', ALTER COLUMN stems SET NOT NULL;
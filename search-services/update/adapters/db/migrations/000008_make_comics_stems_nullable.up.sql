@@ -0,0 +1,2 @@
+ALTER TABLE comics ALTER COLUMN stems DROP NOT NULL, ALTER COLUMN stems DROP DEFAULT;
+UPDATE comics SET stems = NULL WHERE cardinality(stems) = 0;
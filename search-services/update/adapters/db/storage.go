@@ -4,51 +4,88 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/liy0aay/xkcd-search/update/core"
 )
 
 type DB struct {
-	log  *slog.Logger
-	conn *sqlx.DB
+	log     *slog.Logger
+	conn    *sqlx.DB
+	timeout time.Duration
 }
 
-func New(log *slog.Logger, address string) (*DB, error) {
+// New connects to the DB, every query is limited by timeout unless it is zero.
+func New(ctx context.Context, log *slog.Logger, address string, timeout time.Duration) (*DB, error) {
 
-	db, err := sqlx.Connect("pgx", address)
+	db, err := sqlx.ConnectContext(ctx, "pgx", address)
 	if err != nil {
 		log.Error("connection problem", "address", address, "error", err)
 		return nil, err
 	}
 
 	return &DB{
-		log:  log,
-		conn: db,
+		log:     log,
+		conn:    db,
+		timeout: timeout,
 	}, nil
 }
 
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.timeout)
+}
+
+// timedOut reports statements cancelled by the timeout as core.ErrTimeout.
+func (db *DB) timedOut(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: statement exceeded %s: %v", core.ErrTimeout, db.timeout, err)
+	}
+	return err
+}
+
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// Add stores comics, replacing a stored version but its tags and categories.
 func (db *DB) Add(ctx context.Context, comics core.Comics) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	// comics normalized without stems keep NULL ones, so they are told
+	// apart from comics with no stems and get them once stems are kept
+	var stems any
+	if comics.Stems != nil {
+		stems = comics.Stems
+	}
 	_, err := db.conn.ExecContext(
 		ctx,
-		"INSERT INTO comics (id, url, title, alt, words) VALUES($1, $2, $3, $4, $5)",
-		comics.ID, comics.URL, comics.Title, comics.Alt, comics.Words,
+		`INSERT INTO comics (id, url, title, alt, words, stems) VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET url = $2, title = $3, alt = $4, words = $5, stems = $6, updated_at = now()`,
+		comics.ID, comics.URL, comics.Title, comics.Alt, comics.Words, stems,
 	)
+	err = db.timedOut(ctx, err)
 
 	return err
 }
 
 func (db *DB) Stats(ctx context.Context) (core.DBStats, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var stats core.DBStats
 	err := db.conn.GetContext(
 		ctx, &stats.ComicsFetched,
 		"SELECT COUNT(*) FROM comics")
+	err = db.timedOut(ctx, err)
 	if err != nil {
 		return core.DBStats{}, err
 	}
@@ -56,6 +93,7 @@ func (db *DB) Stats(ctx context.Context) (core.DBStats, error) {
 		ctx, &stats.WordsTotal,
 		"SELECT coalesce(SUM(array_length(words,1)), 0) FROM comics",
 	)
+	err = db.timedOut(ctx, err)
 	if err != nil {
 		return core.DBStats{}, err
 	}
@@ -63,6 +101,7 @@ func (db *DB) Stats(ctx context.Context) (core.DBStats, error) {
 		ctx, &stats.WordsUnique,
 		"SELECT count(*) FROM (SELECT distinct(unnest(words)) FROM comics)",
 	)
+	err = db.timedOut(ctx, err)
 	if err != nil {
 		return core.DBStats{}, err
 	}
@@ -71,10 +110,14 @@ func (db *DB) Stats(ctx context.Context) (core.DBStats, error) {
 }
 
 func (db *DB) IDs(ctx context.Context) ([]int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var IDs []int
 	err := db.conn.SelectContext(
 		ctx, &IDs,
 		"SELECT id FROM comics")
+	err = db.timedOut(ctx, err)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -85,8 +128,153 @@ func (db *DB) IDs(ctx context.Context) ([]int, error) {
 	return IDs, nil
 }
 
+// Unstemmed lists comics never normalized in order, like the ones added
+// before stems were kept.
+func (db *DB) Unstemmed(ctx context.Context) ([]int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var IDs []int
+	err := db.conn.SelectContext(
+		ctx, &IDs,
+		"SELECT id FROM comics WHERE stems IS NULL")
+	err = db.timedOut(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+	return IDs, nil
+}
+
+func (db *DB) URLs(ctx context.Context) (map[int]string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var rows []struct {
+		ID  int    `db:"id"`
+		URL string `db:"url"`
+	}
+	err := db.conn.SelectContext(
+		ctx, &rows,
+		"SELECT id, url FROM comics WHERE url <> ''")
+	err = db.timedOut(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[int]string, len(rows))
+	for _, row := range rows {
+		urls[row.ID] = row.URL
+	}
+	return urls, nil
+}
+
+func (db *DB) SetTags(ctx context.Context, id int, tags []string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := db.conn.ExecContext(
+		ctx,
+		"UPDATE comics SET tags = $2, updated_at = now() WHERE id = $1",
+		id, tags,
+	)
+	err = db.timedOut(ctx, err)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
+
+func (db *DB) SetCategories(ctx context.Context, id int, categories []string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.conn.ExecContext(
+		ctx,
+		"UPDATE comics SET categories = $2, updated_at = now() WHERE id = $1",
+		id, categories,
+	)
+	return db.timedOut(ctx, err)
+}
+
+func (db *DB) GetTags(ctx context.Context, id int) ([]string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var tags pq.StringArray
+	err := db.conn.GetContext(
+		ctx, &tags,
+		"SELECT tags FROM comics WHERE id = $1",
+		id,
+	)
+	err = db.timedOut(ctx, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrNotFound
+	}
+	return tags, err
+}
+
+// Delete removes comics id, leaving a tombstone like Drop does,
+// ErrNotFound tells it was not stored.
+func (db *DB) Delete(ctx context.Context, id int) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return db.timedOut(ctx, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM comics WHERE id = $1", id)
+	if err != nil {
+		return db.timedOut(ctx, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return core.ErrNotFound
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO tombstones (id) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET deleted_at = now()`,
+		id,
+	)
+	if err != nil {
+		return db.timedOut(ctx, err)
+	}
+	return db.timedOut(ctx, tx.Commit())
+}
+
+// Drop removes all comics, leaving a tombstone for each so that
+// syncing clients learn about the deletion from the changes feed.
 func (db *DB) Drop(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
-	_, err := db.conn.ExecContext(ctx, "TRUNCATE comics")
-	return err
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return db.timedOut(ctx, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO tombstones (id) SELECT id FROM comics
+		ON CONFLICT (id) DO UPDATE SET deleted_at = now()`,
+	)
+	if err != nil {
+		return db.timedOut(ctx, err)
+	}
+	if _, err = tx.ExecContext(ctx, "TRUNCATE comics"); err != nil {
+		return db.timedOut(ctx, err)
+	}
+	return db.timedOut(ctx, tx.Commit())
 }
@@ -0,0 +1,113 @@
+package explainxkcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/closers"
+	"golang.org/x/time/rate"
+)
+
+// Enricher tags comics with the categories of their explainxkcd wiki page.
+type Enricher struct {
+	log       *slog.Logger
+	client    http.Client
+	url       string
+	limiter   *rate.Limiter
+	transport *http.Transport
+}
+
+type Option func(*Enricher)
+
+// WithTransport sends requests through a shared transport to reuse its
+// connections, a proxy gets a copy of it.
+func WithTransport(transport *http.Transport) Option {
+	return func(e *Enricher) {
+		e.transport = transport
+	}
+}
+
+// NewEnricher sends requests through proxy if set, otherwise HTTP_PROXY/HTTPS_PROXY are honored.
+func NewEnricher(
+	baseURL, proxy string, rps int, timeout time.Duration, log *slog.Logger, opts ...Option,
+) (*Enricher, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("empty base url specified")
+	}
+	if rps < 1 {
+		return nil, fmt.Errorf("wrong rate specified: %d", rps)
+	}
+	e := &Enricher{
+		log:       log,
+		url:       strings.TrimSuffix(baseURL, "/"),
+		limiter:   rate.NewLimiter(rate.Limit(rps), 1),
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	transport := e.transport
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %v", err)
+		}
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	e.client = http.Client{Timeout: timeout, Transport: transport}
+	return e, nil
+}
+
+// Tags are the visible categories of the comics page, like "Comics
+// featuring Cueball", hidden maintenance categories are left out.
+func (e *Enricher) Tags(ctx context.Context, id int) ([]string, error) {
+	if err := e.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	reqURL := fmt.Sprintf("%s/wiki/api.php?action=parse&page=%d&prop=categories&redirects=1&format=json", e.url, id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request categories: %v", err)
+	}
+	defer closers.CloseOrLog(resp.Body, e.log)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Parse struct {
+			Categories []struct {
+				Name   string  `json:"*"`
+				Hidden *string `json:"hidden"`
+			} `json:"categories"`
+		} `json:"parse"`
+		Error *struct {
+			Info string `json:"info"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %v", err)
+	}
+	if parsed.Error != nil {
+		return nil, fmt.Errorf("no categories: %s", parsed.Error.Info)
+	}
+	var tags []string
+	for _, category := range parsed.Parse.Categories {
+		if category.Hidden != nil {
+			continue
+		}
+		tags = append(tags, strings.ReplaceAll(category.Name, "_", " "))
+	}
+	return tags, nil
+}
@@ -0,0 +1,47 @@
+package explainxkcd
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnricher_Tags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/wiki/api.php", r.URL.Path)
+		assert.Equal(t, "categories", r.URL.Query().Get("prop"))
+		if r.URL.Query().Get("page") != "353" {
+			_, _ = w.Write([]byte(`{"error": {"code": "missingtitle", "info": "The page does not exist."}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"parse": {"categories": [
+			{"sortkey": "", "*": "Comics_featuring_Cueball"},
+			{"sortkey": "", "hidden": "", "*": "Pages_with_broken_file_links"},
+			{"sortkey": "", "*": "Python"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	enricher, err := NewEnricher(server.URL, "", 100, time.Second, slog.Default())
+	require.NoError(t, err)
+
+	tags, err := enricher.Tags(context.Background(), 353)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Comics featuring Cueball", "Python"}, tags)
+
+	_, err = enricher.Tags(context.Background(), 100000)
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+func TestNewEnricher_BadArguments(t *testing.T) {
+	_, err := NewEnricher("", "", 1, time.Second, slog.Default())
+	require.Error(t, err)
+	_, err = NewEnricher("https://www.explainxkcd.com", "", 0, time.Second, slog.Default())
+	require.Error(t, err)
+}
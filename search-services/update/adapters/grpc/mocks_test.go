@@ -41,6 +41,34 @@ func (m *MockUpdater) EXPECT() *MockUpdaterMockRecorder {
 	return m.recorder
 }
 
+// BrokenLinks mocks base method.
+func (m *MockUpdater) BrokenLinks(arg0 context.Context) []core.BrokenLink {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BrokenLinks", arg0)
+	ret0, _ := ret[0].([]core.BrokenLink)
+	return ret0
+}
+
+// BrokenLinks indicates an expected call of BrokenLinks.
+func (mr *MockUpdaterMockRecorder) BrokenLinks(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BrokenLinks", reflect.TypeOf((*MockUpdater)(nil).BrokenLinks), arg0)
+}
+
+// CheckLinks mocks base method.
+func (m *MockUpdater) CheckLinks(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLinks", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLinks indicates an expected call of CheckLinks.
+func (mr *MockUpdaterMockRecorder) CheckLinks(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLinks", reflect.TypeOf((*MockUpdater)(nil).CheckLinks), arg0)
+}
+
 // Drop mocks base method.
 func (m *MockUpdater) Drop(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -55,6 +83,63 @@ func (mr *MockUpdaterMockRecorder) Drop(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drop", reflect.TypeOf((*MockUpdater)(nil).Drop), arg0)
 }
 
+// GetTags mocks base method.
+func (m *MockUpdater) GetTags(ctx context.Context, id int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTags", ctx, id)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTags indicates an expected call of GetTags.
+func (mr *MockUpdaterMockRecorder) GetTags(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTags", reflect.TypeOf((*MockUpdater)(nil).GetTags), ctx, id)
+}
+
+// Progress mocks base method.
+func (m *MockUpdater) Progress(arg0 context.Context) <-chan core.Progress {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Progress", arg0)
+	ret0, _ := ret[0].(<-chan core.Progress)
+	return ret0
+}
+
+// Progress indicates an expected call of Progress.
+func (mr *MockUpdaterMockRecorder) Progress(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Progress", reflect.TypeOf((*MockUpdater)(nil).Progress), arg0)
+}
+
+// ResetStats mocks base method.
+func (m *MockUpdater) ResetStats(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetStats", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetStats indicates an expected call of ResetStats.
+func (mr *MockUpdaterMockRecorder) ResetStats(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetStats", reflect.TypeOf((*MockUpdater)(nil).ResetStats), arg0)
+}
+
+// SetTags mocks base method.
+func (m *MockUpdater) SetTags(ctx context.Context, id int, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTags", ctx, id, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags.
+func (mr *MockUpdaterMockRecorder) SetTags(ctx, id, tags any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockUpdater)(nil).SetTags), ctx, id, tags)
+}
+
 // Stats mocks base method.
 func (m *MockUpdater) Stats(arg0 context.Context) (core.ServiceStats, error) {
 	m.ctrl.T.Helper()
@@ -98,6 +183,20 @@ func (mr *MockUpdaterMockRecorder) Update(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUpdater)(nil).Update), arg0)
 }
 
+// UpdateOne mocks base method.
+func (m *MockUpdater) UpdateOne(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOne", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOne indicates an expected call of UpdateOne.
+func (mr *MockUpdaterMockRecorder) UpdateOne(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOne", reflect.TypeOf((*MockUpdater)(nil).UpdateOne), ctx, id)
+}
+
 // MockDB is a mock of DB interface.
 type MockDB struct {
 	ctrl     *gomock.Controller
@@ -150,6 +249,21 @@ func (mr *MockDBMockRecorder) Drop(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drop", reflect.TypeOf((*MockDB)(nil).Drop), arg0)
 }
 
+// GetTags mocks base method.
+func (m *MockDB) GetTags(ctx context.Context, id int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTags", ctx, id)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTags indicates an expected call of GetTags.
+func (mr *MockDBMockRecorder) GetTags(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTags", reflect.TypeOf((*MockDB)(nil).GetTags), ctx, id)
+}
+
 // IDs mocks base method.
 func (m *MockDB) IDs(arg0 context.Context) ([]int, error) {
 	m.ctrl.T.Helper()
@@ -165,6 +279,34 @@ func (mr *MockDBMockRecorder) IDs(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IDs", reflect.TypeOf((*MockDB)(nil).IDs), arg0)
 }
 
+// SetCategories mocks base method.
+func (m *MockDB) SetCategories(ctx context.Context, id int, categories []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCategories", ctx, id, categories)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCategories indicates an expected call of SetCategories.
+func (mr *MockDBMockRecorder) SetCategories(ctx, id, categories any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCategories", reflect.TypeOf((*MockDB)(nil).SetCategories), ctx, id, categories)
+}
+
+// SetTags mocks base method.
+func (m *MockDB) SetTags(ctx context.Context, id int, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTags", ctx, id, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags.
+func (mr *MockDBMockRecorder) SetTags(ctx, id, tags any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockDB)(nil).SetTags), ctx, id, tags)
+}
+
 // Stats mocks base method.
 func (m *MockDB) Stats(arg0 context.Context) (core.DBStats, error) {
 	m.ctrl.T.Helper()
@@ -180,6 +322,21 @@ func (mr *MockDBMockRecorder) Stats(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDB)(nil).Stats), arg0)
 }
 
+// URLs mocks base method.
+func (m *MockDB) URLs(arg0 context.Context) (map[int]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "URLs", arg0)
+	ret0, _ := ret[0].(map[int]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// URLs indicates an expected call of URLs.
+func (mr *MockDBMockRecorder) URLs(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "URLs", reflect.TypeOf((*MockDB)(nil).URLs), arg0)
+}
+
 // MockXKCD is a mock of XKCD interface.
 type MockXKCD struct {
 	ctrl     *gomock.Controller
@@ -234,6 +391,83 @@ func (mr *MockXKCDMockRecorder) LastID(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastID", reflect.TypeOf((*MockXKCD)(nil).LastID), arg0)
 }
 
+// MockLinkChecker is a mock of LinkChecker interface.
+type MockLinkChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkCheckerMockRecorder
+	isgomock struct{}
+}
+
+// MockLinkCheckerMockRecorder is the mock recorder for MockLinkChecker.
+type MockLinkCheckerMockRecorder struct {
+	mock *MockLinkChecker
+}
+
+// NewMockLinkChecker creates a new mock instance.
+func NewMockLinkChecker(ctrl *gomock.Controller) *MockLinkChecker {
+	mock := &MockLinkChecker{ctrl: ctrl}
+	mock.recorder = &MockLinkCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkChecker) EXPECT() *MockLinkCheckerMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockLinkChecker) Check(ctx context.Context, url string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, url)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockLinkCheckerMockRecorder) Check(ctx, url any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockLinkChecker)(nil).Check), ctx, url)
+}
+
+// MockEnricher is a mock of Enricher interface.
+type MockEnricher struct {
+	ctrl     *gomock.Controller
+	recorder *MockEnricherMockRecorder
+	isgomock struct{}
+}
+
+// MockEnricherMockRecorder is the mock recorder for MockEnricher.
+type MockEnricherMockRecorder struct {
+	mock *MockEnricher
+}
+
+// NewMockEnricher creates a new mock instance.
+func NewMockEnricher(ctrl *gomock.Controller) *MockEnricher {
+	mock := &MockEnricher{ctrl: ctrl}
+	mock.recorder = &MockEnricherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEnricher) EXPECT() *MockEnricherMockRecorder {
+	return m.recorder
+}
+
+// Tags mocks base method.
+func (m *MockEnricher) Tags(ctx context.Context, id int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tags", ctx, id)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Tags indicates an expected call of Tags.
+func (mr *MockEnricherMockRecorder) Tags(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tags", reflect.TypeOf((*MockEnricher)(nil).Tags), ctx, id)
+}
+
 // MockWords is a mock of Words interface.
 type MockWords struct {
 	ctrl     *gomock.Controller
@@ -273,6 +507,21 @@ func (mr *MockWordsMockRecorder) Norm(ctx, phrase any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Norm", reflect.TypeOf((*MockWords)(nil).Norm), ctx, phrase)
 }
 
+// NormBatch mocks base method.
+func (m *MockWords) NormBatch(ctx context.Context, phrases []string) ([][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NormBatch", ctx, phrases)
+	ret0, _ := ret[0].([][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NormBatch indicates an expected call of NormBatch.
+func (mr *MockWordsMockRecorder) NormBatch(ctx, phrases any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NormBatch", reflect.TypeOf((*MockWords)(nil).NormBatch), ctx, phrases)
+}
+
 // MockPublisher is a mock of Publisher interface.
 type MockPublisher struct {
 	ctrl     *gomock.Controller
@@ -311,6 +560,20 @@ func (mr *MockPublisherMockRecorder) PublishDBDropEvent(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishDBDropEvent", reflect.TypeOf((*MockPublisher)(nil).PublishDBDropEvent), ctx)
 }
 
+// PublishDBUpdateBatch mocks base method.
+func (m *MockPublisher) PublishDBUpdateBatch(ctx context.Context, ids []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishDBUpdateBatch", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PublishDBUpdateBatch indicates an expected call of PublishDBUpdateBatch.
+func (mr *MockPublisherMockRecorder) PublishDBUpdateBatch(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishDBUpdateBatch", reflect.TypeOf((*MockPublisher)(nil).PublishDBUpdateBatch), ctx, ids)
+}
+
 // PublishDBUpdateEvent mocks base method.
 func (m *MockPublisher) PublishDBUpdateEvent(ctx context.Context) error {
 	m.ctrl.T.Helper()
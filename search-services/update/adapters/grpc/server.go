@@ -9,6 +9,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func NewServer(service core.Updater, publisher core.Publisher) *Server {
@@ -25,6 +26,10 @@ func (s *Server) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, erro
 	return nil, nil
 }
 
+func (s *Server) Version(_ context.Context, _ *emptypb.Empty) (*updatepb.VersionReply, error) {
+	return &updatepb.VersionReply{Version: updatepb.Version}, nil
+}
+
 func (s *Server) Status(ctx context.Context, _ *emptypb.Empty) (*updatepb.StatusReply, error) {
 	st := s.service.Status(ctx)
 
@@ -33,6 +38,8 @@ func (s *Server) Status(ctx context.Context, _ *emptypb.Empty) (*updatepb.Status
 		return &updatepb.StatusReply{Status: updatepb.Status_STATUS_IDLE}, nil
 	case core.StatusRunning:
 		return &updatepb.StatusReply{Status: updatepb.Status_STATUS_RUNNING}, nil
+	case core.StatusReindexing:
+		return &updatepb.StatusReply{Status: updatepb.Status_STATUS_REINDEXING}, nil
 	}
 	return nil, status.Error(codes.Internal, "unknown status from service")
 }
@@ -50,6 +57,28 @@ func (s *Server) Update(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty,
 	return nil, nil
 }
 
+// UpdateOne notifies subscribers so search indexes pick up the new version,
+// or drop comics deleted as gone from xkcd.
+func (s *Server) UpdateOne(ctx context.Context, req *updatepb.UpdateOneRequest) (*emptypb.Empty, error) {
+	if req.Id < 1 {
+		return nil, status.Error(codes.InvalidArgument, "bad id")
+	}
+	if err := s.service.UpdateOne(ctx, int(req.Id)); err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			// search drops comics deleted as gone from xkcd when reindexing them
+			if err := s.publisher.PublishDBUpdateBatch(ctx, []int{int(req.Id)}); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			return nil, status.Error(codes.NotFound, "comics not found")
+		}
+		return nil, err
+	}
+	if err := s.publisher.PublishDBUpdateBatch(ctx, []int{int(req.Id)}); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return nil, nil
+}
+
 func (s *Server) Stats(ctx context.Context, _ *emptypb.Empty) (*updatepb.StatsReply, error) {
 	stats, err := s.service.Stats(ctx)
 	if err != nil {
@@ -61,9 +90,19 @@ func (s *Server) Stats(ctx context.Context, _ *emptypb.Empty) (*updatepb.StatsRe
 		WordsUnique:   int64(stats.DBStats.WordsUnique),
 		ComicsTotal:   int64(stats.ComicsTotal),
 		ComicsFetched: int64(stats.DBStats.ComicsFetched),
+		ComicsAdded:   int64(stats.Counters.Added),
+		ComicsSkipped: int64(stats.Counters.Skipped),
+		ComicsFailed:  int64(stats.Counters.Failed),
 	}, nil
 }
 
+func (s *Server) ResetStats(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := s.service.ResetStats(ctx); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 func (s *Server) Drop(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
 	if err := s.service.Drop(ctx); err != nil {
 		return nil, err
@@ -73,3 +112,58 @@ func (s *Server) Drop(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, er
 	}
 	return nil, nil
 }
+
+func (s *Server) BrokenLinks(ctx context.Context, _ *emptypb.Empty) (*updatepb.BrokenLinksReply, error) {
+	broken := s.service.BrokenLinks(ctx)
+	links := make([]*updatepb.BrokenLink, 0, len(broken))
+	for _, l := range broken {
+		links = append(links, &updatepb.BrokenLink{
+			Id:        int64(l.ID),
+			Url:       l.URL,
+			Reason:    l.Reason,
+			CheckedAt: timestamppb.New(l.CheckedAt),
+		})
+	}
+	return &updatepb.BrokenLinksReply{Links: links}, nil
+}
+
+// SetTags notifies subscribers so search indexes pick up the new tags.
+func (s *Server) SetTags(ctx context.Context, req *updatepb.SetTagsRequest) (*emptypb.Empty, error) {
+	if err := s.service.SetTags(ctx, int(req.Id), req.Tags); err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "comics not found")
+		}
+		return nil, err
+	}
+	if err := s.publisher.PublishDBUpdateEvent(ctx); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return nil, nil
+}
+
+func (s *Server) GetTags(ctx context.Context, req *updatepb.TagsRequest) (*updatepb.TagsReply, error) {
+	tags, err := s.service.GetTags(ctx, int(req.Id))
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "comics not found")
+		}
+		return nil, err
+	}
+	return &updatepb.TagsReply{Tags: tags}, nil
+}
+
+// UpdateProgress streams progress of the running update, it ends with the
+// update or at once when none runs.
+func (s *Server) UpdateProgress(_ *emptypb.Empty, stream updatepb.Update_UpdateProgressServer) error {
+	for progress := range s.service.Progress(stream.Context()) {
+		err := stream.Send(&updatepb.ProgressReply{
+			Fetched:   int64(progress.Fetched),
+			Total:     int64(progress.Total),
+			CurrentId: int64(progress.CurrentID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
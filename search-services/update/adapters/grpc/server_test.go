@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -68,6 +69,40 @@ func TestUpdate_HappyPath(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestUpdateOne_HappyPath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	updater := NewMockUpdater(ctrl)
+	publisher := NewMockPublisher(ctrl)
+	updater.EXPECT().
+		UpdateOne(gomock.Any(), 42).
+		Return(nil)
+	publisher.EXPECT().
+		PublishDBUpdateBatch(gomock.Any(), []int{42}).
+		Return(nil)
+
+	_, err := NewServer(updater, publisher).UpdateOne(context.Background(), &updatepb.UpdateOneRequest{Id: 42})
+	require.NoError(t, err)
+}
+
+func TestUpdateOne_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	updater := NewMockUpdater(ctrl)
+	publisher := NewMockPublisher(ctrl)
+	updater.EXPECT().
+		UpdateOne(gomock.Any(), 404).
+		Return(core.ErrNotFound)
+	publisher.EXPECT().
+		PublishDBUpdateBatch(gomock.Any(), []int{404}).
+		Return(nil)
+
+	_, err := NewServer(updater, publisher).UpdateOne(context.Background(), &updatepb.UpdateOneRequest{Id: 404})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
 func TestUpdate_AlreadyExists(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -212,3 +247,40 @@ func TestDrop_PublisherError(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, codes.Internal, st.Code())
 }
+
+type fakeProgressStream struct {
+	grpc.ServerStream
+	sent []*updatepb.ProgressReply
+}
+
+func (f *fakeProgressStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeProgressStream) Send(reply *updatepb.ProgressReply) error {
+	f.sent = append(f.sent, reply)
+	return nil
+}
+
+func TestUpdateProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	progress := make(chan core.Progress, 2)
+	progress <- core.Progress{Total: 3}
+	progress <- core.Progress{Fetched: 1, Total: 3, CurrentID: 7}
+	close(progress)
+	updater := NewMockUpdater(ctrl)
+	updater.EXPECT().
+		Progress(gomock.Any()).
+		Return(progress)
+
+	stream := &fakeProgressStream{}
+	err := NewServer(updater, nil).UpdateProgress(nil, stream)
+
+	require.NoError(t, err)
+	require.Len(t, stream.sent, 2)
+	assert.EqualValues(t, 3, stream.sent[0].Total)
+	assert.EqualValues(t, 1, stream.sent[1].Fetched)
+	assert.EqualValues(t, 7, stream.sent[1].CurrentId)
+}
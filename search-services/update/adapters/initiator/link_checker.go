@@ -0,0 +1,30 @@
+package initiator
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/update/core"
+)
+
+func RunLinkCheck(
+	ctx context.Context, updater core.Updater, period time.Duration, log *slog.Logger,
+) {
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug("quit link checker")
+				return
+			case <-ticker.C:
+				log.Info("run image links check")
+				if err := updater.CheckLinks(ctx); err != nil {
+					log.Error("image links check failed", "error", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package links
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/liy0aay/xkcd-search/closers"
+	"golang.org/x/time/rate"
+)
+
+type Checker struct {
+	log     *slog.Logger
+	client  http.Client
+	limiter *rate.Limiter
+}
+
+func NewChecker(rps int, timeout time.Duration, log *slog.Logger) (*Checker, error) {
+	if rps < 1 {
+		return nil, fmt.Errorf("wrong rate specified: %d", rps)
+	}
+	return &Checker{
+		log:     log,
+		client:  http.Client{Timeout: timeout},
+		limiter: rate.NewLimiter(rate.Limit(rps), 1),
+	}, nil
+}
+
+func (c *Checker) Check(ctx context.Context, url string) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request image: %v", err)
+	}
+	defer closers.CloseOrLog(resp.Body, c.log)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package links
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck_ReportsMissingImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path == "/comics/gone.png" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checker, err := NewChecker(100, time.Second, slog.Default())
+	require.NoError(t, err)
+
+	require.NoError(t, checker.Check(context.Background(), server.URL+"/comics/alive.png"))
+	err = checker.Check(context.Background(), server.URL+"/comics/gone.png")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestNewChecker_BadRate(t *testing.T) {
+	_, err := NewChecker(0, time.Second, slog.Default())
+	require.Error(t, err)
+}
@@ -2,10 +2,13 @@ package nats
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/liy0aay/xkcd-search/events"
+	"github.com/liy0aay/xkcd-search/metrics"
 	"github.com/liy0aay/xkcd-search/update/core"
 	natslib "github.com/nats-io/nats.go"
 )
@@ -13,39 +16,77 @@ import (
 var _ core.Publisher = (*Publisher)(nil)
 
 type Publisher struct {
-	nc  *natslib.Conn
-	log *slog.Logger
+	nc      *natslib.Conn
+	log     *slog.Logger
+	metrics metrics.NATS
 }
 
-func New(log *slog.Logger, brokerAddress string) (*Publisher, error) {
+func New(ctx context.Context, log *slog.Logger, brokerAddress string) (*Publisher, error) {
+	p := &Publisher{log: log}
 	opts := []natslib.Option{
 		natslib.Name("update-service"),
 		natslib.ReconnectHandler(func(_ *natslib.Conn) {
 			log.Info("NATS reconnected")
+			p.metrics.Reconnected()
 		}),
 		natslib.DisconnectErrHandler(func(_ *natslib.Conn, err error) {
+			p.metrics.Disconnected(time.Now())
 			if err != nil {
 				log.Warn("NATS disconnected", "error", err)
 			} else {
 				log.Warn("NATS disconnected")
 			}
 		}),
+		natslib.ClosedHandler(func(_ *natslib.Conn) {
+			p.metrics.Closed()
+		}),
 		natslib.ErrorHandler(func(_ *natslib.Conn, _ *natslib.Subscription, err error) {
 			log.Error("NATS error", "error", err)
 		}),
 	}
 
+	if deadline, ok := ctx.Deadline(); ok {
+		// a zero timeout is no timeout at all, so a passed deadline fails at once
+		timeout := max(time.Until(deadline), 0)
+		if timeout == 0 {
+			return nil, fmt.Errorf("failed to connect to broker: %w", context.DeadlineExceeded)
+		}
+		opts = append(opts, natslib.Timeout(timeout))
+	}
+
 	nc, err := natslib.Connect(brokerAddress, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to broker: %v", err)
 	}
 
-	return &Publisher{nc: nc, log: log}, nil
+	p.nc = nc
+	p.metrics.Connected()
+
+	return p, nil
+}
+
+// Metrics reports the broker connection state.
+func (p *Publisher) Metrics() *metrics.NATS {
+	return &p.metrics
 }
 
 func (p *Publisher) PublishDBUpdateEvent(ctx context.Context) error {
 	p.log.Info("publishing event: db updated")
-	if err := p.nc.Publish(events.TopicDBUpdated, []byte("updated")); err != nil {
+	return p.publishDBUpdated(events.DBUpdated{})
+}
+
+// PublishDBUpdateBatch announces comics added so far by a running update.
+func (p *Publisher) PublishDBUpdateBatch(ctx context.Context, ids []int) error {
+	p.log.Info("publishing event: db updated", "comics", len(ids))
+	return p.publishDBUpdated(events.DBUpdated{IDs: ids})
+}
+
+func (p *Publisher) publishDBUpdated(event events.DBUpdated) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode db update event: %v", err)
+	}
+	if err := p.nc.Publish(events.TopicDBUpdated, data); err != nil {
 		p.log.Error("failed to publish db update event", "error", err)
 		return fmt.Errorf("failed to publish db update event: %v", err)
 	}
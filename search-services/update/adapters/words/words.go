@@ -2,7 +2,9 @@ package words
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
 
 	wordspb "github.com/liy0aay/xkcd-search/proto/words"
 	"github.com/liy0aay/xkcd-search/update/core"
@@ -45,6 +47,35 @@ func (c *Client) Norm(ctx context.Context, phrase string) ([]string, error) {
 	return reply.GetWords(), nil
 }
 
+// maxBatch is the largest batch the words service normalizes at once.
+const maxBatch = 1000
+
+// NormBatch splits phrases into batches the words service accepts, a failed
+// phrase fails the whole call.
+func (c *Client) NormBatch(ctx context.Context, phrases []string) ([][]string, error) {
+	results := make([][]string, 0, len(phrases))
+	for batch := range slices.Chunk(phrases, maxBatch) {
+		request := &wordspb.WordsBatchRequest{Phrases: make([]*wordspb.WordsRequest, 0, len(batch))}
+		for _, phrase := range batch {
+			request.Phrases = append(request.Phrases, &wordspb.WordsRequest{Phrase: phrase})
+		}
+		reply, err := c.client.NormBatch(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if len(reply.GetResults()) != len(batch) {
+			return nil, fmt.Errorf("got %d normalized phrases of %d", len(reply.GetResults()), len(batch))
+		}
+		for i, item := range reply.GetResults() {
+			if item.GetError() != "" {
+				return nil, fmt.Errorf("failed to normalize %q: %s", batch[i], item.GetError())
+			}
+			results = append(results, item.GetWords())
+		}
+	}
+	return results, nil
+}
+
 func (c *Client) Ping(ctx context.Context) error {
 	_, err := c.client.Ping(ctx, nil)
 	return err
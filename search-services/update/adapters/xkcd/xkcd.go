@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/liy0aay/xkcd-search/closers"
 	"github.com/liy0aay/xkcd-search/update/core"
@@ -15,21 +19,112 @@ import (
 
 const lastPath = "/info.0.json"
 
+// DefaultDescription joins all text fields of comics info.
+const DefaultDescription = "{{.Title}} {{.SafeTitle}} {{.Transcript}} {{.Alt}}"
+
+var defaultDescription = template.Must(template.New("description").Parse(DefaultDescription))
+
 type Client struct {
-	log    *slog.Logger
-	client http.Client
-	url    string
+	log         *slog.Logger
+	client      http.Client
+	url         string
+	description *template.Template
+	transport   *http.Transport
+	// maxTranscript caps transcript length in bytes, zero keeps it whole
+	maxTranscript int
+	// requestTimeout limits each request apart from the client timeout
+	requestTimeout time.Duration
+	// withoutAlt keeps alt text out of descriptions
+	withoutAlt bool
+}
+
+type Option func(*Client) error
+
+// WithDescription sets the template building comics description from
+// Title, SafeTitle, Transcript and Alt fields.
+func WithDescription(text string) Option {
+	return func(c *Client) error {
+		description, err := template.New("description").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid description template: %v", err)
+		}
+		c.description = description
+		return nil
+	}
+}
+
+// WithMaxTranscript keeps at most n bytes from the beginning of transcripts
+// put in the description, cut at a word boundary when there is one.
+func WithMaxTranscript(n int) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return fmt.Errorf("wrong max transcript length: %d", n)
+		}
+		c.maxTranscript = n
+		return nil
+	}
+}
+
+// WithRequestTimeout limits every Get and LastID call on its own, so bulk
+// updates fail fast on a stuck comics while the client timeout stays longer.
+// Zero leaves only the client timeout.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return fmt.Errorf("wrong request timeout: %v", timeout)
+		}
+		c.requestTimeout = timeout
+		return nil
+	}
+}
+
+// WithoutAlt leaves alt text out of descriptions, so it is not indexed.
+// Comics keep their alt text.
+func WithoutAlt() Option {
+	return func(c *Client) error {
+		c.withoutAlt = true
+		return nil
+	}
+}
+
+// WithTransport sends requests through a shared transport to reuse its
+// connections, a proxy gets a copy of it.
+func WithTransport(transport *http.Transport) Option {
+	return func(c *Client) error {
+		c.transport = transport
+		return nil
+	}
 }
 
-func NewClient(url string, timeout time.Duration, log *slog.Logger) (*Client, error) {
-	if url == "" {
+// NewClient sends requests through proxy if set, otherwise HTTP_PROXY/HTTPS_PROXY are honored.
+func NewClient(
+	baseURL, proxy string, timeout time.Duration, log *slog.Logger, opts ...Option,
+) (*Client, error) {
+	if baseURL == "" {
 		return nil, fmt.Errorf("empty base url specified")
 	}
-	return &Client{
-		client: http.Client{Timeout: timeout},
-		log:    log,
-		url:    url,
-	}, nil
+	c := &Client{
+		log:         log,
+		url:         baseURL,
+		description: defaultDescription,
+		transport:   http.DefaultTransport.(*http.Transport).Clone(),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	transport := c.transport
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %v", err)
+		}
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	c.client = http.Client{Timeout: timeout, Transport: transport}
+	return c, nil
 }
 
 func (c Client) Get(ctx context.Context, id int) (core.XKCDInfo, error) {
@@ -44,8 +139,13 @@ func (c Client) LastID(ctx context.Context) (int, error) {
 	return comics.ID, nil
 }
 
-func (c Client) get(ctx context.Context, url string) (core.XKCDInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func (c Client) get(ctx context.Context, reqURL string) (core.XKCDInfo, error) {
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return core.XKCDInfo{}, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -57,28 +157,55 @@ func (c Client) get(ctx context.Context, url string) (core.XKCDInfo, error) {
 	if resp.StatusCode == http.StatusNotFound {
 		return core.XKCDInfo{}, core.ErrNotFound
 	}
-	info := struct {
-		ID         int    `json:"num"`
-		URL        string `json:"img"`
-		Title      string `json:"title"`
-		SafeTitle  string `json:"safe_title"`
-		Transcript string `json:"transcript"`
-		Alt        string `json:"alt"`
-	}{}
+	var info comicsInfo
 	if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
 		return core.XKCDInfo{}, fmt.Errorf("failed to decode comics: %v", err)
 	}
 
+	info.Transcript = truncate(info.Transcript, c.maxTranscript)
+
+	description := c.description
+	if description == nil {
+		description = defaultDescription
+	}
+	described := info
+	if c.withoutAlt {
+		described.Alt = ""
+	}
+	var sb strings.Builder
+	if err := description.Execute(&sb, described); err != nil {
+		return core.XKCDInfo{}, fmt.Errorf("failed to build description: %v", err)
+	}
+
 	return core.XKCDInfo{
 		ID:          info.ID,
 		URL:         info.URL,
 		Title:       info.Title,
 		Alt:         info.Alt,
-		Description: strings.Join([]string{
-			info.Title,
-			info.SafeTitle,
-			info.Transcript,
-			info.Alt,
-		}, " "),
+		Description: sb.String(),
 	}, nil
 }
+
+func truncate(text string, limit int) string {
+	if limit == 0 || len(text) <= limit {
+		return text
+	}
+	// do not split a UTF-8 encoded rune
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if space := strings.LastIndexFunc(text[:cut+1], unicode.IsSpace); space > 0 {
+		cut = space
+	}
+	return text[:cut]
+}
+
+type comicsInfo struct {
+	ID         int    `json:"num"`
+	URL        string `json:"img"`
+	Title      string `json:"title"`
+	SafeTitle  string `json:"safe_title"`
+	Transcript string `json:"transcript"`
+	Alt        string `json:"alt"`
+}
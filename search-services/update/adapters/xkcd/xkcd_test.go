@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -108,3 +109,115 @@ func TestGet_InvalidJSON(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to decode comics")
 }
+
+func TestNewClient_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = io.WriteString(w, `{"num": 42}`)
+	}))
+	defer proxy.Close()
+
+	c, err := NewClient("http://xkcd.invalid", proxy.URL, time.Second, slog.Default())
+	require.NoError(t, err)
+
+	lastID, err := c.LastID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 42, lastID)
+	assert.Equal(t, []string{"http://xkcd.invalid/info.0.json"}, proxied)
+}
+
+func TestNewClient_InvalidProxy(t *testing.T) {
+	_, err := NewClient("https://xkcd.com", "://bad", time.Second, slog.Default())
+	require.Error(t, err)
+}
+
+func TestGet_DescriptionTemplate(t *testing.T) {
+	body := `{"num": 10, "title": "Title", "safe_title": "Safe", "transcript": "Transcript", "alt": "Alt"}`
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	tests := map[string]string{
+		"{{.Title}} {{.Alt}}":              "Title Alt",
+		"{{.Title}}, {{.Title}}; {{.Alt}}": "Title, Title; Alt",
+	}
+	for text, want := range tests {
+		c := testClient(rt)
+		require.NoError(t, WithDescription(text)(&c))
+
+		info, err := c.Get(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, want, info.Description)
+	}
+}
+
+func TestWithDescription_Invalid(t *testing.T) {
+	_, err := NewClient("https://xkcd.com", "", time.Second, slog.Default(), WithDescription("{{.Title"))
+	require.Error(t, err)
+}
+
+func TestGet_MaxTranscript(t *testing.T) {
+	body := `{"num": 10, "title": "Title", "transcript": "first words of a very long transcript", "alt": "Alt"}`
+	c := testClient(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}))
+	require.NoError(t, WithMaxTranscript(20)(&c))
+	require.NoError(t, WithDescription("{{.Transcript}}")(&c))
+
+	info, err := c.Get(context.Background(), 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, "first words of a", info.Description)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "whole", truncate("whole", 0))
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "exactly", truncate("exactly fits", 7))
+	assert.Equal(t, "nospace", truncate("nospaceatall", 7))
+	assert.Equal(t, "при", truncate("привет", 7), "runes are not split")
+}
+
+func TestGet_RequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "", 10*time.Second, slog.Default(), WithRequestTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = c.Get(context.Background(), 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.Less(t, time.Since(start), time.Second, "request timeout fires before the client one")
+
+	_, err = NewClient(srv.URL, "", time.Second, slog.Default(), WithRequestTimeout(-time.Second))
+	assert.Error(t, err)
+}
+
+func TestGet_WithoutAlt(t *testing.T) {
+	body := `{"num": 10, "title": "Python", "alt": "Python again"}`
+	c := testClient(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}))
+	require.NoError(t, WithoutAlt()(&c))
+
+	info, err := c.Get(context.Background(), 10)
+	require.NoError(t, err)
+	assert.NotContains(t, info.Description, "again")
+	assert.Equal(t, "Python again", info.Alt, "alt text is still stored")
+}
@@ -7,20 +7,69 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// HTTPTransport tunes connection reuse of outbound clients, zero keeps net/http defaults.
+type HTTPTransport struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns" env:"HTTP_MAX_IDLE_CONNS" env-default:"100"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host" env:"HTTP_MAX_IDLE_CONNS_PER_HOST" env-default:"16"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout" env:"HTTP_IDLE_CONN_TIMEOUT" env-default:"90s"`
+	KeepAlive           time.Duration `yaml:"keep_alive" env:"HTTP_KEEP_ALIVE" env-default:"30s"`
+}
+
 type XKCD struct {
 	URL         string        `yaml:"url" env:"XKCD_URL" env-default:"xkcd.com"`
+	Proxy       string        `yaml:"proxy" env:"XKCD_PROXY"`
+	Description string        `yaml:"description" env:"XKCD_DESCRIPTION" env-default:"{{.Title}} {{.SafeTitle}} {{.Transcript}} {{.Alt}}"`
 	Concurrency int           `yaml:"concurrency" env:"XKCD_CONCURRENCY" env-default:"1"`
 	Timeout     time.Duration `yaml:"timeout" env:"XKCD_TIMEOUT" env-default:"10s"`
 	CheckPeriod time.Duration `yaml:"check_period" env:"XKCD_CHECK_PERIOD" env-default:"1h"`
+	NewestFirst bool          `yaml:"newest_first" env:"XKCD_NEWEST_FIRST" env-default:"false"`
+	Checkpoint  int           `yaml:"checkpoint" env:"XKCD_CHECKPOINT" env-default:"0"`
+	NotFound    string        `yaml:"not_found" env:"XKCD_NOT_FOUND" env-default:"skip"`
+	// MaxTranscript caps transcripts before normalization, zero disables.
+	MaxTranscript int `yaml:"max_transcript" env:"XKCD_MAX_TRANSCRIPT" env-default:"0"`
+	// RequestTimeout limits each comics fetch apart from Timeout, zero disables.
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"XKCD_REQUEST_TIMEOUT" env-default:"0s"`
+	// ExcludeAlt keeps alt text out of indexed descriptions.
+	ExcludeAlt bool `yaml:"exclude_alt" env:"XKCD_EXCLUDE_ALT" env-default:"false"`
+}
+
+type LinkCheck struct {
+	Enabled bool          `yaml:"enabled" env:"LINK_CHECK_ENABLED" env-default:"false"`
+	Period  time.Duration `yaml:"period" env:"LINK_CHECK_PERIOD" env-default:"24h"`
+	Rate    int           `yaml:"rate" env:"LINK_CHECK_RATE" env-default:"5"`
+	Timeout time.Duration `yaml:"timeout" env:"LINK_CHECK_TIMEOUT" env-default:"10s"`
+}
+
+// Enrich tags comics with their explainxkcd wiki categories as they are
+// stored, at most Rate pages per second. Failures leave comics untagged.
+type Enrich struct {
+	Enabled bool          `yaml:"enabled" env:"ENRICH_ENABLED" env-default:"false"`
+	URL     string        `yaml:"url" env:"ENRICH_URL" env-default:"https://www.explainxkcd.com"`
+	Rate    int           `yaml:"rate" env:"ENRICH_RATE" env-default:"2"`
+	Timeout time.Duration `yaml:"timeout" env:"ENRICH_TIMEOUT" env-default:"10s"`
 }
 
 type Config struct {
-	LogLevel      string `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
-	Address       string `yaml:"update_address" env:"UPDATE_ADDRESS" env-default:"localhost:80"`
-	XKCD          XKCD   `yaml:"xkcd"`
-	DBAddress     string `yaml:"db_address" env:"DB_ADDRESS" env-default:"localhost:82"`
-	WordsAddress  string `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"localhost:81"`
-	BrokerAddress string `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
+	LogLevel       string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"DEBUG"`
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"STARTUP_TIMEOUT" env-default:"30s"`
+	Address        string        `yaml:"update_address" env:"UPDATE_ADDRESS" env-default:"localhost:80"`
+	XKCD           XKCD          `yaml:"xkcd"`
+	HTTPTransport  HTTPTransport `yaml:"http_transport"`
+	DBAddress      string        `yaml:"db_address" env:"DB_ADDRESS" env-default:"localhost:82"`
+	DBTimeout      time.Duration `yaml:"db_timeout" env:"DB_TIMEOUT" env-default:"5s"`
+	WordsAddress   string        `yaml:"words_address" env:"WORDS_ADDRESS" env-default:"localhost:81"`
+	MetricsAddress string        `yaml:"metrics_address" env:"METRICS_ADDRESS"`
+	BrokerAddress  string        `yaml:"broker_address" env:"BROKER_ADDRESS" env-default:"nats://localhost:4222"`
+	LinkCheck      LinkCheck     `yaml:"link_check"`
+	Enrich         Enrich        `yaml:"enrich"`
+	ReindexGrace   time.Duration `yaml:"reindex_grace" env:"REINDEX_GRACE" env-default:"0s"`
+	// NGrams indexes up to n adjacent words as keywords, zero disables,
+	// search must use the same n.
+	NGrams int `yaml:"ngrams" env:"INDEX_NGRAMS" env-default:"0"`
+	// Stems keeps the normalized words of comics in order for phrase
+	// searches and BM25 keyword frequencies, n-grams imply it. Comics
+	// stored while it was off are fetched again by the next update.
+	Stems bool `yaml:"stems" env:"INDEX_STEMS" env-default:"false"`
 }
 
 func MustLoad(configPath string) Config {
@@ -0,0 +1,56 @@
+package core
+
+import "context"
+
+// enrichOne stores categories the enricher finds for comics id apart from
+// its tags, failures only leave the stored categories as they are.
+func (s *Service) enrichOne(ctx context.Context, id int) bool {
+	categories, err := s.enricher.Tags(ctx, id)
+	if err != nil {
+		s.log.Warn("failed to enrich comics", "id", id, "error", err)
+		return false
+	}
+	if err := s.db.SetCategories(ctx, id, normalizeTags(categories)); err != nil {
+		s.log.Warn("failed to save comics categories", "id", id, "error", err)
+		return false
+	}
+	return true
+}
+
+// enrichLater enriches stored comics in the background at the pace of the
+// enricher, so that updates are not held by it. Ids queued while a pass
+// runs join it, subscribers learn of the enriched comics once it ends.
+func (s *Service) enrichLater(ctx context.Context, ids []int) {
+	s.enrichLock.Lock()
+	defer s.enrichLock.Unlock()
+	s.enrichQueue = append(s.enrichQueue, ids...)
+	if s.enriching {
+		return
+	}
+	s.enriching = true
+	go s.enrichPass(context.WithoutCancel(ctx))
+}
+
+func (s *Service) enrichPass(ctx context.Context) {
+	var enriched []int
+	for {
+		s.enrichLock.Lock()
+		if len(s.enrichQueue) == 0 {
+			s.enriching = false
+			s.enrichLock.Unlock()
+			break
+		}
+		id := s.enrichQueue[0]
+		s.enrichQueue = s.enrichQueue[1:]
+		s.enrichLock.Unlock()
+		if s.enrichOne(ctx, id) {
+			enriched = append(enriched, id)
+		}
+	}
+	s.log.Info("enriched comics", "count", len(enriched))
+	if len(enriched) > 0 && s.enrichPublisher != nil {
+		if err := s.enrichPublisher.PublishDBUpdateBatch(ctx, enriched); err != nil {
+			s.log.Error("failed to publish enriched comics", "error", err)
+		}
+	}
+}
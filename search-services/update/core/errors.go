@@ -5,3 +5,4 @@ import "errors"
 var ErrBadArguments = errors.New("arguments are not acceptable")
 var ErrAlreadyExists = errors.New("resource or task already exists")
 var ErrNotFound = errors.New("resource is not found")
+var ErrTimeout = errors.New("operation timed out")
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+func (s *Service) CheckLinks(ctx context.Context) error {
+	if s.links == nil {
+		return nil
+	}
+	urls, err := s.db.URLs(ctx)
+	if err != nil {
+		s.log.Error("failed to get comics URLs", "error", err)
+		return fmt.Errorf("failed to get comics URLs: %v", err)
+	}
+
+	var broken []BrokenLink
+	for _, id := range slices.Sorted(maps.Keys(urls)) {
+		if err := s.links.Check(ctx, urls[id]); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.log.Warn("comics image is unreachable", "id", id, "url", urls[id], "error", err)
+			broken = append(broken, BrokenLink{
+				ID:        id,
+				URL:       urls[id],
+				Reason:    err.Error(),
+				CheckedAt: time.Now(),
+			})
+		}
+	}
+	s.log.Info("checked comics image links", "total", len(urls), "broken", len(broken))
+
+	s.brokenLock.Lock()
+	s.broken = broken
+	s.brokenLock.Unlock()
+	return nil
+}
+
+func (s *Service) BrokenLinks(_ context.Context) []BrokenLink {
+	s.brokenLock.RLock()
+	defer s.brokenLock.RUnlock()
+	return slices.Clone(s.broken)
+}
@@ -1,21 +1,38 @@
 package core
 
+import "time"
+
 type ServiceStatus string
 
 const (
 	StatusRunning ServiceStatus = "running"
 	StatusIdle    ServiceStatus = "idle"
+	// StatusReindexing follows an update while search catches up with it.
+	StatusReindexing ServiceStatus = "reindexing"
 )
 
+// DBStats are derived from the stored comics on every request, a stats
+// reset does not change them.
 type DBStats struct {
 	WordsTotal    int
 	WordsUnique   int
 	ComicsFetched int
 }
 
+// Counters are kept in memory by the service, they count comics handled
+// by updates since the service started or its stats were reset.
+type Counters struct {
+	Added   int
+	Skipped int
+	Failed  int
+}
+
+// ServiceStats holds the stored DB stats, ComicsTotal as reported by xkcd
+// and the in-memory Counters, only the counters are reset.
 type ServiceStats struct {
 	DBStats     DBStats
 	ComicsTotal int
+	Counters    Counters
 }
 
 type Comics struct {
@@ -24,6 +41,18 @@ type Comics struct {
 	Title string
 	Alt   string
 	Words []string
+	// Stems are the description words in order, for phrase searches,
+	// nil when they are not kept.
+	Stems []string
+}
+
+// Progress of a running update, Fetched of the Total missing comics are
+// done with so far, skipped and failed ones included, and CurrentID is the
+// latest of them.
+type Progress struct {
+	Fetched   int
+	Total     int
+	CurrentID int
 }
 
 type XKCDInfo struct {
@@ -33,3 +62,10 @@ type XKCDInfo struct {
 	Alt         string
 	Description string
 }
+
+type BrokenLink struct {
+	ID        int
+	URL       string
+	Reason    string
+	CheckedAt time.Time
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"context"
+
+	"github.com/liy0aay/xkcd-search/ngrams"
+)
+
+// ordered tells whether comics are stored with their stems.
+func (s *Service) ordered() bool {
+	return s.stemmed || s.ngrams > 1
+}
+
+// stems normalizes words of text one by one to keep them in order, stop
+// words are dropped, so the words around them become adjacent.
+func (s *Service) stems(ctx context.Context, text string) ([]string, error) {
+	normalized, err := s.words.NormBatch(ctx, ngrams.Tokens(text))
+	if err != nil {
+		return nil, err
+	}
+	stems := make([]string, 0, len(normalized))
+	for _, words := range normalized {
+		stems = append(stems, words...)
+	}
+	return stems, nil
+}
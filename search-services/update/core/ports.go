@@ -6,16 +6,33 @@ import (
 
 type Updater interface {
 	Update(context.Context) error
+	UpdateOne(ctx context.Context, id int) error
 	Stats(context.Context) (ServiceStats, error)
+	ResetStats(context.Context) error
 	Status(context.Context) ServiceStatus
 	Drop(context.Context) error
+	CheckLinks(context.Context) error
+	BrokenLinks(context.Context) []BrokenLink
+	SetTags(ctx context.Context, id int, tags []string) error
+	GetTags(ctx context.Context, id int) ([]string, error)
+	// Progress streams the progress of the running update until it finishes.
+	Progress(context.Context) <-chan Progress
 }
 
 type DB interface {
 	Add(context.Context, Comics) error
 	Stats(context.Context) (DBStats, error)
 	Drop(context.Context) error
+	// Delete removes comics id, ErrNotFound tells it was not stored.
+	Delete(ctx context.Context, id int) error
 	IDs(context.Context) ([]int, error)
+	// Unstemmed lists comics stored while stems were not kept.
+	Unstemmed(context.Context) ([]int, error)
+	URLs(context.Context) (map[int]string, error)
+	SetTags(ctx context.Context, id int, tags []string) error
+	GetTags(ctx context.Context, id int) ([]string, error)
+	// SetCategories replaces categories found by an enricher, tags stay.
+	SetCategories(ctx context.Context, id int, categories []string) error
 }
 
 type XKCD interface {
@@ -23,11 +40,23 @@ type XKCD interface {
 	LastID(context.Context) (int, error)
 }
 
+type LinkChecker interface {
+	Check(ctx context.Context, url string) error
+}
+
+// Enricher finds categories of comics in an external source.
+type Enricher interface {
+	Tags(ctx context.Context, id int) ([]string, error)
+}
+
 type Words interface {
 	Norm(ctx context.Context, phrase string) ([]string, error)
+	// NormBatch normalizes phrases in a single call, results are in their order.
+	NormBatch(ctx context.Context, phrases []string) ([][]string, error)
 }
 
 type Publisher interface {
 	PublishDBUpdateEvent(ctx context.Context) error
+	PublishDBUpdateBatch(ctx context.Context, ids []int) error
 	PublishDBDropEvent(ctx context.Context) error
 }
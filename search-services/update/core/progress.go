@@ -0,0 +1,78 @@
+package core
+
+import "context"
+
+// progress tracks the running update for its watchers, each watcher
+// keeps only the latest progress it has not received yet.
+type progress struct {
+	running  bool
+	current  Progress
+	watchers map[chan Progress]struct{}
+}
+
+// Progress streams the progress of the running update, the channel is
+// closed once the update finishes or ctx is done, at once when no update runs.
+func (s *Service) Progress(ctx context.Context) <-chan Progress {
+	ch := make(chan Progress, 1)
+	s.progressLock.Lock()
+	defer s.progressLock.Unlock()
+	if !s.progress.running {
+		close(ch)
+		return ch
+	}
+	ch <- s.progress.current
+	s.progress.watchers[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		s.unwatch(ch)
+	}()
+	return ch
+}
+
+func (s *Service) unwatch(ch chan Progress) {
+	s.progressLock.Lock()
+	defer s.progressLock.Unlock()
+	if _, ok := s.progress.watchers[ch]; ok {
+		delete(s.progress.watchers, ch)
+		close(ch)
+	}
+}
+
+func (s *Service) startProgress() {
+	s.progressLock.Lock()
+	defer s.progressLock.Unlock()
+	s.progress = progress{running: true, watchers: make(map[chan Progress]struct{})}
+}
+
+// reportProgress passes the progress to watchers, replacing one they
+// have not received yet.
+func (s *Service) reportProgress(update func(*Progress)) {
+	s.progressLock.Lock()
+	defer s.progressLock.Unlock()
+	update(&s.progress.current)
+	for ch := range s.progress.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- s.progress.current
+	}
+}
+
+// doneWith counts comics id of the update as fetched, whether it is
+// stored, skipped or failed, so Fetched reaches Total once all are done.
+func (s *Service) doneWith(id int) {
+	s.reportProgress(func(p *Progress) {
+		p.Fetched++
+		p.CurrentID = id
+	})
+}
+
+func (s *Service) finishProgress() {
+	s.progressLock.Lock()
+	defer s.progressLock.Unlock()
+	for ch := range s.progress.watchers {
+		close(ch)
+	}
+	s.progress = progress{}
+}
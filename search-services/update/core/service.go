@@ -2,11 +2,15 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/liy0aay/xkcd-search/ngrams"
 )
 
 type Service struct {
@@ -17,21 +21,149 @@ type Service struct {
 	concurrency int
 	inProgress  atomic.Bool
 	lock        sync.Mutex
+	links       LinkChecker
+	enricher    Enricher
+	broken      []BrokenLink
+	brokenLock  sync.RWMutex
+	newestFirst bool
+	publisher   Publisher
+	checkpoint  int
+	notFound    NotFoundPolicy
+	grace       time.Duration
+	ngrams      int
+	stemmed     bool
+	graceUntil  atomic.Int64
+
+	// missingSkipped remembers xkcd answered 404 for missingComics,
+	// so later updates do not ask for it again
+	missingSkipped atomic.Bool
+
+	// added, skipped and failed count comics handled by updates until
+	// the stats are reset
+	added   atomic.Int64
+	skipped atomic.Int64
+	failed  atomic.Int64
+
+	enrichPublisher Publisher
+	enrichQueue     []int
+	enriching       bool
+	enrichLock      sync.Mutex
+
+	progress     progress
+	progressLock sync.Mutex
+}
+
+type Option func(*Service)
+
+// NotFoundPolicy tells an update what to do with ids xkcd does not know.
+type NotFoundPolicy string
+
+const (
+	NotFoundSkip NotFoundPolicy = "skip"
+	NotFoundStop NotFoundPolicy = "stop"
+)
+
+// missingComics is never published, xkcd answers 404 for it as a joke,
+// so it is skipped whatever the not found policy and asked for only once.
+const missingComics = 404
+
+// WithNotFoundPolicy sets how updates treat missing comics, NotFoundSkip by default.
+func WithNotFoundPolicy(policy NotFoundPolicy) Option {
+	return func(s *Service) {
+		s.notFound = policy
+	}
+}
+
+// WithLinkChecker enables validation of stored comics image URLs.
+func WithLinkChecker(links LinkChecker) Option {
+	return func(s *Service) {
+		s.links = links
+	}
+}
+
+// WithEnricher stores categories found by enricher for comics once they are
+// stored, publisher announces the enriched comics.
+func WithEnricher(enricher Enricher, publisher Publisher) Option {
+	return func(s *Service) {
+		s.enricher = enricher
+		s.enrichPublisher = publisher
+	}
+}
+
+// WithNewestFirst makes updates fetch missing comics from the newest one down.
+func WithNewestFirst() Option {
+	return func(s *Service) {
+		s.newestFirst = true
+	}
+}
+
+// WithCheckpoints publishes a db update event with the ids of every n
+// comics added during an update.
+func WithCheckpoints(publisher Publisher, n int) Option {
+	return func(s *Service) {
+		s.publisher = publisher
+		s.checkpoint = n
+	}
+}
+
+// WithReindexGrace reports StatusReindexing for grace after each update,
+// giving search time to rebuild its index from the update event.
+func WithReindexGrace(grace time.Duration) Option {
+	return func(s *Service) {
+		s.grace = grace
+	}
+}
+
+// WithNGrams also indexes every 2 up to n adjacent normalized words as
+// a keyword, so phrases rank comics having them in a row higher.
+func WithNGrams(n int) Option {
+	return func(s *Service) {
+		s.ngrams = n
+	}
+}
+
+// WithStems stores the normalized words of comics in order, search needs
+// them for phrase searches and BM25 keyword frequencies. N-grams imply it.
+func WithStems() Option {
+	return func(s *Service) {
+		s.stemmed = true
+	}
 }
 
 func NewService(
-	log *slog.Logger, db DB, xkcd XKCD, words Words, concurrency int,
+	log *slog.Logger, db DB, xkcd XKCD, words Words, concurrency int, opts ...Option,
 ) (*Service, error) {
 	if concurrency < 1 {
 		return nil, fmt.Errorf("wrong concurrency specified: %d", concurrency)
 	}
-	return &Service{
+	s := &Service{
 		log:         log,
 		db:          db,
 		xkcd:        xkcd,
 		words:       words,
 		concurrency: concurrency,
-	}, nil
+		notFound:    NotFoundSkip,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.notFound != NotFoundSkip && s.notFound != NotFoundStop {
+		return nil, fmt.Errorf("unknown not found policy: %q", s.notFound)
+	}
+	return s, nil
+}
+
+// unique drops repeated n-grams keeping the first occurrences in order,
+// the words service already returns every word once.
+func unique(words []string) []string {
+	seen := make(map[string]bool, len(words))
+	return slices.DeleteFunc(words, func(word string) bool {
+		if seen[word] {
+			return true
+		}
+		seen[word] = true
+		return false
+	})
 }
 
 func (s *Service) Update(ctx context.Context) (err error) {
@@ -43,6 +175,13 @@ func (s *Service) Update(ctx context.Context) (err error) {
 
 	s.inProgress.Store(true)
 	defer s.inProgress.Store(false)
+	s.startProgress()
+	defer s.finishProgress()
+	defer func() {
+		if err == nil && s.grace > 0 {
+			s.graceUntil.Store(time.Now().Add(s.grace).UnixNano())
+		}
+	}()
 
 	s.log.Info("update started")
 	defer func(start time.Time) {
@@ -60,6 +199,21 @@ func (s *Service) Update(ctx context.Context) (err error) {
 	for _, id := range IDs {
 		exists[id] = true
 	}
+	if s.missingSkipped.Load() {
+		exists[missingComics] = true
+	}
+	if s.ordered() {
+		// comics stored before stems were kept are fetched again to fill them in
+		unstemmed, err := s.db.Unstemmed(ctx)
+		if err != nil {
+			s.log.Error("failed to get comics without stems", "error", err)
+			return fmt.Errorf("failed to get comics without stems: %v", err)
+		}
+		s.log.Debug("comics to backfill stems", "count", len(unstemmed))
+		for _, id := range unstemmed {
+			delete(exists, id)
+		}
+	}
 
 	// get last comics ID
 	lastID, err := s.xkcd.LastID(ctx)
@@ -68,35 +222,71 @@ func (s *Service) Update(ctx context.Context) (err error) {
 		return fmt.Errorf("failed to get last ID in XKCD: %v", err)
 	}
 	s.log.Debug("last comics ID in XKCD", "id", lastID)
+	var total int
+	for id := 1; id <= lastID; id++ {
+		if !exists[id] {
+			total++
+		}
+	}
+	s.reportProgress(func(p *Progress) { p.Total = total })
+
+	// fetching is cancelled separately, so comics already fetched are stored
+	fetchCtx, stopFetching := context.WithCancel(ctx)
+	defer stopFetching()
+	skippedBefore := s.skipped.Load()
+	var missing atomic.Int64
+	notFound := func(id int) {
+		if s.notFound == NotFoundStop {
+			missing.CompareAndSwap(0, int64(id))
+			stopFetching()
+			return
+		}
+		s.skipped.Add(1)
+	}
 
-	generator := generateIDs(ctx, 1, lastID, exists)
-	fetchers := s.getComics(ctx, generator)
+	generator := generateIDs(fetchCtx, 1, lastID, exists, s.newestFirst)
+	fetchers := s.getComics(fetchCtx, generator, notFound)
 
 	var errorsFound bool
 	var added int
+	var batch, stored []int
 	for info := range fetchers {
-		words, err := s.words.Norm(ctx, info.Description)
+		s.doneWith(info.ID)
+		comics, err := s.normalize(ctx, info)
 		if err != nil {
 			errorsFound = true
+			s.failed.Add(1)
 			s.log.Error("failed to normalize", "id", info.ID, "error", err)
 			continue
 		}
-		err = s.db.Add(ctx, Comics{
-			ID:    info.ID,
-			URL:   info.URL,
-			Title: info.Title,
-			Alt:   info.Alt,
-			Words: words,
-		})
-		if err != nil {
+		if err := s.db.Add(ctx, comics); err != nil {
 			errorsFound = true
+			s.failed.Add(1)
 			s.log.Error("failed to save comics", "id", info.ID, "error", err)
 			continue
 		}
 		added++
+		s.added.Add(1)
+		stored = append(stored, info.ID)
+		if s.checkpoint > 0 {
+			batch = append(batch, info.ID)
+			if len(batch) == s.checkpoint {
+				s.log.Debug("update checkpoint", "added", added)
+				if err := s.publisher.PublishDBUpdateBatch(ctx, batch); err != nil {
+					s.log.Error("failed to publish checkpoint", "error", err)
+				}
+				batch = nil
+			}
+		}
+	}
+	s.log.Debug("added new comics", "count", added, "skipped", s.skipped.Load()-skippedBefore)
+	if s.enricher != nil && len(stored) > 0 {
+		s.enrichLater(ctx, stored)
 	}
-	s.log.Debug("added new comics", "count", added)
 
+	if id := missing.Load(); id != 0 {
+		return fmt.Errorf("update stopped at comics %d: %w", id, ErrNotFound)
+	}
 	if errorsFound {
 		return fmt.Errorf("failed to fetch/store some comics")
 	}
@@ -104,26 +294,90 @@ func (s *Service) Update(ctx context.Context) (err error) {
 	return nil
 }
 
-func generateIDs(ctx context.Context, first, last int, exists map[int]bool) <-chan int {
+// normalize makes comics to store of fetched info.
+func (s *Service) normalize(ctx context.Context, info XKCDInfo) (Comics, error) {
+	words, err := s.words.Norm(ctx, info.Description)
+	if err != nil {
+		return Comics{}, err
+	}
+	var stems []string
+	if s.ordered() {
+		stems, err = s.stems(ctx, info.Description)
+		if err != nil {
+			// comics are still searchable by keywords, the empty stems keep
+			// the next update from fetching them again
+			s.log.Warn("failed to normalize in order", "id", info.ID, "error", err)
+			stems = []string{}
+		}
+	}
+	if s.ngrams > 1 {
+		words = append(words, unique(ngrams.Join(stems, s.ngrams))...)
+	}
+	return Comics{
+		ID:    info.ID,
+		URL:   info.URL,
+		Title: info.Title,
+		Alt:   info.Alt,
+		Words: words,
+		Stems: stems,
+	}, nil
+}
+
+// UpdateOne fetches comics id again and stores it over the previous version,
+// ErrNotFound tells xkcd has no such comics, a stored one is deleted then.
+func (s *Service) UpdateOne(ctx context.Context, id int) error {
+	info, err := s.xkcd.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		if err := s.db.Delete(ctx, id); err != nil && !errors.Is(err, ErrNotFound) {
+			s.log.Error("failed to delete comics gone from xkcd", "id", id, "error", err)
+			return err
+		}
+		s.log.Info("comics not found in xkcd", "id", id)
+		return err
+	}
+	if err != nil {
+		s.log.Error("failed to get comics", "id", id, "error", err)
+		return err
+	}
+	comics, err := s.normalize(ctx, info)
+	if err != nil {
+		s.log.Error("failed to normalize", "id", id, "error", err)
+		return err
+	}
+	if err := s.db.Add(ctx, comics); err != nil {
+		s.log.Error("failed to save comics", "id", id, "error", err)
+		return err
+	}
+	if s.enricher != nil {
+		s.enrichOne(ctx, id)
+	}
+	s.log.Info("comics updated", "id", id)
+	return nil
+}
+
+func generateIDs(ctx context.Context, first, last int, exists map[int]bool, desc bool) <-chan int {
 	ch := make(chan int)
 	go func() {
 		defer close(ch)
-		for i := first; i <= last; i++ {
+		for n := range last - first + 1 {
+			i := first + n
+			if desc {
+				i = last - n
+			}
 			if exists[i] {
 				continue
 			}
 			select {
 			case <-ctx.Done():
 				return
-			default:
-				ch <- i
+			case ch <- i:
 			}
 		}
 	}()
 	return ch
 }
 
-func (s *Service) getComics(ctx context.Context, in <-chan int) <-chan XKCDInfo {
+func (s *Service) getComics(ctx context.Context, in <-chan int, notFound func(id int)) <-chan XKCDInfo {
 	out := make(chan XKCDInfo)
 	var wg sync.WaitGroup
 	wg.Add(s.concurrency)
@@ -134,14 +388,27 @@ func (s *Service) getComics(ctx context.Context, in <-chan int) <-chan XKCDInfo
 			defer s.log.Debug("fetcher down", "id", i)
 			defer wg.Done()
 			for id := range in {
-				if id == 404 {
-					// special case
-					out <- XKCDInfo{ID: id, Description: "404 Not found"}
-					continue
+				if ctx.Err() != nil {
+					return
 				}
 				info, err := s.xkcd.Get(ctx, id)
+				if errors.Is(err, ErrNotFound) && id == missingComics {
+					s.log.Info("skipped comics missing on purpose", "id", id)
+					s.missingSkipped.Store(true)
+					s.skipped.Add(1)
+					s.doneWith(id)
+					continue
+				}
+				if errors.Is(err, ErrNotFound) {
+					s.log.Warn("comics not found", "id", id, "policy", s.notFound)
+					notFound(id)
+					s.doneWith(id)
+					continue
+				}
 				if err != nil {
 					s.log.Error("failed to get comics", "id", id, "error", err)
+					s.failed.Add(1)
+					s.doneWith(id)
 					continue
 				}
 				s.log.Debug("fetched", "id", id)
@@ -168,16 +435,34 @@ func (s *Service) Stats(ctx context.Context) (ServiceStats, error) {
 		s.log.Error("failed to get last comics ID", "error", err)
 		return ServiceStats{}, err
 	}
+
 	return ServiceStats{
 		DBStats:     dbStats,
 		ComicsTotal: lastID,
+		Counters: Counters{
+			Added:   int(s.added.Load()),
+			Skipped: int(s.skipped.Load()),
+			Failed:  int(s.failed.Load()),
+		},
 	}, nil
 }
 
+// ResetStats zeroes the counters kept in memory, stats derived from the
+// stored comics are not changed.
+func (s *Service) ResetStats(ctx context.Context) error {
+	s.added.Store(0)
+	s.skipped.Store(0)
+	s.failed.Store(0)
+	return nil
+}
+
 func (s *Service) Status(ctx context.Context) ServiceStatus {
 	if s.inProgress.Load() {
 		return StatusRunning
 	}
+	if time.Now().UnixNano() < s.graceUntil.Load() {
+		return StatusReindexing
+	}
 	return StatusIdle
 }
 
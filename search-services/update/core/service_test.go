@@ -5,7 +5,13 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,13 +21,20 @@ var noopLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
 
 type FakeDB struct {
 	added       []Comics
+	deleted     []int
 	dropCalled  bool
 	IDsResult   []int
+	unstemmed   []int
 	StatsResult DBStats
+	URLsResult  map[int]string
+	tags        map[int][]string
 	ErrAdd      error
-	ErrIDs      error
-	ErrStats    error
-	ErrDrop     error
+	// categories are set by the background enrichment
+	categories     map[int][]string
+	categoriesLock sync.Mutex
+	ErrIDs         error
+	ErrStats       error
+	ErrDrop        error
 }
 
 func (f *FakeDB) Add(ctx context.Context, c Comics) error {
@@ -39,6 +52,25 @@ func (f *FakeDB) IDs(ctx context.Context) ([]int, error) {
 	return f.IDsResult, nil
 }
 
+// Unstemmed lists unstemmed and added comics stored with nil stems.
+func (f *FakeDB) Unstemmed(ctx context.Context) ([]int, error) {
+	unstemmed := slices.Clone(f.unstemmed)
+	for _, c := range f.added {
+		if c.Stems == nil {
+			unstemmed = append(unstemmed, c.ID)
+		}
+	}
+	return unstemmed, nil
+}
+
+func (f *FakeDB) Delete(ctx context.Context, id int) error {
+	if !slices.Contains(f.IDsResult, id) {
+		return ErrNotFound
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
 func (f *FakeDB) Drop(ctx context.Context) error {
 	f.dropCalled = true
 	return f.ErrDrop
@@ -51,11 +83,49 @@ func (f *FakeDB) Stats(ctx context.Context) (DBStats, error) {
 	return f.StatsResult, nil
 }
 
+func (f *FakeDB) URLs(ctx context.Context) (map[int]string, error) {
+	return f.URLsResult, nil
+}
+
+func (f *FakeDB) SetTags(ctx context.Context, id int, tags []string) error {
+	if _, ok := f.tags[id]; !ok {
+		return ErrNotFound
+	}
+	f.tags[id] = tags
+	return nil
+}
+
+func (f *FakeDB) GetTags(ctx context.Context, id int) ([]string, error) {
+	tags, ok := f.tags[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return tags, nil
+}
+
+func (f *FakeDB) SetCategories(ctx context.Context, id int, categories []string) error {
+	f.categoriesLock.Lock()
+	defer f.categoriesLock.Unlock()
+	if f.categories == nil {
+		f.categories = make(map[int][]string)
+	}
+	f.categories[id] = categories
+	return nil
+}
+
+func (f *FakeDB) Categories() map[int][]string {
+	f.categoriesLock.Lock()
+	defer f.categoriesLock.Unlock()
+	return maps.Clone(f.categories)
+}
+
 type FakeXKCD struct {
-	lastID int
-	comics map[int]XKCDInfo
-	ErrGet error
-	ErrID  error
+	lastID  int
+	comics  map[int]XKCDInfo
+	fetched []int
+	lock    sync.Mutex
+	ErrGet  error
+	ErrID   error
 }
 
 func (f *FakeXKCD) LastID(ctx context.Context) (int, error) {
@@ -66,10 +136,17 @@ func (f *FakeXKCD) LastID(ctx context.Context) (int, error) {
 }
 
 func (f *FakeXKCD) Get(ctx context.Context, id int) (XKCDInfo, error) {
+	f.lock.Lock()
+	f.fetched = append(f.fetched, id)
+	f.lock.Unlock()
 	if f.ErrGet != nil {
 		return XKCDInfo{}, f.ErrGet
 	}
-	return f.comics[id], nil
+	info, ok := f.comics[id]
+	if f.comics != nil && !ok {
+		return XKCDInfo{}, ErrNotFound
+	}
+	return info, nil
 }
 
 type FakeWords struct {
@@ -84,6 +161,17 @@ func (fw *FakeWords) Norm(ctx context.Context, phrase string) ([]string, error)
 	return []string{"word"}, nil
 }
 
+func (fw *FakeWords) NormBatch(ctx context.Context, phrases []string) ([][]string, error) {
+	if fw.Err != nil {
+		return nil, fw.Err
+	}
+	normalized := make([][]string, len(phrases))
+	for i := range phrases {
+		normalized[i] = []string{"word"}
+	}
+	return normalized, nil
+}
+
 func TestService_Status(t *testing.T) {
 	db := &FakeDB{}
 	xkcd := &FakeXKCD{}
@@ -106,6 +194,46 @@ func TestService_Drop(t *testing.T) {
 	assert.True(t, db.dropCalled)
 }
 
+func TestService_ResetStats(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{StatsResult: DBStats{WordsTotal: 10, WordsUnique: 5, ComicsFetched: 2}}
+	xkcd := &FakeXKCD{
+		lastID: 3,
+		comics: map[int]XKCDInfo{1: {ID: 1, Description: "happy"}, 3: {ID: 3, Description: "year"}},
+	}
+	svc, err := NewService(noopLogger, db, xkcd, &FakeWords{}, 1)
+	require.NoError(t, err)
+	require.NoError(t, svc.Update(ctx))
+
+	stats, err := svc.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Counters{Added: 2, Skipped: 1}, stats.Counters)
+
+	require.NoError(t, svc.ResetStats(ctx))
+
+	stats, err = svc.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Counters{}, stats.Counters)
+	assert.Equal(t, db.StatsResult, stats.DBStats, "stored stats are not reset")
+	assert.Equal(t, 3, stats.ComicsTotal)
+}
+
+func TestService_Tags(t *testing.T) {
+	ctx := context.Background()
+	db := &FakeDB{tags: map[int][]string{1: {}}}
+	svc, err := NewService(noopLogger, db, &FakeXKCD{}, &FakeWords{}, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.SetTags(ctx, 1, []string{" Math", "math", "", "romance"}))
+	tags, err := svc.GetTags(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"math", "romance"}, tags)
+
+	assert.ErrorIs(t, svc.SetTags(ctx, 2, []string{"math"}), ErrNotFound)
+	_, err = svc.GetTags(ctx, 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
 func TestService_Stats(t *testing.T) {
 	db := &FakeDB{StatsResult: DBStats{WordsTotal: 10}}
 	xkcd := &FakeXKCD{lastID: 42}
@@ -140,6 +268,31 @@ func TestService_Update_HappyPath(t *testing.T) {
 	assert.ElementsMatch(t, []string{"url2", "url3"}, addedURLs)
 }
 
+func TestService_Update_FetchesOnlyGaps(t *testing.T) {
+	db := &FakeDB{IDsResult: []int{1, 3, 4, 7}}
+	xkcd := &FakeXKCD{lastID: 8, comics: map[int]XKCDInfo{}}
+	for id := 1; id <= 8; id++ {
+		xkcd.comics[id] = XKCDInfo{ID: id, Description: "comics"}
+	}
+	svc, err := NewService(noopLogger, db, xkcd, &FakeWords{}, 3)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+
+	assert.ElementsMatch(t, []int{2, 5, 6, 8}, xkcd.fetched)
+	var added []int
+	for _, c := range db.added {
+		added = append(added, c.ID)
+	}
+	assert.ElementsMatch(t, []int{2, 5, 6, 8}, added)
+
+	// a rerun resumes with nothing left to fetch
+	db.IDsResult = append(db.IDsResult, added...)
+	xkcd.fetched = nil
+	require.NoError(t, svc.Update(context.Background()))
+	assert.Empty(t, xkcd.fetched)
+}
+
 func TestService_Update_LockPreventsDoubleRun(t *testing.T) {
 	db := &FakeDB{}
 	xkcd := &FakeXKCD{}
@@ -171,3 +324,522 @@ func TestService_Update_XKCDError(t *testing.T) {
 	err := svc.Update(context.Background())
 	assert.Error(t, err)
 }
+
+type FakeLinkChecker struct {
+	broken map[string]bool
+}
+
+func (f *FakeLinkChecker) Check(ctx context.Context, url string) error {
+	if f.broken[url] {
+		return errors.New("unexpected status: 404")
+	}
+	return nil
+}
+
+func TestService_CheckLinks(t *testing.T) {
+	db := &FakeDB{URLsResult: map[int]string{
+		1: "https://imgs.xkcd.com/comics/one.png",
+		2: "https://imgs.xkcd.com/comics/two.png",
+		3: "https://imgs.xkcd.com/comics/three.png",
+	}}
+	checker := &FakeLinkChecker{broken: map[string]bool{
+		"https://imgs.xkcd.com/comics/two.png": true,
+	}}
+	svc, _ := NewService(noopLogger, db, &FakeXKCD{}, &FakeWords{}, 1, WithLinkChecker(checker))
+
+	require.Empty(t, svc.BrokenLinks(context.Background()))
+	require.NoError(t, svc.CheckLinks(context.Background()))
+
+	broken := svc.BrokenLinks(context.Background())
+	require.Len(t, broken, 1)
+	assert.Equal(t, 2, broken[0].ID)
+	assert.Equal(t, "https://imgs.xkcd.com/comics/two.png", broken[0].URL)
+	assert.Contains(t, broken[0].Reason, "404")
+}
+
+type FakePublisher struct {
+	updates int
+	batches [][]int
+	lock    sync.Mutex
+}
+
+func (f *FakePublisher) PublishDBUpdateBatch(ctx context.Context, ids []int) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.batches = append(f.batches, ids)
+	return nil
+}
+
+func (f *FakePublisher) Batches() [][]int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return slices.Clone(f.batches)
+}
+
+func (f *FakePublisher) PublishDBUpdateEvent(ctx context.Context) error {
+	f.updates++
+	return nil
+}
+
+func (f *FakePublisher) PublishDBDropEvent(ctx context.Context) error {
+	return nil
+}
+
+func TestService_Update_NewestFirstWithCheckpoints(t *testing.T) {
+	db := &FakeDB{IDsResult: []int{3}}
+	xkcd := &FakeXKCD{lastID: 7, comics: map[int]XKCDInfo{}}
+	for id := 1; id <= 7; id++ {
+		xkcd.comics[id] = XKCDInfo{ID: id}
+	}
+	publisher := &FakePublisher{}
+	svc, _ := NewService(noopLogger, db, xkcd, &FakeWords{}, 1,
+		WithNewestFirst(), WithCheckpoints(publisher, 2))
+
+	err := svc.Update(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 6, 5, 4, 2, 1}, xkcd.fetched)
+	assert.Equal(t, [][]int{{7, 6}, {5, 4}, {2, 1}}, publisher.batches)
+}
+
+func gapXKCD() *FakeXKCD {
+	xkcd := &FakeXKCD{lastID: 5, comics: map[int]XKCDInfo{}}
+	for _, id := range []int{1, 2, 4, 5} {
+		xkcd.comics[id] = XKCDInfo{ID: id}
+	}
+	return xkcd
+}
+
+func TestService_Update_NotFoundSkip(t *testing.T) {
+	db := &FakeDB{}
+	svc, err := NewService(noopLogger, db, gapXKCD(), &FakeWords{}, 1)
+	require.NoError(t, err)
+
+	err = svc.Update(context.Background())
+
+	require.NoError(t, err)
+	var added []int
+	for _, c := range db.added {
+		added = append(added, c.ID)
+	}
+	assert.Equal(t, []int{1, 2, 4, 5}, added)
+	assert.EqualValues(t, 1, svc.skipped.Load())
+}
+
+func TestService_Update_NotFoundStop(t *testing.T) {
+	db := &FakeDB{}
+	svc, err := NewService(noopLogger, db, gapXKCD(), &FakeWords{}, 1,
+		WithNotFoundPolicy(NotFoundStop))
+	require.NoError(t, err)
+
+	err = svc.Update(context.Background())
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	for _, c := range db.added {
+		assert.Less(t, c.ID, 3)
+	}
+	assert.EqualValues(t, 0, svc.skipped.Load())
+}
+
+func TestService_Update_SkipsMissingComics(t *testing.T) {
+	for _, policy := range []NotFoundPolicy{NotFoundSkip, NotFoundStop} {
+		xkcd := &FakeXKCD{lastID: 406, comics: map[int]XKCDInfo{}}
+		for _, id := range []int{403, 405, 406} {
+			xkcd.comics[id] = XKCDInfo{ID: id}
+		}
+		db := &FakeDB{}
+		for id := 1; id < 403; id++ {
+			db.IDsResult = append(db.IDsResult, id)
+		}
+		svc, err := NewService(noopLogger, db, xkcd, &FakeWords{}, 1, WithNotFoundPolicy(policy))
+		require.NoError(t, err)
+
+		err = svc.Update(context.Background())
+
+		require.NoError(t, err, policy)
+		var added []int
+		for _, c := range db.added {
+			added = append(added, c.ID)
+		}
+		assert.Equal(t, []int{403, 405, 406}, added, policy)
+		assert.Contains(t, xkcd.fetched, 404, policy)
+
+		xkcd.fetched = nil
+		require.NoError(t, svc.Update(context.Background()), policy)
+		assert.NotContains(t, xkcd.fetched, 404, policy)
+	}
+}
+
+func TestService_UpdateOne(t *testing.T) {
+	db := &FakeDB{}
+	xkcd := &FakeXKCD{comics: map[int]XKCDInfo{
+		3: {ID: 3, Title: "Island", Description: "island sketch"},
+	}}
+	svc, err := NewService(noopLogger, db, xkcd, &FakeWords{}, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.UpdateOne(context.Background(), 3))
+	require.Len(t, db.added, 1)
+	assert.Equal(t, 3, db.added[0].ID)
+	assert.Equal(t, "Island", db.added[0].Title)
+	assert.Equal(t, []string{"word"}, db.added[0].Words)
+	assert.Equal(t, []int{3}, xkcd.fetched)
+
+	assert.ErrorIs(t, svc.UpdateOne(context.Background(), 4), ErrNotFound)
+	assert.Len(t, db.added, 1)
+	assert.Empty(t, db.deleted, "comics never stored is not deleted")
+}
+
+func TestService_UpdateOne_GoneFromXKCD(t *testing.T) {
+	db := &FakeDB{IDsResult: []int{3, 4}}
+	xkcd := &FakeXKCD{comics: map[int]XKCDInfo{3: {ID: 3, Description: "island"}}}
+	svc, err := NewService(noopLogger, db, xkcd, &FakeWords{}, 1)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.UpdateOne(context.Background(), 4), ErrNotFound)
+	assert.Equal(t, []int{4}, db.deleted)
+	assert.Empty(t, db.added)
+}
+
+type FakeEnricher struct {
+	tags map[int][]string
+	err  error
+}
+
+func (f *FakeEnricher) Tags(ctx context.Context, id int) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.tags[id], nil
+}
+
+func TestService_Update_Enricher(t *testing.T) {
+	db := &FakeDB{}
+	enricher := &FakeEnricher{tags: map[int][]string{
+		1: {"Comics featuring Cueball", " python ", "comics featuring cueball"},
+	}}
+	publisher := &FakePublisher{}
+	svc, err := NewService(noopLogger, db, gapXKCD(), &FakeWords{}, 1,
+		WithEnricher(enricher, publisher))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	require.Len(t, db.added, 4)
+
+	// enrichment runs after the update and announces the enriched comics
+	require.Eventually(t, func() bool {
+		return len(publisher.Batches()) == 1
+	}, time.Second, 10*time.Millisecond)
+	categories := db.Categories()
+	assert.Len(t, categories, 4)
+	assert.Equal(t, []string{"comics featuring cueball", "python"}, categories[1])
+	assert.Empty(t, categories[2])
+	assert.ElementsMatch(t, []int{1, 2, 4, 5}, publisher.Batches()[0])
+}
+
+func TestService_Update_EnricherErrorsIgnored(t *testing.T) {
+	db := &FakeDB{}
+	enricher := &FakeEnricher{err: errors.New("explainxkcd is down")}
+	publisher := &FakePublisher{}
+	svc, err := NewService(noopLogger, db, gapXKCD(), &FakeWords{}, 1,
+		WithEnricher(enricher, publisher))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	require.Len(t, db.added, 4)
+	require.Eventually(t, func() bool {
+		svc.enrichLock.Lock()
+		defer svc.enrichLock.Unlock()
+		return !svc.enriching
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, db.Categories())
+	assert.Empty(t, publisher.Batches())
+}
+
+func TestService_UpdateOne_Enricher(t *testing.T) {
+	db := &FakeDB{}
+	enricher := &FakeEnricher{tags: map[int][]string{1: {"Math"}}}
+	svc, err := NewService(noopLogger, db, gapXKCD(), &FakeWords{}, 1,
+		WithEnricher(enricher, &FakePublisher{}))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.UpdateOne(context.Background(), 1))
+	assert.Equal(t, []string{"math"}, db.Categories()[1])
+}
+
+func TestNewService_UnknownNotFoundPolicy(t *testing.T) {
+	_, err := NewService(noopLogger, &FakeDB{}, &FakeXKCD{}, &FakeWords{}, 1,
+		WithNotFoundPolicy("retry"))
+	assert.Error(t, err)
+}
+
+func TestService_Status_ReindexGrace(t *testing.T) {
+	xkcd := &FakeXKCD{lastID: 1, comics: map[int]XKCDInfo{1: {ID: 1}}}
+	svc, err := NewService(noopLogger, &FakeDB{}, xkcd, &FakeWords{}, 1,
+		WithReindexGrace(100*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, StatusIdle, svc.Status(context.Background()))
+
+	require.NoError(t, svc.Update(context.Background()))
+
+	assert.Equal(t, StatusReindexing, svc.Status(context.Background()))
+	assert.Eventually(t, func() bool {
+		return svc.Status(context.Background()) == StatusIdle
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestService_Update_CheckpointBatches(t *testing.T) {
+	xkcd := &FakeXKCD{lastID: 25, comics: map[int]XKCDInfo{}}
+	for id := 1; id <= 25; id++ {
+		xkcd.comics[id] = XKCDInfo{ID: id}
+	}
+	publisher := &FakePublisher{}
+	svc, err := NewService(noopLogger, &FakeDB{}, xkcd, &FakeWords{}, 4,
+		WithCheckpoints(publisher, 10))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+
+	// the rest is announced by the event published after the update
+	require.Len(t, publisher.batches, 2)
+	var published []int
+	for _, batch := range publisher.batches {
+		assert.Len(t, batch, 10)
+		published = append(published, batch...)
+	}
+	assert.Len(t, published, 20)
+	assert.Subset(t, xkcdIDs(25), published)
+	assert.Zero(t, publisher.updates)
+}
+
+func xkcdIDs(n int) []int {
+	ids := make([]int, 0, n)
+	for id := 1; id <= n; id++ {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// splitWords lower cases words of phrases, drops "the" as a stop word and
+// returns each word once, like the words service.
+type splitWords struct{}
+
+func (splitWords) Norm(_ context.Context, phrase string) ([]string, error) {
+	words := strings.FieldsFunc(strings.ToLower(phrase), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	var normalized []string
+	for _, word := range words {
+		if word != "the" && !slices.Contains(normalized, word) {
+			normalized = append(normalized, word)
+		}
+	}
+	return normalized, nil
+}
+
+func (w splitWords) NormBatch(ctx context.Context, phrases []string) ([][]string, error) {
+	normalized := make([][]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		words, _ := w.Norm(ctx, phrase)
+		normalized = append(normalized, words)
+	}
+	return normalized, nil
+}
+
+func TestService_Update_KeywordsCountOnce(t *testing.T) {
+	db := &FakeDB{}
+	xkcd := &FakeXKCD{
+		lastID: 1,
+		comics: map[int]XKCDInfo{
+			1: {ID: 1, Title: "Python", Alt: "python snake", Description: "Python python snake"},
+		},
+	}
+	svc, err := NewService(noopLogger, db, xkcd, splitWords{}, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	require.Len(t, db.added, 1)
+	assert.Equal(t, []string{"python", "snake"}, db.added[0].Words)
+}
+
+func TestService_Update_NGrams(t *testing.T) {
+	db := &FakeDB{}
+	xkcd := &FakeXKCD{
+		lastID: 1,
+		comics: map[int]XKCDInfo{
+			1: {ID: 1, Title: "Car", Description: "Self-driving the car, self driving"},
+		},
+	}
+	svc, err := NewService(noopLogger, db, xkcd, splitWords{}, 1, WithNGrams(3))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	require.Len(t, db.added, 1)
+	assert.Equal(t, []string{
+		"self", "driving", "car",
+		"self driving", "driving car", "car self",
+		"self driving car", "driving car self", "car self driving",
+	}, db.added[0].Words)
+	assert.Equal(t, []string{"self", "driving", "car", "self", "driving"}, db.added[0].Stems)
+}
+
+// unorderedWords fails normalizing words in order.
+type unorderedWords struct {
+	splitWords
+}
+
+func (unorderedWords) NormBatch(context.Context, []string) ([][]string, error) {
+	return nil, errors.New("batch failed")
+}
+
+func TestService_Update_StemsOnlyWhenNeeded(t *testing.T) {
+	db := &FakeDB{}
+	xkcd := &FakeXKCD{
+		lastID: 1,
+		comics: map[int]XKCDInfo{1: {ID: 1, Description: "self driving car"}},
+	}
+	svc, err := NewService(noopLogger, db, xkcd, unorderedWords{}, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	require.Len(t, db.added, 1)
+	assert.Empty(t, db.added[0].Stems)
+	assert.Equal(t, []string{"self", "driving", "car"}, db.added[0].Words)
+}
+
+func TestService_Update_StemsFailureKeepsComics(t *testing.T) {
+	db := &FakeDB{}
+	xkcd := &FakeXKCD{
+		lastID: 1,
+		comics: map[int]XKCDInfo{1: {ID: 1, Description: "self driving car"}},
+	}
+	svc, err := NewService(noopLogger, db, xkcd, unorderedWords{}, 1, WithStems())
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	require.Len(t, db.added, 1)
+	assert.Empty(t, db.added[0].Stems)
+	assert.Equal(t, []string{"self", "driving", "car"}, db.added[0].Words)
+}
+
+func TestService_Update_BackfillsStems(t *testing.T) {
+	db := &FakeDB{IDsResult: []int{1, 2}, unstemmed: []int{2}}
+	xkcd := &FakeXKCD{
+		lastID: 2,
+		comics: map[int]XKCDInfo{
+			1: {ID: 1, Description: "happy new year"},
+			2: {ID: 2, Description: "self driving car"},
+		},
+	}
+	svc, err := NewService(noopLogger, db, xkcd, splitWords{}, 1, WithStems())
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(context.Background()))
+	assert.Equal(t, []int{2}, xkcd.fetched)
+	require.Len(t, db.added, 1)
+	assert.Equal(t, []string{"self", "driving", "car"}, db.added[0].Stems)
+
+	db.added = nil
+	xkcd.fetched = nil
+	plain, err := NewService(noopLogger, db, xkcd, splitWords{}, 1)
+	require.NoError(t, err)
+	require.NoError(t, plain.Update(context.Background()))
+	assert.Empty(t, xkcd.fetched, "comics are not fetched again without stems")
+}
+
+func TestService_Update_EmptyStemsNotFetchedAgain(t *testing.T) {
+	for name, words := range map[string]Words{
+		"stop words only": splitWords{},
+		"stems failed":    unorderedWords{},
+	} {
+		db := &FakeDB{}
+		xkcd := &FakeXKCD{
+			lastID: 1,
+			comics: map[int]XKCDInfo{1: {ID: 1, Description: "the"}},
+		}
+		svc, err := NewService(noopLogger, db, xkcd, words, 1, WithStems())
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Update(context.Background()))
+		require.Len(t, db.added, 1, name)
+		assert.NotNil(t, db.added[0].Stems, name)
+		assert.Empty(t, db.added[0].Stems, name)
+
+		db.IDsResult = []int{1}
+		xkcd.fetched = nil
+		require.NoError(t, svc.Update(context.Background()))
+		assert.Empty(t, xkcd.fetched, name)
+		assert.Len(t, db.added, 1, name)
+	}
+}
+
+type blockingXKCD struct {
+	*FakeXKCD
+	release chan struct{}
+}
+
+func (b *blockingXKCD) Get(ctx context.Context, id int) (XKCDInfo, error) {
+	<-b.release
+	return b.FakeXKCD.Get(ctx, id)
+}
+
+func TestService_Progress(t *testing.T) {
+	ctx := context.Background()
+	xkcd := &blockingXKCD{FakeXKCD: gapXKCD(), release: make(chan struct{})}
+	svc, err := NewService(noopLogger, &FakeDB{IDsResult: []int{1}}, xkcd, &FakeWords{}, 1)
+	require.NoError(t, err)
+
+	_, ok := <-svc.Progress(ctx)
+	assert.False(t, ok, "no update runs")
+
+	done := make(chan error)
+	go func() { done <- svc.Update(ctx) }()
+
+	var watch <-chan Progress
+	require.Eventually(t, func() bool {
+		watch = svc.Progress(ctx)
+		p, ok := <-watch
+		return ok && p.Total > 0
+	}, time.Second, time.Millisecond)
+	canceled, cancel := context.WithCancel(ctx)
+	unwatched := svc.Progress(canceled)
+	cancel()
+	for range unwatched {
+		// closed by the cancel while the update still runs
+	}
+
+	close(xkcd.release)
+	var last Progress
+	for p := range watch {
+		last = p
+	}
+	require.NoError(t, <-done)
+	assert.Equal(t, Progress{Fetched: 4, Total: 4, CurrentID: 5}, last, "the missing comics counts too")
+}
+
+func TestService_Progress_CountsFailures(t *testing.T) {
+	ctx := context.Background()
+	failing := gapXKCD()
+	failing.ErrGet = errors.New("xkcd is down")
+	xkcd := &blockingXKCD{FakeXKCD: failing, release: make(chan struct{})}
+	svc, err := NewService(noopLogger, &FakeDB{}, xkcd, &FakeWords{}, 2)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() { done <- svc.Update(ctx) }()
+	var watch <-chan Progress
+	require.Eventually(t, func() bool {
+		watch = svc.Progress(ctx)
+		p, ok := <-watch
+		return ok && p.Total > 0
+	}, time.Second, time.Millisecond)
+
+	close(xkcd.release)
+	var last Progress
+	for p := range watch {
+		last = p
+	}
+	<-done
+	assert.Equal(t, 5, last.Total)
+	assert.Equal(t, last.Total, last.Fetched, "failed comics are done with")
+}
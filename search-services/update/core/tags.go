@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// SetTags replaces comics tags, tags are lowercased and deduplicated.
+func (s *Service) SetTags(ctx context.Context, id int, tags []string) error {
+	if err := s.db.SetTags(ctx, id, normalizeTags(tags)); err != nil {
+		s.log.Error("failed to set tags", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) GetTags(ctx context.Context, id int) ([]string, error) {
+	tags, err := s.db.GetTags(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get tags", "id", id, "error", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
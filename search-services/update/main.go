@@ -10,14 +10,21 @@ import (
 	"os/signal"
 
 	"github.com/liy0aay/xkcd-search/closers"
+	"github.com/liy0aay/xkcd-search/metrics"
 	updatepb "github.com/liy0aay/xkcd-search/proto/update"
+	"github.com/liy0aay/xkcd-search/transport"
 	"github.com/liy0aay/xkcd-search/update/adapters/db"
+	"github.com/liy0aay/xkcd-search/update/adapters/explainxkcd"
 	updategrpc "github.com/liy0aay/xkcd-search/update/adapters/grpc"
+	"github.com/liy0aay/xkcd-search/update/adapters/initiator"
+	"github.com/liy0aay/xkcd-search/update/adapters/links"
 	updatenats "github.com/liy0aay/xkcd-search/update/adapters/nats"
 	"github.com/liy0aay/xkcd-search/update/adapters/words"
 	"github.com/liy0aay/xkcd-search/update/adapters/xkcd"
 	"github.com/liy0aay/xkcd-search/update/config"
 	"github.com/liy0aay/xkcd-search/update/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -43,8 +50,12 @@ func run(cfg config.Config, log *slog.Logger) error {
 	log.Info("starting server")
 	log.Debug("debug messages are enabled")
 
+	// dependencies have to be ready within startup timeout
+	startCtx, cancel := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	defer cancel()
+
 	// database adapter
-	storage, err := db.New(log, cfg.DBAddress)
+	storage, err := db.New(startCtx, log, cfg.DBAddress, cfg.DBTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to connect to db: %v", err)
 	}
@@ -54,7 +65,18 @@ func run(cfg config.Config, log *slog.Logger) error {
 	}
 
 	// xkcd adapter
-	xkcd, err := xkcd.NewClient(cfg.XKCD.URL, cfg.XKCD.Timeout, log)
+	// one transport pools connections of both xkcd and the enricher
+	httpTransport := transport.New(transport.Config(cfg.HTTPTransport))
+	xkcdOpts := []xkcd.Option{
+		xkcd.WithDescription(cfg.XKCD.Description),
+		xkcd.WithMaxTranscript(cfg.XKCD.MaxTranscript),
+		xkcd.WithRequestTimeout(cfg.XKCD.RequestTimeout),
+		xkcd.WithTransport(httpTransport),
+	}
+	if cfg.XKCD.ExcludeAlt {
+		xkcdOpts = append(xkcdOpts, xkcd.WithoutAlt())
+	}
+	xkcd, err := xkcd.NewClient(cfg.XKCD.URL, cfg.XKCD.Proxy, cfg.XKCD.Timeout, log, xkcdOpts...)
 	if err != nil {
 		return fmt.Errorf("failed create XKCD client: %v", err)
 	}
@@ -67,14 +89,47 @@ func run(cfg config.Config, log *slog.Logger) error {
 	defer closers.CloseOrLog(words, log)
 
 	// nats publisher
-	publisher, err := updatenats.New(log, cfg.BrokerAddress)
+	publisher, err := updatenats.New(startCtx, log, cfg.BrokerAddress)
 	if err != nil {
 		return fmt.Errorf("failed to create NATS publisher: %v", err)
 	}
 	defer closers.CloseOrLog(publisher, log)
 
 	// service
-	updater, err := core.NewService(log, storage, xkcd, words, cfg.XKCD.Concurrency)
+	opts := []core.Option{core.WithNotFoundPolicy(core.NotFoundPolicy(cfg.XKCD.NotFound))}
+	if cfg.LinkCheck.Enabled {
+		checker, err := links.NewChecker(cfg.LinkCheck.Rate, cfg.LinkCheck.Timeout, log)
+		if err != nil {
+			return fmt.Errorf("failed create link checker: %v", err)
+		}
+		opts = append(opts, core.WithLinkChecker(checker))
+	}
+	if cfg.Enrich.Enabled {
+		enricher, err := explainxkcd.NewEnricher(
+			cfg.Enrich.URL, cfg.XKCD.Proxy, cfg.Enrich.Rate, cfg.Enrich.Timeout, log,
+			explainxkcd.WithTransport(httpTransport),
+		)
+		if err != nil {
+			return fmt.Errorf("failed create enricher: %v", err)
+		}
+		opts = append(opts, core.WithEnricher(enricher, publisher))
+	}
+	if cfg.XKCD.NewestFirst {
+		opts = append(opts, core.WithNewestFirst())
+	}
+	if cfg.ReindexGrace > 0 {
+		opts = append(opts, core.WithReindexGrace(cfg.ReindexGrace))
+	}
+	if cfg.NGrams > 1 {
+		opts = append(opts, core.WithNGrams(cfg.NGrams))
+	}
+	if cfg.Stems {
+		opts = append(opts, core.WithStems())
+	}
+	if cfg.XKCD.Checkpoint > 0 {
+		opts = append(opts, core.WithCheckpoints(publisher, cfg.XKCD.Checkpoint))
+	}
+	updater, err := core.NewService(log, storage, xkcd, words, cfg.XKCD.Concurrency, opts...)
 	if err != nil {
 		return fmt.Errorf("failed create Update service: %v", err)
 	}
@@ -93,6 +148,18 @@ func run(cfg config.Config, log *slog.Logger) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	if cfg.MetricsAddress != "" {
+		prometheus.MustRegister(publisher.Metrics())
+		if err := metrics.Serve(ctx, cfg.MetricsAddress, promhttp.Handler(), log); err != nil {
+			return fmt.Errorf("failed to serve metrics: %v", err)
+		}
+	}
+
+	// image links checker
+	if cfg.LinkCheck.Enabled {
+		initiator.RunLinkCheck(ctx, updater, cfg.LinkCheck.Period, log)
+	}
+
 	go func() {
 		<-ctx.Done()
 		log.Debug("shutting down server")
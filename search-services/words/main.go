@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"log"
 	"net"
+	"slices"
 	"strconv"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -19,24 +21,77 @@ import (
 
 const maxPhraseLen = 20000
 
+const maxBatchSize = 1000
+
+// known input and its stem used by Ping to check the normalizer works
+const (
+	selfCheckPhrase = "running"
+	selfCheckStem   = "run"
+)
+
 type server struct {
 	wordspb.UnimplementedWordsServer
+	norm func(phrase, language string) ([]string, error)
 }
 
 func (s *server) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if got, _ := s.norm(selfCheckPhrase, words.DefaultLanguage); !slices.Equal(got, []string{selfCheckStem}) {
+		return nil, status.Errorf(
+			codes.Internal,
+			"normalizer self-check failed: %q gave %q, want %q", selfCheckPhrase, got, selfCheckStem,
+		)
+	}
 	return nil, nil
 }
 
+func (s *server) Version(_ context.Context, _ *emptypb.Empty) (*wordspb.VersionReply, error) {
+	return &wordspb.VersionReply{Version: wordspb.Version}, nil
+}
+
 func (s *server) Norm(_ context.Context, in *wordspb.WordsRequest) (*wordspb.WordsReply, error) {
+	normalized, err := s.normOne(in)
+	if err != nil {
+		return nil, err
+	}
+	return &wordspb.WordsReply{
+		Words: normalized,
+	}, nil
+}
+
+func (s *server) NormBatch(_ context.Context, in *wordspb.WordsBatchRequest) (*wordspb.WordsBatchReply, error) {
+	if len(in.GetPhrases()) > maxBatchSize {
+		return nil, status.Error(
+			codes.ResourceExhausted,
+			"batch is large than "+strconv.Itoa(maxBatchSize),
+		)
+	}
+	results := make([]*wordspb.WordsBatchItem, 0, len(in.GetPhrases()))
+	for _, phrase := range in.GetPhrases() {
+		normalized, err := s.normOne(phrase)
+		if err != nil {
+			results = append(results, &wordspb.WordsBatchItem{Error: status.Convert(err).Message()})
+			continue
+		}
+		results = append(results, &wordspb.WordsBatchItem{Words: normalized})
+	}
+	return &wordspb.WordsBatchReply{Results: results}, nil
+}
+
+func (s *server) normOne(in *wordspb.WordsRequest) ([]string, error) {
 	if len(in.GetPhrase()) > maxPhraseLen {
 		return nil, status.Error(
 			codes.ResourceExhausted,
 			"phrase is large than "+strconv.Itoa(maxPhraseLen),
 		)
 	}
-	return &wordspb.WordsReply{
-		Words: words.Norm(in.GetPhrase()),
-	}, nil
+	normalized, err := s.norm(in.GetPhrase(), in.GetLanguage())
+	if errors.Is(err, words.ErrUnsupportedLanguage) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return normalized, nil
 }
 
 type Config struct {
@@ -59,7 +114,7 @@ func main() {
 	}
 
 	s := grpc.NewServer()
-	wordspb.RegisterWordsServer(s, &server{})
+	wordspb.RegisterWordsServer(s, &server{norm: words.NormLanguage})
 	reflection.Register(s)
 
 	if err := s.Serve(listener); err != nil {
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	wordspb "github.com/liy0aay/xkcd-search/proto/words"
+	"github.com/liy0aay/xkcd-search/words/words"
+)
+
+func TestPing_Healthy(t *testing.T) {
+	s := &server{norm: words.NormLanguage}
+
+	_, err := s.Ping(context.Background(), nil)
+
+	require.NoError(t, err)
+}
+
+func TestPing_BrokenStemmer(t *testing.T) {
+	s := &server{norm: func(phrase, _ string) ([]string, error) {
+		return []string{phrase}, nil
+	}}
+
+	_, err := s.Ping(context.Background(), nil)
+
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestNorm_Language(t *testing.T) {
+	s := &server{norm: words.NormLanguage}
+
+	reply, err := s.Norm(context.Background(), &wordspb.WordsRequest{Phrase: "chats", Language: "fr"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chat"}, reply.GetWords())
+
+	_, err = s.Norm(context.Background(), &wordspb.WordsRequest{Phrase: "chats", Language: "xx"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestNormBatch_PerItemErrors(t *testing.T) {
+	s := &server{norm: words.NormLanguage}
+
+	reply, err := s.NormBatch(context.Background(), &wordspb.WordsBatchRequest{Phrases: []*wordspb.WordsRequest{
+		{Phrase: "running"},
+		{Phrase: strings.Repeat("a", maxPhraseLen+1)},
+		{Phrase: "chats", Language: "fr"},
+	}})
+
+	require.NoError(t, err)
+	results := reply.GetResults()
+	require.Len(t, results, 3)
+	assert.Equal(t, []string{"run"}, results[0].GetWords())
+	assert.Empty(t, results[0].GetError())
+	assert.Empty(t, results[1].GetWords())
+	assert.Contains(t, results[1].GetError(), "large")
+	assert.Equal(t, []string{"chat"}, results[2].GetWords())
+	assert.Empty(t, results[2].GetError())
+}
+
+func TestNormBatch_TooLarge(t *testing.T) {
+	s := &server{norm: words.NormLanguage}
+
+	_, err := s.NormBatch(context.Background(), &wordspb.WordsBatchRequest{
+		Phrases: make([]*wordspb.WordsRequest, maxBatchSize+1),
+	})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -1,25 +1,73 @@
 package words
 
 import (
+	"errors"
+	"fmt"
 	"maps"
 	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/french"
+	"github.com/kljensen/snowball/hungarian"
+	"github.com/kljensen/snowball/norwegian"
+	"github.com/kljensen/snowball/russian"
+	"github.com/kljensen/snowball/spanish"
+	"github.com/kljensen/snowball/swedish"
 )
 
+// DefaultLanguage is used for phrases without a language.
+const DefaultLanguage = "en"
+
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+type stemmer struct {
+	stem       func(word string, stemStopWords bool) string
+	isStopWord func(word string) bool
+}
+
+// stemmers are keyed by ISO 639-1 language codes.
+var stemmers = map[string]stemmer{
+	"en": {english.Stem, english.IsStopWord},
+	"es": {spanish.Stem, spanish.IsStopWord},
+	"fr": {french.Stem, french.IsStopWord},
+	"hu": {hungarian.Stem, hungarian.IsStopWord},
+	"no": {norwegian.Stem, norwegian.IsStopWord},
+	"ru": {russian.Stem, russian.IsStopWord},
+	"sv": {swedish.Stem, swedish.IsStopWord},
+}
+
+// Languages lists the supported language codes.
+func Languages() []string {
+	return slices.Sorted(maps.Keys(stemmers))
+}
+
 func Norm(phrase string) []string {
+	words, _ := NormLanguage(phrase, DefaultLanguage)
+	return words
+}
+
+// NormLanguage normalizes phrase with the stemmer of language,
+// DefaultLanguage is used when language is empty.
+func NormLanguage(phrase, language string) ([]string, error) {
+	if language == "" {
+		language = DefaultLanguage
+	}
+	stemmer, ok := stemmers[language]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLanguage, language)
+	}
 	words := make(map[string]bool)
 	splitted := strings.FieldsFunc(phrase, func(r rune) bool {
 		return !unicode.IsDigit(r) && !unicode.IsLetter(r)
 	})
 	for _, w := range splitted {
 		w := strings.ToLower(w)
-		if english.IsStopWord(w) {
+		if stemmer.isStopWord(w) {
 			continue
 		}
-		words[english.Stem(w, false)] = true
+		words[stemmer.stem(w, false)] = true
 	}
-	return slices.Collect(maps.Keys(words))
+	return slices.Collect(maps.Keys(words)), nil
 }
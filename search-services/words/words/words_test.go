@@ -108,3 +108,16 @@ func TestNorm_PhraseWithAndOrThe(t *testing.T) {
 	assert.NotContains(t, result, "and")
 	assert.Len(t, result, 4)
 }
+
+func TestNormLanguage(t *testing.T) {
+	result, err := NormLanguage("chats mangent", "fr")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"chat", "mangent"}, result)
+
+	result, err = NormLanguage("running", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"run"}, result)
+
+	_, err = NormLanguage("running", "xx")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+}
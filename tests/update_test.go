@@ -66,6 +66,28 @@ func TestUpdate(t *testing.T) {
 	require.True(t, 100 < st.WordsUnique, "not enough unique words in DB")
 
 	prepare(t)
+	deleted := tombstones(t)
+	require.NotEmpty(t, deleted, "dropped comics must leave tombstones")
+	for _, comics := range deleted {
+		require.True(t, comics.Deleted, "only tombstones are left after drop")
+	}
+}
+
+type ChangedComics struct {
+	ID      int  `json:"id"`
+	Deleted bool `json:"deleted"`
+}
+
+func tombstones(t *testing.T) []ChangedComics {
+	resp, err := client.Get(address + "/api/comics/changes?since=1970-01-01T00:00:00Z&limit=10&tombstones=true")
+	require.NoError(t, err, "could not get changes")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var changes struct {
+		Comics []ChangedComics `json:"comics"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&changes), "cannot decode")
+	return changes.Comics
 }
 
 func login(t *testing.T) string {